@@ -6,21 +6,28 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+
+	"skyrix/internal/commands"
 )
 
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	consoleApp, cleanup, err := buildConsoleApp()
+	consoleApp, cleanup, err := buildConsoleApp(ctx)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to build console app: %v\n", err)
 		os.Exit(1)
 	}
-	defer cleanup()
 
-	if err := consoleApp.Execute(ctx); err != nil {
+	err = consoleApp.Execute(ctx)
+	if err != nil {
 		consoleApp.Kernel.Logger.Error("console command failed", "error", err)
-		os.Exit(1)
 	}
+	// os.Exit skips deferred calls, so release resources explicitly first.
+	if stopErr := consoleApp.Stop(); stopErr != nil {
+		consoleApp.Kernel.Logger.Error("console shutdown failed", "error", stopErr)
+	}
+	cleanup()
+	os.Exit(commands.ExitCode(err))
 }
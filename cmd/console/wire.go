@@ -4,29 +4,37 @@
 package main
 
 import (
+	"context"
+
 	"skyrix/internal/engine"
+	"skyrix/internal/engine/cache"
+	"skyrix/internal/engine/tenantPackage"
 	"skyrix/internal/kernel"
 	"skyrix/internal/providers"
 
 	"github.com/google/wire"
 )
 
-func buildConsoleApp() (*kernel.ConsoleApp, func(), error) {
+func buildConsoleApp(ctx context.Context) (*kernel.ConsoleApp, func(), error) {
 	wire.Build(
 		// 1) Bootstrap (config, logger, raw db/redis)
 		kernel.ProviderSet,
 
-		// 2) Engine (db/redis wrappers)
+		// 2) Engine (db/redis wrappers) and the tenant-scoped cache over them
 		engine.ProviderSet,
+		cache.ProviderSet,
 
 		// 3) Build the Kernel
 		kernel.NewKernel,
 
-		// 4) Console layer
+		// 4) Tenant core (repo/service) so commands can run in tenant context
+		tenantPackage.CoreSet,
+
+		// 5) Console layer
 		providers.JobProviderSet,
 		providers.CommandProviderSet,
 
-		// 5) Final console app
+		// 6) Final console app
 		kernel.NewConsoleApp,
 	)
 	return nil, nil, nil
@@ -7,8 +7,16 @@
 package main
 
 import (
+	"context"
 	"skyrix/internal/commands"
 	"skyrix/internal/engine"
+	"skyrix/internal/engine/cache"
+	"skyrix/internal/engine/metrics"
+	"skyrix/internal/engine/migrate"
+	"skyrix/internal/engine/outbox"
+	"skyrix/internal/engine/tenantPackage"
+	"skyrix/internal/engine/tenantPackage/repository"
+	"skyrix/internal/engine/tenantPackage/service"
 	jobs2 "skyrix/internal/jobs"
 	"skyrix/internal/kernel"
 	"skyrix/internal/kernel/jobs"
@@ -17,7 +25,7 @@ import (
 
 // Injectors from wire.go:
 
-func buildConsoleApp() (*kernel.ConsoleApp, func(), error) {
+func buildConsoleApp(ctx context.Context) (*kernel.ConsoleApp, func(), error) {
 	config, err := kernel.ProvideConfig()
 	if err != nil {
 		return nil, nil, err
@@ -25,28 +33,79 @@ func buildConsoleApp() (*kernel.ConsoleApp, func(), error) {
 	logger := kernel.ProvideLoggerConfig(config)
 	loggerInterface := kernel.ProvideLogger(logger)
 	database := kernel.ProvideDatabaseConfig(config)
-	db, cleanup, err := kernel.ProvidePostgres(database, loggerInterface)
+	registry := metrics.NewRegistry()
+	configMetrics := kernel.ProvideMetricsConfig(config)
+	metricsMetrics, err := metrics.ProvideMetrics(registry, configMetrics)
 	if err != nil {
 		return nil, nil, err
 	}
-	engineDatabase := engine.ProvideDatabaseService(db, config)
+	lifecycle := kernel.NewLifecycle(loggerInterface)
+	db, cleanup, err := kernel.ProvidePostgres(ctx, database, loggerInterface, metricsMetrics, lifecycle)
+	if err != nil {
+		return nil, nil, err
+	}
+	tenantPools, cleanup2 := kernel.ProvideTenantPools(database, loggerInterface, metricsMetrics, lifecycle)
+	engineDatabase := engine.ProvideDatabaseService(db, config, tenantPools)
 	redis := kernel.ProvideRedisConfig(config)
-	client, cleanup2, err := kernel.ProvideRedis(redis, loggerInterface)
+	configCache := kernel.ProvideCacheConfig(config)
+	universalClient, cleanup3, err := kernel.ProvideRedis(redis, configCache, loggerInterface, lifecycle)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	engineRedis := engine.ProvideRedisService(universalClient, loggerInterface, config, metricsMetrics)
+	engineCache, cleanup4, err := engine.ProvideCache(config, engineRedis, loggerInterface, lifecycle, metricsMetrics)
 	if err != nil {
+		cleanup3()
+		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
-	engineRedis := engine.ProvideRedisService(client, loggerInterface, config)
-	registry := jobs.NewRegistry(loggerInterface)
-	kernelKernel := kernel.NewKernel(config, loggerInterface, engineDatabase, engineRedis, registry)
+	tenantCache := cache.ProvideTenantCache(engineCache, config)
+	locker := engine.ProvideLocker(config, engineRedis, loggerInterface)
+	jobsRegistry := jobs.NewRegistry(loggerInterface, locker, metricsMetrics)
 	systemPingJob := jobs2.NewSystemPingJob(loggerInterface)
+	transactionManager := engine.NewTxManager(engineDatabase, loggerInterface)
+	memorySink := outbox.NewMemorySink()
+	sink, err := outbox.ProvideSink(config, memorySink, jobsRegistry)
+	if err != nil {
+		cleanup4()
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	tenantRepository := repository.NewTenantRepository(engineDatabase)
+	opts := outbox.ProvideOpts(config)
+	relay := outbox.ProvideRelay(engineDatabase, transactionManager, sink, tenantRepository, lifecycle, loggerInterface, opts)
+	outboxRelayJob := jobs2.NewOutboxRelayJob(relay)
+	outboxCleanupJob := jobs2.NewOutboxCleanupJob(relay)
 	providersJobs := &providers.Jobs{
-		SystemPingJob: systemPingJob,
+		SystemPingJob:    systemPingJob,
+		OutboxRelayJob:   outboxRelayJob,
+		OutboxCleanupJob: outboxCleanupJob,
 	}
+	registry2 := providers.ProvideJobRegistry(jobsRegistry, providersJobs)
+	kernelKernel := kernel.NewKernel(config, loggerInterface, engineDatabase, tenantCache, registry2)
 	helloCommand := commands.NewHelloCommand()
-	providersCommands := providers.ProvideCommands(helloCommand)
-	consoleApp := kernel.NewConsoleApp(kernelKernel, providersJobs, providersCommands)
+	cacheOpts := tenantPackage.ProvideTenantCacheOpts(config)
+	tenantService := service.NewTenantService(loggerInterface, tenantRepository, tenantCache, cacheOpts)
+	tenantRunner := jobs.NewTenantRunner(registry2, tenantService, loggerInterface)
+	dispatcher, cleanup5 := providers.ProvideDispatcher(jobsRegistry, tenantCache, locker, lifecycle, loggerInterface)
+	jobRunCommand := commands.NewJobRunCommand(registry2, tenantService, tenantRunner, dispatcher, loggerInterface)
+	jobListCommand := commands.NewJobListCommand(registry2)
+	outboxRelayCommand := commands.NewOutboxRelayCommand(relay)
+	migrateOpts := migrate.ProvideOpts(config)
+	migrator := migrate.NewMigrator(engineDatabase, transactionManager, locker, loggerInterface, migrateOpts)
+	migrateUpCommand := commands.NewMigrateUpCommand(migrator)
+	providersCommands := providers.ProvideCommands(helloCommand, jobRunCommand, jobListCommand, outboxRelayCommand, migrateUpCommand)
+	httpServer := kernel.ProvideHttpServerConfig(config)
+	consoleApp := kernel.NewConsoleApp(kernelKernel, providersJobs, providersCommands, lifecycle, httpServer)
 	return consoleApp, func() {
+		cleanup5()
+		cleanup4()
+		cleanup3()
 		cleanup2()
 		cleanup()
 	}, nil
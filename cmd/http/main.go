@@ -13,7 +13,7 @@ func main() {
 	defer stop()
 
 	// 2. Build the application using wire
-	app, cleanup, err := buildHTTPApp()
+	app, cleanup, err := buildHTTPApp(ctx)
 	if err != nil {
 		// Use a standard logger here because the app's logger might not be initialized yet
 		log.Fatalf("Failed to build http app: %v", err)
@@ -4,6 +4,8 @@
 package main
 
 import (
+	"context"
+
 	"skyrix/internal/engine"
 	"skyrix/internal/kernel"
 	"skyrix/internal/providers"
@@ -13,7 +15,7 @@ import (
 	"github.com/google/wire"
 )
 
-func buildHTTPApp() (*kernel.HTTPApp, func(), error) {
+func buildHTTPApp(ctx context.Context) (*kernel.HTTPApp, func(), error) {
 	wire.Build(
 		// 1. Microkernel bootstrap (config, logger, raw db/redis)
 		kernel.ProviderSet,
@@ -27,13 +29,15 @@ func buildHTTPApp() (*kernel.HTTPApp, func(), error) {
 		// 4. Platform policies
 		providers.PlatformProviderSet,
 
-		// 5. Business domains
+		// 5. Business domains + domain event subscriptions
 		providers.DomainProviderSet,
+		providers.EventProviderSet,
 
 		// 6. App layer
 		providers.HandlerProviderSet,
 		providers.JobProviderSet,
 		providers.GlobalMiddlewareProviderSet,
+		providers.HealthProviderSet,
 
 		// 7. HTTP layer
 		validation.NewValidator,
@@ -7,11 +7,22 @@
 package main
 
 import (
+	"context"
+	repository2 "skyrix/internal/domain/order/repository"
+	services2 "skyrix/internal/domain/order/services"
+	"skyrix/internal/domain/subscriber/listeners"
 	"skyrix/internal/domain/subscriber/repository"
 	"skyrix/internal/domain/subscriber/services"
 	"skyrix/internal/engine"
+	"skyrix/internal/engine/cache"
+	"skyrix/internal/engine/events"
+	"skyrix/internal/engine/health"
+	"skyrix/internal/engine/metrics"
+	"skyrix/internal/engine/outbox"
 	"skyrix/internal/engine/tenantPackage"
+	repository3 "skyrix/internal/engine/tenantPackage/repository"
 	"skyrix/internal/handlers"
+	jobs2 "skyrix/internal/jobs"
 	"skyrix/internal/kernel"
 	"skyrix/internal/kernel/jobs"
 	"skyrix/internal/middleware"
@@ -22,7 +33,7 @@ import (
 
 // Injectors from wire.go:
 
-func buildHTTPApp() (*kernel.HTTPApp, func(), error) {
+func buildHTTPApp(ctx context.Context) (*kernel.HTTPApp, func(), error) {
 	config, err := kernel.ProvideConfig()
 	if err != nil {
 		return nil, nil, err
@@ -33,44 +44,123 @@ func buildHTTPApp() (*kernel.HTTPApp, func(), error) {
 	loggerInterface := kernel.ProvideLogger(logger)
 	recoverMiddleware := middleware.NewRecoverMiddleware(loggerInterface)
 	gzipDecompressMiddleware := middleware.NewGzipDecompressMiddleware(loggerInterface)
+	consistencyMiddleware := middleware.NewConsistencyMiddleware()
+	redis := kernel.ProvideRedisConfig(config)
+	configCache := kernel.ProvideCacheConfig(config)
+	lifecycle := kernel.NewLifecycle(loggerInterface)
+	universalClient, cleanup, err := kernel.ProvideRedis(redis, configCache, loggerInterface, lifecycle)
+	if err != nil {
+		return nil, nil, err
+	}
+	registry := metrics.NewRegistry()
+	configMetrics := kernel.ProvideMetricsConfig(config)
+	metricsMetrics, err := metrics.ProvideMetrics(registry, configMetrics)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	engineRedis := engine.ProvideRedisService(universalClient, loggerInterface, config, metricsMetrics)
+	engineCache, cleanup2, err := engine.ProvideCache(config, engineRedis, loggerInterface, lifecycle, metricsMetrics)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	tenantCache := cache.ProvideTenantCache(engineCache, config)
+	responseCacheMiddleware := middleware.NewResponseCacheMiddleware(tenantCache, httpServer, loggerInterface)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(tenantCache, httpServer, loggerInterface)
+	metricsMiddleware := middleware.NewMetricsMiddleware(metricsMetrics)
 	globalMiddleware := &providers.GlobalMiddleware{
 		ManyRequests:   manyRequestsMiddleware,
 		Recover:        recoverMiddleware,
 		GzipDecompress: gzipDecompressMiddleware,
+		Consistency:    consistencyMiddleware,
+		ResponseCache:  responseCacheMiddleware,
+		Idempotency:    idempotencyMiddleware,
+		Metrics:        metricsMiddleware,
 	}
 	noopTenantMiddleware := router.NewNoopTenantMiddleware()
 	database := kernel.ProvideDatabaseConfig(config)
-	db, cleanup, err := kernel.ProvidePostgres(database, loggerInterface)
+	db, cleanup3, err := kernel.ProvidePostgres(ctx, database, loggerInterface, metricsMetrics, lifecycle)
 	if err != nil {
+		cleanup2()
+		cleanup()
 		return nil, nil, err
 	}
-	engineDatabase := engine.ProvideDatabaseService(db, config)
+	tenantPools, cleanup4 := kernel.ProvideTenantPools(database, loggerInterface, metricsMetrics, lifecycle)
+	engineDatabase := engine.ProvideDatabaseService(db, config, tenantPools)
 	string2 := tenantPackage.ProvideTenantHeader(config)
 	subscriberRepository := repository.NewSubscriberRepository(engineDatabase, string2, loggerInterface)
 	subscriberService := services.NewSubscriberService(subscriberRepository, loggerInterface)
 	validator := validation.NewValidator()
 	subscriberHandler := handlers.NewSubscriberHandler(loggerInterface, subscriberService, validator)
+	transactionManager := engine.NewTxManager(engineDatabase, loggerInterface)
+	orderRepository := repository2.NewOrderRepository(engineDatabase)
+	locker := engine.ProvideLocker(config, engineRedis, loggerInterface)
+	jobsRegistry := jobs.NewRegistry(loggerInterface, locker, metricsMetrics)
+	dispatcher, cleanup5 := providers.ProvideDispatcher(jobsRegistry, tenantCache, locker, lifecycle, loggerInterface)
+	bus := events.NewBus(dispatcher, loggerInterface)
+	orderPlacedListener := listeners.NewOrderPlacedListener(subscriberService)
+	eventSubscribers := &providers.EventSubscribers{
+		OrderPlaced: orderPlacedListener,
+	}
+	publisher := providers.RegisterEventSubscribers(bus, eventSubscribers)
+	orderService := services2.NewOrderService(transactionManager, orderRepository, publisher, loggerInterface)
+	orderHandler := handlers.NewOrderHandler(loggerInterface, orderService, validator)
 	providersHandlers := &providers.Handlers{
 		Subscriber: subscriberHandler,
+		Order:      orderHandler,
 	}
-	handler := router.ProvideRouter(httpServer, globalMiddleware, noopTenantMiddleware, providersHandlers)
+	databaseChecker := health.NewDatabaseChecker(engineDatabase)
+	schemaChecker := health.ProvideSchemaChecker(engineDatabase, config)
+	redisChecker := health.ProvideRedisChecker(engineRedis, config)
+	queueChecker := health.ProvideQueueChecker(config)
+	lifecycleChecker := health.NewLifecycleChecker(lifecycle)
+	healthChecks := &providers.HealthChecks{
+		Database:  databaseChecker,
+		Schema:    schemaChecker,
+		Redis:     redisChecker,
+		Queue:     queueChecker,
+		Lifecycle: lifecycleChecker,
+	}
+	healthRegistry := providers.ProvideHealth(config, loggerInterface, healthChecks)
+	handler := router.ProvideRouter(httpServer, globalMiddleware, noopTenantMiddleware, providersHandlers, healthRegistry, registry, configMetrics)
 	server := kernel.ProvideHTTPServer(handler, httpServer)
-	redis := kernel.ProvideRedisConfig(config)
-	client, cleanup2, err := kernel.ProvideRedis(redis, loggerInterface)
+	systemPingJob := jobs2.NewSystemPingJob(loggerInterface)
+	memorySink := outbox.NewMemorySink()
+	sink, err := outbox.ProvideSink(config, memorySink, jobsRegistry)
 	if err != nil {
+		cleanup5()
+		cleanup4()
+		cleanup3()
+		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
-	engineRedis := engine.ProvideRedisService(client, loggerInterface, config)
-	registry := jobs.NewRegistry(loggerInterface)
-	kernelKernel := kernel.NewKernel(config, loggerInterface, engineDatabase, engineRedis, registry)
-	httpApp, err := kernel.NewHTTPApp(server, kernelKernel)
+	tenantRepository := repository3.NewTenantRepository(engineDatabase)
+	opts := outbox.ProvideOpts(config)
+	relay := outbox.ProvideRelay(engineDatabase, transactionManager, sink, tenantRepository, lifecycle, loggerInterface, opts)
+	outboxRelayJob := jobs2.NewOutboxRelayJob(relay)
+	outboxCleanupJob := jobs2.NewOutboxCleanupJob(relay)
+	providersJobs := &providers.Jobs{
+		SystemPingJob:    systemPingJob,
+		OutboxRelayJob:   outboxRelayJob,
+		OutboxCleanupJob: outboxCleanupJob,
+	}
+	registry2 := providers.ProvideJobRegistry(jobsRegistry, providersJobs)
+	kernelKernel := kernel.NewKernel(config, loggerInterface, engineDatabase, tenantCache, registry2)
+	httpApp, err := kernel.NewHTTPApp(server, kernelKernel, lifecycle, httpServer)
 	if err != nil {
+		cleanup5()
+		cleanup4()
+		cleanup3()
 		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
 	return httpApp, func() {
+		cleanup5()
+		cleanup4()
+		cleanup3()
 		cleanup2()
 		cleanup()
 	}, nil
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	engineJobs "skyrix/internal/engine/jobs"
+
+	"github.com/spf13/cobra"
+)
+
+// JobListCommand prints every job registered in the jobs Registry.
+type JobListCommand struct {
+	Jobs engineJobs.Registry
+}
+
+func NewJobListCommand(jobs engineJobs.Registry) *JobListCommand {
+	return &JobListCommand{Jobs: jobs}
+}
+
+func (c *JobListCommand) ToCobraCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "job:list",
+		Short: "List registered jobs",
+		Long:  "Lists all jobs available to job:run, sorted by name.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names := c.Jobs.List()
+			if len(names) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "no jobs registered")
+				return nil
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tSCOPE\tRETRIES")
+			for _, name := range names {
+				j, ok := c.Jobs.Get(name)
+				if !ok {
+					continue
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%d\n", name, engineJobs.ScopeOf(j), j.RetryCount())
+			}
+			return tw.Flush()
+		},
+	}
+}
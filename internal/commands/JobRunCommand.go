@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	engineJobs "skyrix/internal/engine/jobs"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+	"skyrix/internal/engine/tenantPackage/schemaResolver"
+	"skyrix/internal/engine/tenantPackage/service"
+	kernelJobs "skyrix/internal/kernel/jobs"
+	"skyrix/internal/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// JobRunCommand runs any job registered in the jobs Registry by name.
+//
+//	job:run <name> --arg key=value --args-json '{...}' [--tenant ns] [--async] [--concurrency n]
+//
+// Tenant-scoped jobs run for the given --tenant, or fan out across all active tenants when
+// --tenant is omitted. Main-scoped jobs always run once against the main schema.
+// With --async the job is handed to the jobs Enqueuer and the command returns right away.
+type JobRunCommand struct {
+	Jobs     engineJobs.Registry
+	Tenants  *service.TenantService
+	FanOut   *kernelJobs.TenantRunner
+	Enqueuer engineJobs.Enqueuer
+	Log      logger.Interface
+}
+
+func NewJobRunCommand(
+	jobs engineJobs.Registry,
+	tenants *service.TenantService,
+	fanOut *kernelJobs.TenantRunner,
+	enqueuer engineJobs.Enqueuer,
+	log logger.Interface,
+) *JobRunCommand {
+	return &JobRunCommand{Jobs: jobs, Tenants: tenants, FanOut: fanOut, Enqueuer: enqueuer, Log: log}
+}
+
+func (c *JobRunCommand) ToCobraCommand() *cobra.Command {
+	var (
+		kv          []string
+		argsJSON    string
+		tenant      string
+		async       bool
+		concurrency int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "job:run <name>",
+		Short: "Run a registered job by name",
+		Long: `Runs a job from the jobs registry.
+Arguments are merged from --args-json first, then every --arg key=value (which wins on conflict).
+With --tenant the job runs with the tenant schema in context, so tenant-scoped DB access works.
+Tenant-scoped jobs without --tenant fan out across all active tenants (bounded by --concurrency).
+With --async the job is enqueued on the jobs dispatcher and the command returns once it is enqueued;
+the console still lets it finish at shutdown, within APP_SHUTDOWN_TIMEOUT. Fan-out runs cannot be
+enqueued, so --async on a tenant-scoped job requires --tenant.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			name := strings.TrimSpace(args[0])
+
+			job, ok := c.Jobs.Get(name)
+			if !ok {
+				return exitErr(ExitUsage, fmt.Errorf("job not found: %s", name))
+			}
+			scope := engineJobs.ScopeOf(job)
+			if scope == engineJobs.ScopeMain && strings.TrimSpace(tenant) != "" {
+				return exitErr(ExitUsage, fmt.Errorf("job %q is main-scoped and cannot run for a tenant", name))
+			}
+
+			jobArgs, err := parseJobArgs(argsJSON, kv)
+			if err != nil {
+				return exitErr(ExitUsage, err)
+			}
+
+			fanOut := scope == engineJobs.ScopeTenant && strings.TrimSpace(tenant) == ""
+			if async && fanOut {
+				return exitErr(ExitUsage, fmt.Errorf("job %q is tenant-scoped: --async requires --tenant", name))
+			}
+
+			ctx, err = c.withTenant(ctx, tenant)
+			if err != nil {
+				return exitErr(ExitUsage, err)
+			}
+
+			if async {
+				return c.enqueue(ctx, cmd, name, jobArgs)
+			}
+
+			run := func(ctx context.Context) error {
+				return c.Jobs.Run(ctx, name, jobArgs)
+			}
+			if fanOut {
+				run = func(ctx context.Context) error {
+					return c.runForAllTenants(ctx, cmd, name, jobArgs, concurrency)
+				}
+			}
+
+			if err := run(ctx); err != nil {
+				if ctx.Err() != nil {
+					return exitErr(ExitInterrupted, err)
+				}
+				return exitErr(ExitFailure, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "job %q finished\n", name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&kv, "arg", nil, "Job argument as key=value (repeatable)")
+	cmd.Flags().StringVar(&argsJSON, "args-json", "", "Job arguments as a JSON object")
+	cmd.Flags().StringVarP(&tenant, "tenant", "t", "", "Tenant namespace to run the job for")
+	cmd.Flags().BoolVar(&async, "async", false, "Enqueue the job and return without waiting for it")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 0, "Max tenants processed at once during fan-out (default 4)")
+
+	return cmd
+}
+
+// withTenant resolves the tenant namespace into its schema and stores it in ctx,
+// the same way TenantMiddleware does for HTTP requests.
+func (c *JobRunCommand) withTenant(ctx context.Context, namespace string) (context.Context, error) {
+	namespace = strings.TrimSpace(namespace)
+	if namespace == "" {
+		return ctx, nil
+	}
+	if !schemaResolver.ReIdent.MatchString(namespace) {
+		return ctx, fmt.Errorf("invalid tenant: %q", namespace)
+	}
+	if c.Tenants == nil {
+		return ctx, errors.New("tenant service is not configured")
+	}
+
+	t, err := c.Tenants.GetByNamespace(ctx, namespace)
+	if err != nil {
+		return ctx, fmt.Errorf("tenant %q: %w", namespace, err)
+	}
+	res, err := schemaResolver.ResolutionOf(t, "console")
+	if err != nil {
+		return ctx, fmt.Errorf("tenant %q: %w", namespace, err)
+	}
+
+	ctx = tenantContext.WithSchema(ctx, res.Schema)
+	if res.TenantID > 0 {
+		ctx = tenantContext.WithTenantID(ctx, res.TenantID)
+	}
+	if res.Database != "" {
+		ctx = tenantContext.WithDatabase(ctx, res.Database)
+	}
+	ctx = tenantContext.WithResolvedBy(ctx, res.By)
+	return ctx, nil
+}
+
+// enqueue hands the job to the Enqueuer and returns without waiting for it. The tenant in ctx
+// travels with the run. A Duplicate or Throttled status is reported but is not a failure.
+func (c *JobRunCommand) enqueue(ctx context.Context, cmd *cobra.Command, name string, args map[string]any) error {
+	if c.Enqueuer == nil {
+		return exitErr(ExitFailure, errors.New("job dispatcher is not configured"))
+	}
+	status, err := c.Enqueuer.Enqueue(ctx, name, args)
+	if err != nil {
+		return exitErr(ExitFailure, fmt.Errorf("enqueue %q: %w", name, err))
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "job %q %s\n", name, status)
+	return nil
+}
+
+// runForAllTenants fans a tenant-scoped job out across all active tenants and prints the report.
+// It returns the joined per-tenant errors so a partial failure yields a non-zero exit code.
+func (c *JobRunCommand) runForAllTenants(ctx context.Context, cmd *cobra.Command, name string, args map[string]any, concurrency int) error {
+	if c.FanOut == nil {
+		return errors.New("tenant runner is not configured")
+	}
+	report, err := c.FanOut.RunForAllTenants(ctx, name, args, kernelJobs.FanOutOpts{Concurrency: concurrency})
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TENANT\tSCHEMA\tSTATUS\tDURATION\tERROR")
+	for _, res := range report.Results {
+		status, msg := "ok", ""
+		switch {
+		case res.Skipped:
+			status, msg = "skipped", res.Err.Error()
+		case res.Err != nil:
+			status, msg = "failed", res.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", res.Namespace, res.Schema, status, res.Duration.Round(time.Millisecond), msg)
+	}
+	_ = tw.Flush()
+	fmt.Fprintf(cmd.OutOrStdout(), "%d tenants, %d succeeded, %d failed in %s\n",
+		len(report.Results), report.Succeeded(), len(report.Failed()), report.Duration.Round(time.Millisecond))
+
+	return report.Err()
+}
+
+// parseJobArgs merges --args-json and --arg key=value pairs into a single args map.
+func parseJobArgs(argsJSON string, kv []string) (map[string]any, error) {
+	out := make(map[string]any)
+
+	if s := strings.TrimSpace(argsJSON); s != "" {
+		if err := json.Unmarshal([]byte(s), &out); err != nil {
+			return nil, fmt.Errorf("invalid --args-json (expected a JSON object): %w", err)
+		}
+		if out == nil {
+			out = make(map[string]any)
+		}
+	}
+
+	for _, pair := range kv {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid --arg %q (expected key=value)", pair)
+		}
+		out[k] = v
+	}
+	return out, nil
+}
@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"skyrix/internal/engine"
+	engineJobs "skyrix/internal/engine/jobs"
+	kernelJobs "skyrix/internal/kernel/jobs"
+	"skyrix/internal/logger"
+)
+
+func TestParseJobArgs(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		json    string
+		kv      []string
+		want    map[string]any
+		wantErr bool
+	}{
+		{name: "empty", want: map[string]any{}},
+		{name: "json only", json: `{"a":1,"b":"x"}`, want: map[string]any{"a": float64(1), "b": "x"}},
+		{name: "json null", json: "null", want: map[string]any{}},
+		{name: "arg only", kv: []string{"a=1", " b =x=y"}, want: map[string]any{"a": "1", "b": "x=y"}},
+		{name: "arg wins over json", json: `{"a":1,"b":2}`, kv: []string{"a=over"}, want: map[string]any{"a": "over", "b": float64(2)}},
+		{name: "last arg wins", kv: []string{"a=1", "a=2"}, want: map[string]any{"a": "2"}},
+		{name: "empty value", kv: []string{"a="}, want: map[string]any{"a": ""}},
+		{name: "missing equals", kv: []string{"a"}, wantErr: true},
+		{name: "empty key", kv: []string{"=1"}, wantErr: true},
+		{name: "malformed json", json: `{"a":`, wantErr: true},
+		{name: "json array", json: `[1,2]`, wantErr: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseJobArgs(c.json, c.kv)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+// funcJob is a job backed by a function; tenant marks it tenant-scoped.
+type funcJob struct {
+	name   string
+	tenant bool
+	exec   func(ctx context.Context, args map[string]any) error
+}
+
+func (j *funcJob) Name() string    { return j.name }
+func (j *funcJob) RetryCount() int { return 0 }
+
+func (j *funcJob) Scope() engineJobs.Scope {
+	if j.tenant {
+		return engineJobs.ScopeTenant
+	}
+	return engineJobs.ScopeMain
+}
+
+func (j *funcJob) Execute(ctx context.Context, args map[string]any) error {
+	return j.exec(ctx, args)
+}
+
+type enqueued struct {
+	name string
+	args map[string]any
+}
+
+// fakeEnqueuer records Enqueue calls without running anything.
+type fakeEnqueuer struct {
+	calls []enqueued
+}
+
+func (e *fakeEnqueuer) Enqueue(_ context.Context, name string, args map[string]any, _ ...engineJobs.EnqueueOption) (engineJobs.EnqueueStatus, error) {
+	e.calls = append(e.calls, enqueued{name: name, args: args})
+	return engineJobs.Enqueued, nil
+}
+
+func newTestJobRunCommand(t *testing.T, enq engineJobs.Enqueuer, jobs ...engineJobs.Job) *JobRunCommand {
+	t.Helper()
+	log := logger.NewSlogWrapper(slog.New(slog.DiscardHandler))
+	reg := kernelJobs.NewRegistry(log, engine.NewMemoryLocker(log), nil)
+	for _, j := range jobs {
+		reg.Register(j)
+	}
+	return NewJobRunCommand(reg, nil, nil, enq, log)
+}
+
+func runJobCommand(ctx context.Context, c *JobRunCommand, args ...string) (string, error) {
+	cmd := c.ToCobraCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(args)
+	err := cmd.ExecuteContext(ctx)
+	return out.String(), err
+}
+
+func TestJobRunExitCodes(t *testing.T) {
+	var gotArgs map[string]any
+	c := newTestJobRunCommand(t, nil,
+		&funcJob{name: "ok", exec: func(_ context.Context, args map[string]any) error {
+			gotArgs = args
+			return nil
+		}},
+		&funcJob{name: "fail", exec: func(context.Context, map[string]any) error {
+			return errors.New("boom")
+		}},
+		&funcJob{name: "wait", exec: func(ctx context.Context, _ map[string]any) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, tc := range []struct {
+		name string
+		ctx  context.Context
+		args []string
+		want int
+	}{
+		{"success", context.Background(), []string{"ok", "--arg", "a=1"}, ExitOK},
+		{"unknown job", context.Background(), []string{"missing"}, ExitUsage},
+		{"malformed arg", context.Background(), []string{"ok", "--arg", "novalue"}, ExitUsage},
+		{"malformed json", context.Background(), []string{"ok", "--args-json", "{"}, ExitUsage},
+		{"main job with tenant", context.Background(), []string{"ok", "--tenant", "acme"}, ExitUsage},
+		{"job fails", context.Background(), []string{"fail"}, ExitFailure},
+		{"interrupted", cancelled, []string{"wait"}, ExitInterrupted},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runJobCommand(tc.ctx, c, tc.args...)
+			if got := ExitCode(err); got != tc.want {
+				t.Errorf("exit code %d (err %v), want %d", got, err, tc.want)
+			}
+		})
+	}
+
+	if want := map[string]any{"a": "1"}; !reflect.DeepEqual(gotArgs, want) {
+		t.Errorf("job args = %v, want %v", gotArgs, want)
+	}
+}
+
+func TestJobRunAsyncEnqueues(t *testing.T) {
+	enq := &fakeEnqueuer{}
+	ran := false
+	c := newTestJobRunCommand(t, enq,
+		&funcJob{name: "ok", exec: func(context.Context, map[string]any) error {
+			ran = true
+			return nil
+		}},
+		&funcJob{name: "per-tenant", tenant: true, exec: func(context.Context, map[string]any) error {
+			ran = true
+			return nil
+		}},
+	)
+
+	out, err := runJobCommand(context.Background(), c, "ok", "--async", "--arg", "a=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ran {
+		t.Error("--async ran the job in the command")
+	}
+	want := []enqueued{{name: "ok", args: map[string]any{"a": "1"}}}
+	if !reflect.DeepEqual(enq.calls, want) {
+		t.Errorf("enqueued %v, want %v", enq.calls, want)
+	}
+	if !strings.Contains(out, `job "ok" enqueued`) {
+		t.Errorf("output %q does not report the enqueue", out)
+	}
+
+	_, err = runJobCommand(context.Background(), c, "per-tenant", "--async")
+	if got := ExitCode(err); got != ExitUsage {
+		t.Errorf("async fan-out: exit code %d (err %v), want %d", got, err, ExitUsage)
+	}
+	if len(enq.calls) != 1 || ran {
+		t.Errorf("async fan-out enqueued %v", enq.calls[1:])
+	}
+}
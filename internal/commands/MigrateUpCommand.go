@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"skyrix/internal/engine/migrate"
+
+	"github.com/spf13/cobra"
+)
+
+// MigrateUpCommand applies pending SQL migrations to the main database.
+type MigrateUpCommand struct {
+	Migrator *migrate.Migrator
+}
+
+func NewMigrateUpCommand(migrator *migrate.Migrator) *MigrateUpCommand {
+	return &MigrateUpCommand{Migrator: migrator}
+}
+
+func (c *MigrateUpCommand) ToCobraCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate:up",
+		Short: "Apply pending database migrations",
+		Long: `Applies the <version>_<name>.up.sql files in DB_MIGRATIONS_DIR that are not recorded in
+schema_migrations yet, in version order, each in its own transaction.
+The run holds a distributed lock, so a second migrate:up (e.g. from another pod) waits for the
+first one and then applies only what is left. Stops on SIGINT/SIGTERM.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			applied, err := c.Migrator.Up(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return exitErr(ExitInterrupted, err)
+				}
+				return exitErr(ExitFailure, err)
+			}
+			if len(applied) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "nothing to migrate")
+				return nil
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "applied %d migrations: %s\n", len(applied), strings.Join(applied, ", "))
+			return nil
+		},
+	}
+}
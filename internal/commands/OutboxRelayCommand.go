@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"skyrix/internal/engine/outbox"
+
+	"github.com/spf13/cobra"
+)
+
+// OutboxRelayCommand runs the outbox relay worker until interrupted.
+type OutboxRelayCommand struct {
+	Relay *outbox.Relay
+}
+
+func NewOutboxRelayCommand(relay *outbox.Relay) *OutboxRelayCommand {
+	return &OutboxRelayCommand{Relay: relay}
+}
+
+func (c *OutboxRelayCommand) ToCobraCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "outbox:relay",
+		Short: "Run the outbox relay worker",
+		Long: `Polls the outbox table and publishes pending messages to the configured sink (OUTBOX_SINK).
+Several relays may run at once; rows are locked with FOR UPDATE SKIP LOCKED. Stops on SIGINT/SIGTERM.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.Relay.Run(cmd.Context())
+		},
+	}
+}
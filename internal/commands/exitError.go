@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exit codes returned by console commands.
+const (
+	ExitOK          = 0
+	ExitFailure     = 1 // command ran but the work failed
+	ExitUsage       = 2 // invalid input (unknown job, bad args, unknown tenant)
+	ExitInterrupted = 130
+)
+
+// ExitError carries a process exit code alongside the underlying error.
+// cmd/console unwraps it to decide the exit status.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit code %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
+func exitErr(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExitError{Code: code, Err: err}
+}
+
+// ExitCode maps an error returned by a command to a process exit code.
+// nil -> 0, *ExitError -> its code, anything else -> 1.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var e *ExitError
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ExitFailure
+}
@@ -13,10 +13,14 @@ type Config struct {
 	HttpServer  `yaml:"HTTP_SERVER" env:"HTTP_SERVER"`
 	Database    `yaml:"DATABASE" env:"DATABASE"`
 	Redis       `yaml:"REDIS" env:"REDIS"`
+	Cache       `yaml:"CACHE" env:"CACHE"`
 	JWT         `yaml:"JWT" env:"JWT"`
 	Queue       `yaml:"QUEUE" env:"QUEUE"`
 	OAuth       `yaml:"OAUTH" env:"OAUTH"`
 	TenantCache `yaml:"TENANT_CACHE" env:"TENANT_CACHE"`
+	Outbox      `yaml:"OUTBOX" env:"OUTBOX"`
+	Health      `yaml:"HEALTH" env:"HEALTH"`
+	Metrics     `yaml:"METRICS" env:"METRICS"`
 }
 
 type Logger struct {
@@ -30,21 +34,82 @@ type HttpServer struct {
 	Address string        `yaml:"APP_ADDRESS" env:"APP_ADDRESS"`
 	Port    int           `yaml:"APP_PORT" env:"APP_PORT"`
 	Timeout time.Duration `yaml:"APP_REQUEST_TIMEOUT" env:"APP_REQUEST_TIMEOUT" env-default:"5s"`
+
+	// Response cache for the routes wrapped with middleware.ResponseCacheMiddleware.
+	ResponseCacheEnabled bool          `yaml:"APP_RESPONSE_CACHE_ENABLED" env:"APP_RESPONSE_CACHE_ENABLED" env-default:"true"`
+	ResponseCacheTTL     time.Duration `yaml:"APP_RESPONSE_CACHE_TTL" env:"APP_RESPONSE_CACHE_TTL" env-default:"1m"`
+	ResponseCacheVary    []string      `yaml:"APP_RESPONSE_CACHE_VARY" env:"APP_RESPONSE_CACHE_VARY" env-separator:"," env-default:"Accept-Language"` // request headers selecting the variant
+	ResponseCacheMaxBody int64         `yaml:"APP_RESPONSE_CACHE_MAX_BODY" env:"APP_RESPONSE_CACHE_MAX_BODY" env-default:"1048576"`                   // larger responses are not cached
+
+	// Idempotency-Key handling (middleware.IdempotencyMiddleware).
+	IdempotencyTTL     time.Duration `yaml:"APP_IDEMPOTENCY_TTL" env:"APP_IDEMPOTENCY_TTL" env-default:"24h"`               // how long responses are replayed
+	IdempotencyMaxBody int64         `yaml:"APP_IDEMPOTENCY_MAX_BODY" env:"APP_IDEMPOTENCY_MAX_BODY" env-default:"1048576"` // larger bodies are not replayed, only status and headers
+
+	// Graceful shutdown (kernel.HTTPApp): readiness fails for ShutdownGrace while the server
+	// still serves, then draining and the stop hooks share ShutdownTimeout.
+	ShutdownGrace   time.Duration `yaml:"APP_SHUTDOWN_GRACE" env:"APP_SHUTDOWN_GRACE" env-default:"5s"`
+	ShutdownTimeout time.Duration `yaml:"APP_SHUTDOWN_TIMEOUT" env:"APP_SHUTDOWN_TIMEOUT" env-default:"20s"`
 }
 type Database struct {
-	Host       string `yaml:"DB_HOST" env:"DB_HOST"`
-	Port       int    `yaml:"DB_PORT" env:"DB_PORT"`
-	User       string `yaml:"DB_USER" env:"DB_USER"`
-	Pass       string `yaml:"DB_PASS" env:"DB_PASS"`
-	Name       string `yaml:"DB_NAME" env:"DB_NAME"`
-	MainSchema string `yaml:"DB_MAIN_SCHEMA" env:"DB_MAIN_SCHEMA"`
+	Host        string `yaml:"DB_HOST" env:"DB_HOST"`
+	Port        int    `yaml:"DB_PORT" env:"DB_PORT"`
+	User        string `yaml:"DB_USER" env:"DB_USER"`
+	Pass        string `yaml:"DB_PASS" env:"DB_PASS"`
+	Name        string `yaml:"DB_NAME" env:"DB_NAME"`
+	MainSchema  string `yaml:"DB_MAIN_SCHEMA" env:"DB_MAIN_SCHEMA"`
+	SSLMode     string `yaml:"DB_SSL_MODE" env:"DB_SSL_MODE" env-default:"disable"` // disable, require, verify-ca, verify-full
+	SSLRootCert string `yaml:"DB_SSL_ROOT_CERT" env:"DB_SSL_ROOT_CERT"`             // CA bundle for verify-ca/verify-full
+	AppName     string `yaml:"DB_APPLICATION_NAME" env:"DB_APPLICATION_NAME" env-default:"skyrix"`
+
+	MaxOpenConns     int           `yaml:"DB_MAX_OPEN_CONNS" env:"DB_MAX_OPEN_CONNS" env-default:"25"`
+	MaxIdleConns     int           `yaml:"DB_MAX_IDLE_CONNS" env:"DB_MAX_IDLE_CONNS" env-default:"10"`
+	ConnMaxLifetime  time.Duration `yaml:"DB_CONN_MAX_LIFETIME" env:"DB_CONN_MAX_LIFETIME" env-default:"30m"`
+	ConnMaxIdleTime  time.Duration `yaml:"DB_CONN_MAX_IDLE_TIME" env:"DB_CONN_MAX_IDLE_TIME" env-default:"5m"`
+	StatementTimeout time.Duration `yaml:"DB_STATEMENT_TIMEOUT" env:"DB_STATEMENT_TIMEOUT" env-default:"0s"` // 0 = server default
+	ConnectTimeout   time.Duration `yaml:"DB_CONNECT_TIMEOUT" env:"DB_CONNECT_TIMEOUT" env-default:"60s"`    // startup retry deadline
+	LogLevel         string        `yaml:"DB_LOG_LEVEL" env:"DB_LOG_LEVEL" env-default:"warn"`               // silent, error, warn, info
+
+	// TenantStrict makes tenant-scoped queries without a tenant in context fail instead of
+	// falling back to the main schema.
+	TenantStrict bool `yaml:"DB_TENANT_STRICT" env:"DB_TENANT_STRICT" env-default:"true"`
+
+	// MigrationsDir holds the "<version>_<name>.up.sql" files applied by migrate:up.
+	MigrationsDir string `yaml:"DB_MIGRATIONS_DIR" env:"DB_MIGRATIONS_DIR" env-default:"migrations"`
+
+	// Read replicas ("host" or "host:port"), sharing user, password and database name with the primary.
+	Replicas              []string      `yaml:"DB_REPLICAS" env:"DB_REPLICAS" env-separator:","`
+	ReplicaPolicy         string        `yaml:"DB_REPLICA_POLICY" env:"DB_REPLICA_POLICY" env-default:"round_robin"` // round_robin, least_lag
+	ReplicaMaxLag         time.Duration `yaml:"DB_REPLICA_MAX_LAG" env:"DB_REPLICA_MAX_LAG" env-default:"10s"`       // replicas lagging more are skipped
+	ReplicaHealthInterval time.Duration `yaml:"DB_REPLICA_HEALTH_INTERVAL" env:"DB_REPLICA_HEALTH_INTERVAL" env-default:"5s"`
+
+	// Pools of tenants with a dedicated database (entity.IsolationDatabase).
+	TenantDBMaxPools       int           `yaml:"DB_TENANT_DB_MAX_POOLS" env:"DB_TENANT_DB_MAX_POOLS" env-default:"32"`          // least recently used pools are closed beyond this
+	TenantDBMaxOpenConns   int           `yaml:"DB_TENANT_DB_MAX_OPEN_CONNS" env:"DB_TENANT_DB_MAX_OPEN_CONNS" env-default:"5"` // per tenant pool
+	TenantDBIdleTimeout    time.Duration `yaml:"DB_TENANT_DB_IDLE_TIMEOUT" env:"DB_TENANT_DB_IDLE_TIMEOUT" env-default:"15m"`   // unused pools are closed after this
+	TenantDBHealthInterval time.Duration `yaml:"DB_TENANT_DB_HEALTH_INTERVAL" env:"DB_TENANT_DB_HEALTH_INTERVAL" env-default:"30s"`
 }
 
 type Redis struct {
+	Mode string `yaml:"REDIS_MODE" env:"REDIS_MODE" env-default:"standalone"` // standalone, sentinel, cluster
 	Host string `yaml:"REDIS_HOST" env:"REDIS_HOST"`
 	Port int    `yaml:"REDIS_PORT" env:"REDIS_PORT"`
+	User string `yaml:"REDIS_USER" env:"REDIS_USER"` // ACL username; empty = default user
 	Pass string `yaml:"REDIS_PASS" env:"REDIS_PASS"`
-	Db   int    `yaml:"REDIS_DB" env:"REDIS_DB"`
+	Db   int    `yaml:"REDIS_DB" env:"REDIS_DB"` // must be 0 in cluster mode
+
+	// Addrs lists sentinel addresses (sentinel) or seed nodes (cluster) as "host:port".
+	// When empty, REDIS_HOST:REDIS_PORT is used.
+	Addrs        []string `yaml:"REDIS_ADDRS" env:"REDIS_ADDRS" env-separator:","`
+	MasterName   string   `yaml:"REDIS_MASTER_NAME" env:"REDIS_MASTER_NAME"` // sentinel master set
+	SentinelUser string   `yaml:"REDIS_SENTINEL_USER" env:"REDIS_SENTINEL_USER"`
+	SentinelPass string   `yaml:"REDIS_SENTINEL_PASS" env:"REDIS_SENTINEL_PASS"`
+
+	TLS           bool   `yaml:"REDIS_TLS" env:"REDIS_TLS"`
+	TLSCACert     string `yaml:"REDIS_TLS_CA_CERT" env:"REDIS_TLS_CA_CERT"`         // PEM CA bundle; empty = system roots
+	TLSCert       string `yaml:"REDIS_TLS_CERT" env:"REDIS_TLS_CERT"`               // client certificate (mutual TLS)
+	TLSKey        string `yaml:"REDIS_TLS_KEY" env:"REDIS_TLS_KEY"`                 // client key (mutual TLS)
+	TLSServerName string `yaml:"REDIS_TLS_SERVER_NAME" env:"REDIS_TLS_SERVER_NAME"` // defaults to the dialed host
+	TLSSkipVerify bool   `yaml:"REDIS_TLS_SKIP_VERIFY" env:"REDIS_TLS_SKIP_VERIFY"` // testing only
 }
 
 type JWT struct {
@@ -69,11 +134,43 @@ type OAuth struct {
 	AppleClientID     string `yaml:"APPLE_CLIENT_ID" env:"OAUTH_APPLE_CLIENT_ID"`
 }
 
+type Cache struct {
+	Backend          string        `yaml:"CACHE_BACKEND" env:"CACHE_BACKEND" env-default:"redis"` // redis, memory, tiered
+	MemoryMaxEntries int           `yaml:"CACHE_MEMORY_MAX_ENTRIES" env:"CACHE_MEMORY_MAX_ENTRIES" env-default:"100000"`
+	MemoryMaxBytes   int64         `yaml:"CACHE_MEMORY_MAX_BYTES" env:"CACHE_MEMORY_MAX_BYTES" env-default:"67108864"` // keys + values
+	L1TTL            time.Duration `yaml:"CACHE_L1_TTL" env:"CACHE_L1_TTL" env-default:"30s"`                          // tiered: max age of in-memory copies
+}
+
 type TenantCache struct {
 	TTL       time.Duration `yaml:"TENANT_CACHE_TTL" env:"TENANT_CACHE_TTL" env-default:"3m"`
 	KeyPrefix string        `yaml:"TENANT_CACHE_KEY_PREFIX" env:"TENANT_CACHE_KEY_PREFIX" env-default:"skyrix-delivery"`
 }
 
+type Outbox struct {
+	Sink          string        `yaml:"OUTBOX_SINK" env:"OUTBOX_SINK" env-default:"jobs"` // jobs, memory
+	PollInterval  time.Duration `yaml:"OUTBOX_POLL_INTERVAL" env:"OUTBOX_POLL_INTERVAL" env-default:"1s"`
+	BatchSize     int           `yaml:"OUTBOX_BATCH_SIZE" env:"OUTBOX_BATCH_SIZE" env-default:"100"`
+	MaxAttempts   int           `yaml:"OUTBOX_MAX_ATTEMPTS" env:"OUTBOX_MAX_ATTEMPTS" env-default:"10"`
+	Retention     time.Duration `yaml:"OUTBOX_RETENTION" env:"OUTBOX_RETENTION" env-default:"168h"`           // how long published rows are kept
+	DeadRetention time.Duration `yaml:"OUTBOX_DEAD_RETENTION" env:"OUTBOX_DEAD_RETENTION" env-default:"720h"` // how long dead rows are kept for inspection
+}
+
+type Health struct {
+	Timeout        time.Duration `yaml:"HEALTH_CHECK_TIMEOUT" env:"HEALTH_CHECK_TIMEOUT" env-default:"2s"`                     // per check
+	CacheTTL       time.Duration `yaml:"HEALTH_CACHE_TTL" env:"HEALTH_CACHE_TTL" env-default:"5s"`                             // results are reused this long
+	RequiredTables []string      `yaml:"HEALTH_REQUIRED_TABLES" env:"HEALTH_REQUIRED_TABLES" env-separator:"," env-default:""` // main-schema tables the migrations create
+	CheckQueue     bool          `yaml:"HEALTH_CHECK_QUEUE" env:"HEALTH_CHECK_QUEUE" env-default:"false"`                      // dial QUEUE_HOST:QUEUE_PORT
+	Version        string        `yaml:"HEALTH_VERSION" env:"HEALTH_VERSION" env-default:"1"`
+	ReleaseID      string        `yaml:"HEALTH_RELEASE_ID" env:"HEALTH_RELEASE_ID"`
+}
+
+type Metrics struct {
+	Enabled    bool   `yaml:"METRICS_ENABLED" env:"METRICS_ENABLED" env-default:"true"`
+	Path       string `yaml:"METRICS_PATH" env:"METRICS_PATH" env-default:"/metrics"`
+	MaxSeries  int    `yaml:"METRICS_MAX_SERIES" env:"METRICS_MAX_SERIES" env-default:"1000"`  // label combinations per metric, the rest is "_other"
+	MaxTenants int    `yaml:"METRICS_MAX_TENANTS" env:"METRICS_MAX_TENANTS" env-default:"100"` // distinct tenant label values, the rest is "_other"
+}
+
 func LoadConfig() (*Config, error) {
 	cfg := &Config{}
 
@@ -0,0 +1,17 @@
+// Package events holds integration event contracts shared between domains.
+// Domains import this package instead of each other; handlers are registered
+// on the engine event bus (skyrix/internal/engine/events).
+package events
+
+import "time"
+
+// OrderPlaced is published by the order domain once an order has been stored.
+type OrderPlaced struct {
+	OrderID      int64     `json:"order_id"`
+	SubscriberID int64     `json:"subscriber_id"`
+	Total        int64     `json:"total"` // minor units
+	Currency     string    `json:"currency"`
+	PlacedAt     time.Time `json:"placed_at"`
+}
+
+func (OrderPlaced) EventName() string { return "order.placed" }
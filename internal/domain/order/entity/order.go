@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// Order is a placed order. It lives in the tenant schema.
+//
+//	CREATE TABLE orders (
+//	    id            bigserial   PRIMARY KEY,
+//	    subscriber_id bigint      NOT NULL,
+//	    total         bigint      NOT NULL,
+//	    currency      text        NOT NULL,
+//	    placed_at     timestamptz NOT NULL
+//	);
+type Order struct {
+	ID           int64     `gorm:"column:id;primaryKey" json:"id"`
+	SubscriberID int64     `gorm:"column:subscriber_id;not null" json:"subscriber_id"`
+	Total        int64     `gorm:"column:total;not null" json:"total"` // minor units
+	Currency     string    `gorm:"column:currency;type:text;not null" json:"currency"`
+	PlacedAt     time.Time `gorm:"column:placed_at;not null" json:"placed_at"`
+}
+
+func (Order) TableName() string { return "orders" }
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"skyrix/internal/domain/order/entity"
+	"skyrix/internal/engine"
+	engineRepository "skyrix/internal/engine/repository"
+)
+
+type OrderRepository struct {
+	*engineRepository.Repository[entity.Order]
+}
+
+func NewOrderRepository(db *engine.Database) *OrderRepository {
+	return &OrderRepository{Repository: engineRepository.New[entity.Order](db)}
+}
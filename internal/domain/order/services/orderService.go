@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	domainEvents "skyrix/internal/domain/events"
+	"skyrix/internal/domain/order/entity"
+	"skyrix/internal/domain/order/repository"
+	"skyrix/internal/engine"
+	"skyrix/internal/engine/events"
+	"skyrix/internal/logger"
+)
+
+type OrderService struct {
+	Tx              engine.TransactionManager
+	OrderRepository *repository.OrderRepository
+	Events          events.Publisher
+	Logger          logger.Interface
+}
+
+func NewOrderService(
+	tx engine.TransactionManager,
+	orderRepository *repository.OrderRepository,
+	publisher events.Publisher,
+	logger logger.Interface,
+) *OrderService {
+	return &OrderService{
+		Tx:              tx,
+		OrderRepository: orderRepository,
+		Events:          publisher,
+		Logger:          logger,
+	}
+}
+
+// PlaceOrderInput is what a caller needs to place an order.
+type PlaceOrderInput struct {
+	SubscriberID int64
+	Total        int64 // minor units
+	Currency     string
+}
+
+// PlaceOrder stores the order and announces it in the same transaction, so subscribers
+// never hear about an order that was rolled back, and a failing synchronous subscriber
+// rolls the order back. After-commit subscribers run once, after the final attempt.
+func (s *OrderService) PlaceOrder(ctx context.Context, in PlaceOrderInput) (*entity.Order, error) {
+	order := &entity.Order{
+		SubscriberID: in.SubscriberID,
+		Total:        in.Total,
+		Currency:     strings.ToUpper(strings.TrimSpace(in.Currency)),
+		PlacedAt:     time.Now().UTC(),
+	}
+	err := s.Tx.Run(ctx, func(ctx context.Context) error {
+		if err := s.OrderRepository.Create(ctx, order); err != nil {
+			return err
+		}
+		tx, _ := engine.TxFrom(ctx)
+		return s.Events.Publish(ctx, tx, domainEvents.OrderPlaced{
+			OrderID:      order.ID,
+			SubscriberID: order.SubscriberID,
+			Total:        order.Total,
+			Currency:     order.Currency,
+			PlacedAt:     order.PlacedAt,
+		})
+	}, engine.WithRetry(3)) // only database work: safe to re-run on a deadlock
+	if err != nil {
+		return nil, err
+	}
+	s.Logger.Info("order placed", "order_id", order.ID, "subscriber_id", order.SubscriberID)
+	return order, nil
+}
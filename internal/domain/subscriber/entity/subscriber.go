@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// Subscriber is the subscriber record. It lives in the tenant schema; only the columns
+// this domain maintains are mapped.
+type Subscriber struct {
+	ID          int64      `gorm:"column:id;primaryKey"`
+	OrdersCount int64      `gorm:"column:orders_count;not null;default:0"`
+	LastOrderAt *time.Time `gorm:"column:last_order_at"`
+}
+
+func (Subscriber) TableName() string { return "subscribers" }
@@ -0,0 +1,32 @@
+package listeners
+
+import (
+	"context"
+
+	domainEvents "skyrix/internal/domain/events"
+	"skyrix/internal/domain/subscriber/services"
+	"skyrix/internal/engine/events"
+
+	"gorm.io/gorm"
+)
+
+// OrderPlacedListener keeps the subscriber's order stats in step with placed orders.
+type OrderPlacedListener struct {
+	SubscriberService *services.SubscriberService
+}
+
+func NewOrderPlacedListener(subscriberService *services.SubscriberService) *OrderPlacedListener {
+	return &OrderPlacedListener{SubscriberService: subscriberService}
+}
+
+// Subscribe records the order inside the order's transaction: an order for an unknown
+// subscriber fails and is rolled back together with the stats update.
+func (l *OrderPlacedListener) Subscribe(b *events.Bus) {
+	events.Subscribe(b, l.onOrderPlaced)
+}
+
+func (l *OrderPlacedListener) onOrderPlaced(ctx context.Context, _ *gorm.DB, e domainEvents.OrderPlaced) error {
+	return l.SubscriberService.RecordOrder(ctx, e.SubscriberID, e.PlacedAt)
+}
+
+var _ events.Subscriber = (*OrderPlacedListener)(nil)
@@ -1,6 +1,7 @@
 package subscriber
 
 import (
+	"skyrix/internal/domain/subscriber/listeners"
 	"skyrix/internal/domain/subscriber/repository"
 	"skyrix/internal/domain/subscriber/services"
 
@@ -12,4 +13,5 @@ import (
 var ProviderSet = wire.NewSet(
 	services.NewSubscriberService,
 	repository.NewSubscriberRepository,
+	listeners.NewOrderPlacedListener,
 )
@@ -1,11 +1,19 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"skyrix/internal/domain/subscriber/entity"
 	"skyrix/internal/engine"
 	"skyrix/internal/logger"
 	"strings"
+	"time"
+
+	"gorm.io/gorm"
 )
 
+var ErrSubscriberNotFound = errors.New("subscriber not found")
+
 type SubscriberRepository struct {
 	DB           *engine.Database
 	DbMainSchema string
@@ -20,4 +28,23 @@ func NewSubscriberRepository(db *engine.Database, dbMainSchema string, logger lo
 	}
 }
 
+// RecordOrder counts one more order for the subscriber and moves last_order_at to at.
+// The transaction in ctx is joined.
+func (r *SubscriberRepository) RecordOrder(ctx context.Context, subscriberID int64, at time.Time) error {
+	res := r.DB.WithContext(ctx).
+		Model(&entity.Subscriber{}).
+		Where("id = ?", subscriberID).
+		Updates(map[string]any{
+			"orders_count":  gorm.Expr("orders_count + 1"),
+			"last_order_at": at,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSubscriberNotFound
+	}
+	return nil
+}
+
 func normLower(s string) string { return strings.ToLower(strings.TrimSpace(s)) }
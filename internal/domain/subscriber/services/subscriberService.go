@@ -1,6 +1,9 @@
 package services
 
 import (
+	"context"
+	"time"
+
 	"skyrix/internal/domain/subscriber/repository"
 	"skyrix/internal/logger"
 )
@@ -24,3 +27,8 @@ const (
 	bucket     = "core"
 	collection = "list"
 )
+
+// RecordOrder updates the subscriber's order stats for an order placed at placedAt.
+func (s *SubscriberService) RecordOrder(ctx context.Context, subscriberID int64, placedAt time.Time) error {
+	return s.SubscriberRepository.RecordOrder(ctx, subscriberID, placedAt)
+}
@@ -17,13 +17,13 @@ import (
 // RedisAuthStore provides a high-level interface for Redis operations including passport management,
 // token blacklisting, session management, and generic key-value operations.
 type RedisAuthStore struct {
-	client    *redis.Client
+	client    redis.UniversalClient
 	logger    logger.Interface
 	keyPrefix string
 	statusTTL time.Duration
 }
 
-func NewRedisAuthStore(client *redis.Client, lg logger.Interface, storeOpts contracts.StoreOpts) *RedisAuthStore {
+func NewRedisAuthStore(client redis.UniversalClient, lg logger.Interface, storeOpts contracts.StoreOpts) *RedisAuthStore {
 	prefix := strings.TrimSuffix(strings.TrimSpace(storeOpts.KeyPrefix), ":")
 	if prefix == "" {
 		prefix = "skyrix-catalog"
@@ -163,14 +163,14 @@ func (r *RedisAuthStore) SetPassport(ctx context.Context, scope contracts.Scope,
 }
 
 // SetPassportBoth stores a subscriber passport under both namespace and domain keys.
-// Allows lookup by either identifier. Uses Redis pipeline for atomic operations.
+// Allows lookup by either identifier. Uses a Redis pipeline (a transaction outside cluster mode).
 // TTL is calculated from activeTo. Returns error if passport is nil.
 func (r *RedisAuthStore) SetPassportBoth(ctx context.Context, namespace, domain string, passport *contracts.Passport) error {
 	if passport == nil {
 		return errors.New("passport cannot be nil")
 	}
 	ttl := r.ttlForPassport(passport)
-	pipe := r.client.TxPipeline()
+	pipe := r.pipeline()
 	pipe.HSet(ctx, r.kPassport(contracts.ScopeNamespace, namespace), passportToHash(passport))
 	pipe.Expire(ctx, r.kPassport(contracts.ScopeNamespace, namespace), ttl)
 	pipe.HSet(ctx, r.kPassport(contracts.ScopeDomain, domain), passportToHash(passport))
@@ -179,6 +179,15 @@ func (r *RedisAuthStore) SetPassportBoth(ctx context.Context, namespace, domain
 	return err
 }
 
+// pipeline returns a transaction pipeline, or a plain one in cluster mode where the keys of
+// one call may live in different hash slots (MULTI cannot span slots).
+func (r *RedisAuthStore) pipeline() redis.Pipeliner {
+	if _, cluster := r.client.(*redis.ClusterClient); cluster {
+		return r.client.Pipeline()
+	}
+	return r.client.TxPipeline()
+}
+
 // InvalidatePassport removes a subscriber passport from Redis.
 // Typically called when a subscription is deactivated or deleted.
 func (r *RedisAuthStore) InvalidatePassport(ctx context.Context, scope contracts.Scope, id string) error {
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+const envelopeVersion byte = 1
+
+var errBadEnvelope = errors.New("cache: malformed entry")
+
+// envelope is what TypedCache stores: the encoded value plus what early expiration and
+// tag invalidation need.
+//
+//	version(1) | expires unix nanos(8) | load time nanos(8) | tag count(uvarint)
+//	| per tag: name len(uvarint) name, version len(uvarint) version | payload
+type envelope struct {
+	expires time.Time // zero = no expiry
+	delta   time.Duration
+	tags    []tagVersion
+	payload []byte
+}
+
+type tagVersion struct {
+	tag     string
+	version string
+}
+
+func (e *envelope) marshal() []byte {
+	size := 1 + 8 + 8 + binary.MaxVarintLen64 + len(e.payload)
+	for _, t := range e.tags {
+		size += 2*binary.MaxVarintLen64 + len(t.tag) + len(t.version)
+	}
+	b := make([]byte, 0, size)
+	b = append(b, envelopeVersion)
+	var expires int64
+	if !e.expires.IsZero() {
+		expires = e.expires.UnixNano()
+	}
+	b = binary.BigEndian.AppendUint64(b, uint64(expires))
+	b = binary.BigEndian.AppendUint64(b, uint64(e.delta))
+	b = binary.AppendUvarint(b, uint64(len(e.tags)))
+	for _, t := range e.tags {
+		b = binary.AppendUvarint(b, uint64(len(t.tag)))
+		b = append(b, t.tag...)
+		b = binary.AppendUvarint(b, uint64(len(t.version)))
+		b = append(b, t.version...)
+	}
+	return append(b, e.payload...)
+}
+
+func unmarshalEnvelope(b []byte) (*envelope, error) {
+	if len(b) < 17 || b[0] != envelopeVersion {
+		return nil, errBadEnvelope
+	}
+	e := &envelope{}
+	if n := int64(binary.BigEndian.Uint64(b[1:9])); n != 0 {
+		e.expires = time.Unix(0, n)
+	}
+	e.delta = time.Duration(binary.BigEndian.Uint64(b[9:17]))
+	b = b[17:]
+
+	count, n := binary.Uvarint(b)
+	if n <= 0 || count > uint64(len(b)) {
+		return nil, errBadEnvelope
+	}
+	b = b[n:]
+	readString := func() (string, bool) {
+		l, n := binary.Uvarint(b)
+		if n <= 0 || uint64(len(b)-n) < l {
+			return "", false
+		}
+		s := string(b[n : n+int(l)])
+		b = b[n+int(l):]
+		return s, true
+	}
+	for i := uint64(0); i < count; i++ {
+		tag, ok := readString()
+		if !ok {
+			return nil, errBadEnvelope
+		}
+		version, ok := readString()
+		if !ok {
+			return nil, errBadEnvelope
+		}
+		e.tags = append(e.tags, tagVersion{tag: tag, version: version})
+	}
+	e.payload = b
+	return e, nil
+}
+
+// expired reports whether the entry is past its logical expiry.
+func (e *envelope) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// refreshEarly implements probabilistic early expiration (XFetch): the closer the entry is
+// to expiry and the longer it took to load, the likelier one caller reloads it ahead of time,
+// so a hot key does not expire for everyone at once.
+func (e *envelope) refreshEarly(now time.Time, beta float64) bool {
+	if e.expires.IsZero() || beta <= 0 || e.delta <= 0 {
+		return false
+	}
+	gap := time.Duration(float64(e.delta) * beta * -math.Log(1-rand.Float64()))
+	return !now.Add(gap).Before(e.expires)
+}
@@ -0,0 +1,17 @@
+package cache
+
+import (
+	"skyrix/internal/config"
+	"skyrix/internal/engine"
+
+	"github.com/google/wire"
+)
+
+// ProviderSet wires the tenant-scoped cache over the configured engine.Cache.
+var ProviderSet = wire.NewSet(
+	ProvideTenantCache,
+)
+
+func ProvideTenantCache(c engine.Cache, cfg *config.Config) *TenantCache {
+	return NewTenantCache(c, cfg.TenantCache.KeyPrefix)
+}
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"skyrix/internal/engine"
+)
+
+// Tags are versioned: "<prefix>:tag:<tag>" holds the tag's current version and every entry
+// records the versions of its tags when written. Invalidating a tag replaces its version, so
+// all entries written before become stale at once without enumerating keys. A read of a
+// tagged entry costs one extra cache read per tag, plus a TTL refresh where the cache
+// supports it (engine.Expirer).
+//
+// Tag keys expire (Options.TagTTL), so tags of deleted tenants or records do not pile up.
+// Every write and read of a tagged entry pushes its tags' expiry past the entry's own, so a
+// tag outlives the entries recording it. Should a tag expire anyway, it is recreated with a
+// new version: its old entries turn into misses, never into stale hits.
+
+// InvalidateTags drops every entry tagged with one of tags, across all TypedCaches
+// sharing prefix (the application key prefix).
+func InvalidateTags(ctx context.Context, c engine.Cache, prefix string, tags ...string) error {
+	return invalidate(ctx, c, joinKey(strings.TrimSuffix(strings.TrimSpace(prefix), ":"), "tag"), defaultTagTTL, tags)
+}
+
+// InvalidateTags drops every entry tagged with one of tags (in any namespace).
+func (c *TypedCache[T]) InvalidateTags(ctx context.Context, tags ...string) error {
+	return invalidate(ctx, c.cache, c.tagPrefix, c.tagTTL, tags)
+}
+
+// invalidate replaces the versions of tags. The TTL only has to cover entries written from
+// now on, which refresh it themselves.
+func invalidate(ctx context.Context, c engine.Cache, tagPrefix string, ttl time.Duration, tags []string) error {
+	for _, tag := range tags {
+		if err := c.Set(ctx, joinKey(tagPrefix, tag), newTagVersion(), ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tagTTLFor returns how long tag keys must live for an entry expiring in entryTTL.
+func (c *TypedCache[T]) tagTTLFor(entryTTL time.Duration) time.Duration {
+	return max(c.tagTTL, entryTTL)
+}
+
+// touchTag extends the TTL of a tag key without changing its version.
+func (c *TypedCache[T]) touchTag(ctx context.Context, key string, ttl time.Duration) error {
+	if e, ok := c.cache.(engine.Expirer); ok {
+		_, err := e.Expire(ctx, key, ttl)
+		return err
+	}
+	return nil
+}
+
+// tagVersions returns the current versions of tags for an entry living entryTTL, creating
+// missing ones and extending the others.
+func (c *TypedCache[T]) tagVersions(ctx context.Context, tags []string, entryTTL time.Duration) ([]tagVersion, error) {
+	ttl := c.tagTTLFor(entryTTL)
+	out := make([]tagVersion, 0, len(tags))
+	for _, tag := range tags {
+		key := joinKey(c.tagPrefix, tag)
+		b, ok, err := c.cache.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if err := c.touchTag(ctx, key, ttl); err != nil {
+				return nil, err
+			}
+		} else {
+			if _, err := c.cache.SetNX(ctx, key, newTagVersion(), ttl); err != nil {
+				return nil, err
+			}
+			// Read back: a concurrent writer may have created it first.
+			if b, ok, err = c.cache.Get(ctx, key); err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, fmt.Errorf("cache: tag %q vanished while writing", tag)
+			}
+		}
+		out = append(out, tagVersion{tag: tag, version: string(b)})
+	}
+	return out, nil
+}
+
+// tagsCurrent reports whether none of the recorded tag versions changed, and keeps the tags
+// alive for an entry with entryTTL left.
+func (c *TypedCache[T]) tagsCurrent(ctx context.Context, tags []tagVersion, entryTTL time.Duration) (bool, error) {
+	ttl := c.tagTTLFor(entryTTL)
+	for _, t := range tags {
+		key := joinKey(c.tagPrefix, t.tag)
+		b, ok, err := c.cache.Get(ctx, key)
+		if err != nil || !ok || string(b) != t.version {
+			return false, err
+		}
+		if err := c.touchTag(ctx, key, ttl); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func newTagVersion() []byte {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return []byte(hex.EncodeToString(b[:]))
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"skyrix/internal/engine"
+)
+
+// ttlCache records the TTL last given to each key through Set, SetNX or Expire.
+type ttlCache struct {
+	*engine.MemoryCache
+	mu   sync.Mutex
+	ttls map[string]time.Duration
+}
+
+func newTTLCache(t *testing.T) *ttlCache {
+	m := engine.NewMemoryCache(engine.MemoryOpts{})
+	t.Cleanup(func() { _ = m.Close() })
+	return &ttlCache{MemoryCache: m, ttls: map[string]time.Duration{}}
+}
+
+func (c *ttlCache) record(key string, ttl time.Duration) {
+	c.mu.Lock()
+	c.ttls[key] = ttl
+	c.mu.Unlock()
+}
+
+func (c *ttlCache) ttl(key string) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ttl, ok := c.ttls[key]
+	return ttl, ok
+}
+
+func (c *ttlCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	c.record(key, ttl)
+	return c.MemoryCache.Set(ctx, key, val, ttl)
+}
+
+func (c *ttlCache) SetNX(ctx context.Context, key string, val []byte, ttl time.Duration) (bool, error) {
+	ok, err := c.MemoryCache.SetNX(ctx, key, val, ttl)
+	if ok {
+		c.record(key, ttl)
+	}
+	return ok, err
+}
+
+func (c *ttlCache) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ok, err := c.MemoryCache.Expire(ctx, key, ttl)
+	if ok {
+		c.record(key, ttl)
+	}
+	return ok, err
+}
+
+func TestTagKeysExpireNoEarlierThanEntries(t *testing.T) {
+	ctx := context.Background()
+	c := newTTLCache(t)
+	tc := NewTyped[string](c, Options{Prefix: "app", Namespace: "users", TTL: time.Minute, TagTTL: time.Hour})
+	const tagKey = "app:tag:tenant:acme"
+
+	if err := tc.Set(ctx, "1", "ann", WithTags("tenant:acme")); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, _ := c.ttl(tagKey); ttl != time.Hour {
+		t.Fatalf("tag TTL after a write = %v, want the 1h tag TTL", ttl)
+	}
+
+	// An entry outliving the tag TTL stretches the tag to its own TTL.
+	if err := tc.Set(ctx, "2", "bob", WithTags("tenant:acme"), WithTTL(48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, _ := c.ttl(tagKey); ttl != 48*time.Hour {
+		t.Fatalf("tag TTL after a 48h write = %v, want 48h", ttl)
+	}
+
+	// Reads keep the tag alive.
+	c.record(tagKey, 0)
+	if v, ok, err := tc.Get(ctx, "1"); err != nil || !ok || v != "ann" {
+		t.Fatalf("Get = %q, %v, %v", v, ok, err)
+	}
+	if ttl, _ := c.ttl(tagKey); ttl != time.Hour {
+		t.Fatalf("tag TTL after a read = %v, want 1h", ttl)
+	}
+
+	if err := tc.InvalidateTags(ctx, "tenant:acme"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, _ := c.ttl(tagKey); ttl <= 0 {
+		t.Fatalf("tag TTL after invalidation = %v, want a finite TTL", ttl)
+	}
+	if _, ok, _ := tc.Get(ctx, "1"); ok {
+		t.Fatal("entry survived the invalidation of its tag")
+	}
+}
+
+func TestTagTTLIsAtLeastEntryTTL(t *testing.T) {
+	tc := NewTyped[string](newTTLCache(t), Options{TTL: 2 * time.Hour, TagTTL: time.Minute})
+	if tc.tagTTL != 2*time.Hour {
+		t.Fatalf("tag TTL = %v, want the 2h entry TTL", tc.tagTTL)
+	}
+}
+
+func TestExpiredTagMakesEntriesMiss(t *testing.T) {
+	ctx := context.Background()
+	c := newTTLCache(t)
+	tc := NewTyped[string](c, Options{Prefix: "app", Namespace: "users"})
+
+	if err := tc.Set(ctx, "1", "ann", WithTags("tenant:acme")); err != nil {
+		t.Fatal(err)
+	}
+	// The tag key is gone (expired or evicted) and gets recreated with a new version.
+	if err := c.Del(ctx, "app:tag:tenant:acme"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := tc.Get(ctx, "1"); ok {
+		t.Fatal("entry of a vanished tag was served")
+	}
+	if err := tc.Set(ctx, "2", "bob", WithTags("tenant:acme")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := tc.Get(ctx, "1"); ok {
+		t.Fatal("old entry matched the recreated tag version")
+	}
+	if v, ok, _ := tc.Get(ctx, "2"); !ok || v != "bob" {
+		t.Fatalf("new entry: got %q, %v", v, ok)
+	}
+}
@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"skyrix/internal/engine"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+)
+
+var (
+	// ErrNoTenant is returned by a tenant-scoped TenantCache when ctx carries no tenant.
+	// Use Global() for data shared by all tenants.
+	ErrNoTenant = errors.New("cache: no tenant in context")
+	// ErrNoScan is returned by FlushTenant when the backend cannot enumerate keys.
+	ErrNoScan = errors.New("cache: backend does not support key scans")
+	// ErrNoCompareDelete is returned by DelIfValue when the backend cannot compare and delete.
+	ErrNoCompareDelete = errors.New("cache: backend does not support compare-and-delete")
+)
+
+const flushBatch = 500
+
+// TenantCache is an engine.Cache that namespaces every key by the tenant in ctx, so a tenant
+// can never read or overwrite another tenant's entries:
+//
+//	tenant scope: "<prefix>:t:<tenant>:<key>" (tenant is tenantContext.Key: schema or "schema#id")
+//	global scope: "<prefix>:global:<key>"
+//
+// Tenant-scoped calls without a tenant in ctx fail with ErrNoTenant instead of falling back to
+// a shared key. Layer a TypedCache on top (with an empty Options.Prefix) to get typed values,
+// GetOrLoad single-flight and tags that are tenant-scoped too.
+type TenantCache struct {
+	cache  engine.Cache
+	prefix string
+	global bool
+}
+
+var (
+	_ engine.Cache          = (*TenantCache)(nil)
+	_ engine.Expirer        = (*TenantCache)(nil)
+	_ engine.CompareDeleter = (*TenantCache)(nil)
+)
+
+// NewTenantCache returns the tenant-scoped cache. prefix is the application key prefix
+// (config TENANT_CACHE_KEY_PREFIX).
+func NewTenantCache(c engine.Cache, prefix string) *TenantCache {
+	prefix = strings.TrimSuffix(strings.TrimSpace(prefix), ":")
+	if prefix == "" {
+		prefix = "skyrix-delivery"
+	}
+	return &TenantCache{cache: c, prefix: prefix}
+}
+
+// Global returns a view of the same cache for data shared by all tenants; it ignores the
+// tenant in ctx.
+func (c *TenantCache) Global() *TenantCache {
+	return &TenantCache{cache: c.cache, prefix: c.prefix, global: true}
+}
+
+// Key returns the full backend key of key in ctx's scope.
+func (c *TenantCache) Key(ctx context.Context, key string) (string, error) {
+	if c.global {
+		return joinKey(c.prefix, "global", key), nil
+	}
+	if tenantContext.SchemaFrom(ctx) == "" {
+		return "", ErrNoTenant
+	}
+	return joinKey(c.prefix, "t", tenantContext.Key(ctx), key), nil
+}
+
+func (c *TenantCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	full, err := c.Key(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	return c.cache.Get(ctx, full)
+}
+
+func (c *TenantCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	full, err := c.Key(ctx, key)
+	if err != nil {
+		return err
+	}
+	return c.cache.Set(ctx, full, val, ttl)
+}
+
+func (c *TenantCache) SetNX(ctx context.Context, key string, val []byte, ttl time.Duration) (bool, error) {
+	full, err := c.Key(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	return c.cache.SetNX(ctx, full, val, ttl)
+}
+
+func (c *TenantCache) Del(ctx context.Context, key string) error {
+	full, err := c.Key(ctx, key)
+	if err != nil {
+		return err
+	}
+	return c.cache.Del(ctx, full)
+}
+
+func (c *TenantCache) Exists(ctx context.Context, key string) (bool, error) {
+	full, err := c.Key(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	return c.cache.Exists(ctx, full)
+}
+
+// DelIfValue deletes key if it still holds val; it needs a backend implementing
+// engine.CompareDeleter.
+func (c *TenantCache) DelIfValue(ctx context.Context, key string, val []byte) (bool, error) {
+	full, err := c.Key(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	cd, ok := c.cache.(engine.CompareDeleter)
+	if !ok {
+		return false, ErrNoCompareDelete
+	}
+	return cd.DelIfValue(ctx, full, val)
+}
+
+// Expire sets key's TTL if the backend supports it (engine.Expirer); otherwise it reports false.
+func (c *TenantCache) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	full, err := c.Key(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	e, ok := c.cache.(engine.Expirer)
+	if !ok {
+		return false, nil
+	}
+	return e.Expire(ctx, full, ttl)
+}
+
+// InvalidateTags drops every entry tagged with one of tags in ctx's scope, for TypedCaches
+// layered on this cache with an empty Options.Prefix.
+func (c *TenantCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	return InvalidateTags(ctx, c, "", tags...)
+}
+
+// FlushTenant deletes every entry of the tenant with schema, including all shared-schema
+// tenants of that schema, and returns how many keys were deleted. Global entries are kept.
+// It needs a backend implementing engine.KeyScanner.
+func (c *TenantCache) FlushTenant(ctx context.Context, schema string) (int, error) {
+	schema = strings.TrimSpace(schema)
+	if schema == "" {
+		return 0, ErrNoTenant
+	}
+	sc, ok := c.cache.(engine.KeyScanner)
+	if !ok {
+		return 0, ErrNoScan
+	}
+	base := globEscape(joinKey(c.prefix, "t", schema))
+	deleted := 0
+	for _, pattern := range []string{base + ":*", base + "#*"} {
+		var cursor uint64
+		for {
+			keys, next, err := sc.ScanKeys(ctx, pattern, flushBatch, cursor)
+			if err != nil {
+				return deleted, err
+			}
+			if len(keys) > 0 {
+				if err := sc.DelMany(ctx, keys); err != nil {
+					return deleted, err
+				}
+				deleted += len(keys)
+			}
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// globEscape quotes the glob metacharacters of s for ScanKeys patterns.
+func globEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
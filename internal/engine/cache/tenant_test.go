@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"skyrix/internal/engine"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+)
+
+func newTenantCache(t *testing.T) (*TenantCache, *engine.MemoryCache) {
+	m := engine.NewMemoryCache(engine.MemoryOpts{})
+	t.Cleanup(func() { _ = m.Close() })
+	return NewTenantCache(m, "app:"), m
+}
+
+func TestTenantCacheKeys(t *testing.T) {
+	c, m := newTenantCache(t)
+	acme := tenantContext.WithSchema(context.Background(), "acme")
+	beta := tenantContext.WithSchema(context.Background(), "beta")
+
+	if err := c.Set(acme, "k", []byte("a"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Global().Set(acme, "k", []byte("g"), 0); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{"app:t:acme:k": "a", "app:global:k": "g"} {
+		if v, ok, _ := m.Get(context.Background(), key); !ok || string(v) != want {
+			t.Errorf("backend %s = %q, %v; want %q", key, v, ok, want)
+		}
+	}
+	if _, ok, _ := c.Get(beta, "k"); ok {
+		t.Error("another tenant read acme's entry")
+	}
+	if _, _, err := c.Get(context.Background(), "k"); !errors.Is(err, ErrNoTenant) {
+		t.Errorf("Get without tenant: got %v, want ErrNoTenant", err)
+	}
+}
+
+func TestTenantCacheDelIfValue(t *testing.T) {
+	c, _ := newTenantCache(t)
+	ctx := tenantContext.WithSchema(context.Background(), "acme")
+	if err := c.Set(ctx, "k", []byte("v1"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := c.DelIfValue(ctx, "k", []byte("v2")); err != nil || ok {
+		t.Fatalf("DelIfValue(other value) = %v, %v; want false", ok, err)
+	}
+	if ok, err := c.DelIfValue(ctx, "k", []byte("v1")); err != nil || !ok {
+		t.Fatalf("DelIfValue(current value) = %v, %v; want true", ok, err)
+	}
+	if ok, _ := c.Exists(ctx, "k"); ok {
+		t.Error("key still exists")
+	}
+}
+
+func TestTenantCacheFlushTenant(t *testing.T) {
+	c, m := newTenantCache(t)
+	bg := context.Background()
+	acme := tenantContext.WithSchema(bg, "acme")
+	acme7 := tenantContext.WithTenantID(acme, 7)
+	beta := tenantContext.WithSchema(bg, "beta")
+	for _, ctx := range []context.Context{acme, acme7, beta} {
+		if err := c.Set(ctx, "k", []byte("v"), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Global().Set(bg, "k", []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := c.FlushTenant(bg, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("deleted %d keys, want 2", n)
+	}
+	for key, want := range map[string]bool{"app:t:beta:k": true, "app:global:k": true} {
+		if ok, _ := m.Exists(bg, key); ok != want {
+			t.Errorf("%s exists = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestTypedOverTenantCacheLoadsPerTenant(t *testing.T) {
+	c, _ := newTenantCache(t)
+	typed := NewTyped[string](c, Options{Namespace: "names"})
+	acme := tenantContext.WithSchema(context.Background(), "acme")
+	shared := tenantContext.WithTenantID(acme, 7)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	acmeDone := make(chan string)
+	go func() {
+		v, _ := typed.GetOrLoad(acme, "k", func(context.Context) (string, error) {
+			close(started)
+			<-release
+			return "acme", nil
+		})
+		acmeDone <- v
+	}()
+	<-started
+
+	// acme's load is in flight: a load of the same key by another tenant must not join it.
+	ctx, cancel := context.WithTimeout(shared, time.Second)
+	defer cancel()
+	got, err := typed.GetOrLoad(ctx, "k", func(context.Context) (string, error) { return "acme#7", nil })
+	close(release)
+	if err != nil || got != "acme#7" {
+		t.Errorf("shared-schema tenant got %q, %v; want its own value", got, err)
+	}
+	if v := <-acmeDone; v != "acme" {
+		t.Errorf("acme got %q", v)
+	}
+}
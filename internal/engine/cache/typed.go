@@ -0,0 +1,246 @@
+// Package cache provides a typed, read-through cache on top of engine.Cache.
+//
+// TypedCache[T] encodes values with a pluggable Codec, loads misses through GetOrLoad with
+// single-flight (one load per key and instance), refreshes hot keys ahead of expiry and
+// supports tag invalidation (e.g. every entry tagged "tenant:acme").
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"skyrix/internal/engine"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultTTL    = 5 * time.Minute
+	defaultTagTTL = 24 * time.Hour
+)
+
+// Options configures a TypedCache.
+type Options struct {
+	// Prefix is the application key prefix (config TENANT_CACHE_KEY_PREFIX).
+	Prefix string
+	// Namespace separates value types: keys are "<Prefix>:<Namespace>:<key>".
+	Namespace string
+	// TTL of entries (default 5m); a per-call WithTTL overrides it.
+	TTL time.Duration
+	// Codec encodes values (default JSON).
+	Codec Codec
+	// Beta tunes early expiration: 1 is the usual choice, higher refreshes earlier,
+	// negative disables it. 0 means 1.
+	Beta float64
+	// TagTTL is how long a tag version survives without reads or writes of entries tagged
+	// with it (default 24h, never shorter than TTL).
+	TagTTL time.Duration
+}
+
+// TypedCache caches values of type T under one namespace.
+type TypedCache[T any] struct {
+	cache     engine.Cache
+	prefix    string
+	tagPrefix string
+	ttl       time.Duration
+	tagTTL    time.Duration
+	codec     Codec
+	beta      float64
+	group     singleflight.Group
+}
+
+func NewTyped[T any](c engine.Cache, opts Options) *TypedCache[T] {
+	prefix := strings.TrimSuffix(strings.TrimSpace(opts.Prefix), ":")
+	tc := &TypedCache[T]{
+		cache:     c,
+		prefix:    joinKey(prefix, opts.Namespace),
+		tagPrefix: joinKey(prefix, "tag"),
+		ttl:       opts.TTL,
+		codec:     opts.Codec,
+		beta:      opts.Beta,
+	}
+	if tc.ttl <= 0 {
+		tc.ttl = defaultTTL
+	}
+	tc.tagTTL = opts.TagTTL
+	if tc.tagTTL <= 0 {
+		tc.tagTTL = defaultTagTTL
+	}
+	tc.tagTTL = max(tc.tagTTL, tc.ttl)
+	if tc.codec == nil {
+		tc.codec = JSON
+	}
+	if tc.beta == 0 {
+		tc.beta = 1
+	}
+	return tc
+}
+
+func joinKey(parts ...string) string {
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.Trim(strings.TrimSpace(p), ":"); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, ":")
+}
+
+// Key returns the full engine.Cache key of key.
+func (c *TypedCache[T]) Key(key string) string { return joinKey(c.prefix, key) }
+
+// scopedCache is a backend that namespaces keys by ctx, such as TenantCache.
+type scopedCache interface {
+	Key(ctx context.Context, key string) (string, error)
+}
+
+// flightKey is the single-flight key of full: the backend's own key when the backend is
+// scoped by ctx, so callers of different tenants never share a load.
+func (c *TypedCache[T]) flightKey(ctx context.Context, full string) string {
+	if sc, ok := c.cache.(scopedCache); ok {
+		if scoped, err := sc.Key(ctx, full); err == nil {
+			return scoped
+		}
+	}
+	return full
+}
+
+// SetOption customizes one write.
+type SetOption func(*setOptions)
+
+type setOptions struct {
+	ttl    time.Duration
+	tags   []string
+	tagsOf func(v any) []string
+}
+
+// WithTTL overrides the cache's TTL for one entry.
+func WithTTL(ttl time.Duration) SetOption { return func(o *setOptions) { o.ttl = ttl } }
+
+// WithTags attaches tags; InvalidateTags of any of them drops the entry.
+func WithTags(tags ...string) SetOption {
+	return func(o *setOptions) { o.tags = append(o.tags, tags...) }
+}
+
+// WithTagsFrom derives tags from the value being stored, for GetOrLoad calls whose tags
+// are only known once the value is loaded.
+func WithTagsFrom[T any](fn func(v T) []string) SetOption {
+	return func(o *setOptions) {
+		o.tagsOf = func(v any) []string {
+			if tv, ok := v.(T); ok {
+				return fn(tv)
+			}
+			return nil
+		}
+	}
+}
+
+// Get returns the cached value. ok is false on a miss, an expired or invalidated entry,
+// or an entry that no longer decodes.
+func (c *TypedCache[T]) Get(ctx context.Context, key string) (T, bool, error) {
+	v, _, ok, err := c.lookup(ctx, c.Key(key))
+	return v, ok, err
+}
+
+// Set stores v under key.
+func (c *TypedCache[T]) Set(ctx context.Context, key string, v T, opts ...SetOption) error {
+	return c.store(ctx, c.Key(key), v, 0, opts)
+}
+
+// Del removes key.
+func (c *TypedCache[T]) Del(ctx context.Context, key string) error {
+	return c.cache.Del(ctx, c.Key(key))
+}
+
+// GetOrLoad returns the cached value or calls load and caches its result.
+//
+// Concurrent misses of one key in this process share a single load; over a TenantCache only
+// misses of the same tenant do. The load runs with ctx's
+// values but not its cancellation, so one caller giving up does not fail the others.
+// A hot entry close to expiry is reloaded early by one caller (see Options.Beta) while the
+// others keep reading it. Cache errors are not fatal: the value is loaded instead.
+// Errors of load are returned as is and nothing is cached.
+func (c *TypedCache[T]) GetOrLoad(ctx context.Context, key string, load func(ctx context.Context) (T, error), opts ...SetOption) (T, error) {
+	full := c.Key(key)
+	cached, env, ok, _ := c.lookup(ctx, full)
+	if ok && !env.refreshEarly(time.Now(), c.beta) {
+		return cached, nil
+	}
+
+	ch := c.group.DoChan(c.flightKey(ctx, full), func() (any, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		started := time.Now()
+		v, err := load(loadCtx)
+		if err != nil {
+			return v, err
+		}
+		_ = c.store(loadCtx, full, v, time.Since(started), opts)
+		return v, nil
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			if ok {
+				return cached, nil // early refresh failed; the entry is still valid
+			}
+			var zero T
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	case <-ctx.Done():
+		if ok {
+			return cached, nil
+		}
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+func (c *TypedCache[T]) lookup(ctx context.Context, full string) (T, *envelope, bool, error) {
+	var zero T
+	b, ok, err := c.cache.Get(ctx, full)
+	if err != nil || !ok {
+		return zero, nil, false, err
+	}
+	env, err := unmarshalEnvelope(b)
+	if err != nil || env.expired(time.Now()) {
+		return zero, nil, false, nil
+	}
+	if len(env.tags) > 0 {
+		fresh, err := c.tagsCurrent(ctx, env.tags, time.Until(env.expires))
+		if err != nil || !fresh {
+			return zero, nil, false, err
+		}
+	}
+	var v T
+	if err := c.codec.Unmarshal(env.payload, &v); err != nil {
+		return zero, nil, false, nil
+	}
+	return v, env, true, nil
+}
+
+func (c *TypedCache[T]) store(ctx context.Context, full string, v T, delta time.Duration, opts []SetOption) error {
+	o := setOptions{ttl: c.ttl}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.ttl <= 0 {
+		o.ttl = c.ttl
+	}
+	if o.tagsOf != nil {
+		o.tags = append(o.tags, o.tagsOf(v)...)
+	}
+	payload, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	env := &envelope{expires: time.Now().Add(o.ttl), delta: delta, payload: payload}
+	if len(o.tags) > 0 {
+		if env.tags, err = c.tagVersions(ctx, o.tags, o.ttl); err != nil {
+			return err
+		}
+	}
+	return c.cache.Set(ctx, full, env.marshal(), o.ttl)
+}
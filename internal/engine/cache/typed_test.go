@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"skyrix/internal/engine"
+)
+
+func newTestTyped(t *testing.T, opts Options) (*TypedCache[string], *engine.MemoryCache) {
+	t.Helper()
+	m := engine.NewMemoryCache(engine.MemoryOpts{})
+	t.Cleanup(func() { _ = m.Close() })
+	return NewTyped[string](m, opts), m
+}
+
+func TestGetOrLoadSharesConcurrentMisses(t *testing.T) {
+	c, _ := newTestTyped(t, Options{Namespace: "n"})
+	ctx := context.Background()
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) (string, error) {
+		loads.Add(1)
+		<-release
+		return "v", nil
+	}
+
+	const callers = 10
+	var arrived, finished sync.WaitGroup
+	arrived.Add(callers)
+	finished.Add(callers)
+	for range callers {
+		go func() {
+			defer finished.Done()
+			arrived.Done()
+			if v, err := c.GetOrLoad(ctx, "k", load); err != nil || v != "v" {
+				t.Errorf("GetOrLoad = %q, %v", v, err)
+			}
+		}()
+	}
+	arrived.Wait()
+	time.Sleep(20 * time.Millisecond) // let every caller miss and join the flight
+	close(release)
+	finished.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loaded %d times, want 1", n)
+	}
+	if v, ok, err := c.Get(ctx, "k"); err != nil || !ok || v != "v" {
+		t.Errorf("Get after load = %q, %v, %v", v, ok, err)
+	}
+}
+
+func TestGetOrLoadCallerCancelDoesNotFailOthers(t *testing.T) {
+	c, _ := newTestTyped(t, Options{Namespace: "n"})
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var loadErr error
+	load := func(ctx context.Context) (string, error) {
+		close(started)
+		<-release
+		loadErr = ctx.Err()
+		return "v", nil
+	}
+
+	// The first caller starts the load with its ctx, then gives up.
+	first, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error)
+	go func() {
+		_, err := c.GetOrLoad(first, "k", load)
+		firstDone <- err
+	}()
+	<-started
+
+	waiterDone := make(chan string)
+	go func() {
+		v, err := c.GetOrLoad(context.Background(), "k", func(context.Context) (string, error) {
+			return "", errors.New("second load")
+		})
+		if err != nil {
+			t.Errorf("waiting caller: %v", err)
+		}
+		waiterDone <- v
+	}()
+	time.Sleep(20 * time.Millisecond) // let the second caller join the flight
+
+	cancel()
+	if err := <-firstDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller: got %v, want context.Canceled", err)
+	}
+	close(release)
+	if v := <-waiterDone; v != "v" {
+		t.Errorf("waiting caller got %q, want the shared load's value", v)
+	}
+	if loadErr != nil {
+		t.Errorf("load saw %v: the first caller's cancellation reached it", loadErr)
+	}
+}
+
+func TestGetOrLoadEarlyRefreshKeepsValueOnError(t *testing.T) {
+	// A huge beta makes the entry refresh early on every read.
+	c, m := newTestTyped(t, Options{Namespace: "n", Beta: 1e6})
+	ctx := context.Background()
+
+	payload, err := JSON.Marshal("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := &envelope{expires: time.Now().Add(time.Minute), delta: time.Second, payload: payload}
+	if err := m.Set(ctx, c.Key("k"), env.marshal(), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := false
+	v, err := c.GetOrLoad(ctx, "k", func(context.Context) (string, error) {
+		loaded = true
+		return "", errors.New("backend down")
+	})
+	if !loaded {
+		t.Fatal("entry close to expiry was not refreshed early")
+	}
+	if err != nil || v != "old" {
+		t.Errorf("GetOrLoad = %q, %v; want the still valid old value", v, err)
+	}
+
+	v, err = c.GetOrLoad(ctx, "k", func(context.Context) (string, error) { return "new", nil })
+	if err != nil || v != "new" {
+		t.Errorf("successful refresh = %q, %v; want new", v, err)
+	}
+}
+
+func TestMalformedEnvelopeIsAMiss(t *testing.T) {
+	c, m := newTestTyped(t, Options{Namespace: "n"})
+	ctx := context.Background()
+
+	valid := (&envelope{expires: time.Now().Add(time.Minute), payload: []byte(`"v"`)}).marshal()
+	badTag := append([]byte(nil), valid[:17]...)
+	badTag = append(badTag, 1, 200) // one tag whose name runs past the end
+
+	for name, raw := range map[string][]byte{
+		"empty":           {},
+		"short":           []byte("x"),
+		"unknown version": append([]byte{envelopeVersion + 1}, valid[1:]...),
+		"truncated tag":   badTag,
+		"bad payload":     (&envelope{expires: time.Now().Add(time.Minute), payload: []byte("{")}).marshal(),
+		"expired":         (&envelope{expires: time.Now().Add(-time.Second), payload: []byte(`"v"`)}).marshal(),
+	} {
+		if err := m.Set(ctx, c.Key("k"), raw, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if v, ok, err := c.Get(ctx, "k"); ok || err != nil {
+			t.Errorf("%s: Get = %q, %v, %v; want a miss", name, v, ok, err)
+		}
+		v, err := c.GetOrLoad(ctx, "k", func(context.Context) (string, error) { return "loaded", nil })
+		if err != nil || v != "loaded" {
+			t.Errorf("%s: GetOrLoad = %q, %v; want a fresh load", name, v, err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoConnScope is returned for tenant queries issued outside a transaction and outside a
+// connection scope. Running them on an arbitrary pooled connection could read another tenant's
+// rows (search_path is per connection), so the session fails closed instead.
+var ErrNoConnScope = errors.New("tenant query outside a connection scope: wrap the unit of work with engine.WithConnScope or run it in TxManager.Execute")
+
+// ErrConnScopeSchema is returned when a connection scope already pinned to one tenant
+// is asked to serve another.
+var ErrConnScopeSchema = errors.New("connection scope is pinned to a different tenant schema")
+
+// tenantIDSetting is the session setting read by row-level security policies of shared-schema
+// tables (see scope.RLSPolicySQL).
+const tenantIDSetting = "app.tenant_id"
+
+type connScopeKey struct{}
+
+// connScope pins one physical connection for a unit of work (an HTTP request, a job run).
+// The connection is acquired lazily on the first tenant query, gets its search_path (and, for
+// shared-schema tenants, app.tenant_id) set once, and is reset and returned to the pool on release.
+// Pools of dedicated tenant databases used by the unit of work are leased until release as well.
+type connScope struct {
+	mu       sync.Mutex
+	conn     *sql.Conn
+	key      connKey
+	leases   map[string]poolLease
+	released bool
+}
+
+// poolLease is a dedicated tenant pool held by a scope.
+type poolLease struct {
+	db      *gorm.DB
+	release func()
+}
+
+// connKey is what a pinned connection is bound to.
+type connKey struct {
+	database string // dedicated database reference; "" = main
+	schema   string
+	tenantID int64 // shared-schema tenants; 0 otherwise
+}
+
+// WithConnScope opens a connection scope. Tenant queries made through Database.WithContext(ctx)
+// share one pinned connection until release is called; release must be called exactly when the
+// unit of work is finished (typically deferred).
+func WithConnScope(ctx context.Context) (context.Context, func()) {
+	s := &connScope{}
+	return context.WithValue(ctx, connScopeKey{}, s), s.release
+}
+
+func connScopeFrom(ctx context.Context) *connScope {
+	s, _ := ctx.Value(connScopeKey{}).(*connScope)
+	return s
+}
+
+// acquire returns the pinned connection for key, pinning one from pool on first use.
+func (s *connScope) acquire(ctx context.Context, d *Database, pool *gorm.DB, key connKey) (*sql.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.released {
+		return nil, ErrNoConnScope
+	}
+	if s.conn != nil {
+		if s.key != key {
+			return nil, fmt.Errorf("%w (pinned %q#%d, requested %q#%d)", ErrConnScopeSchema, s.key.schema, s.key.tenantID, key.schema, key.tenantID)
+		}
+		return s.conn, nil
+	}
+
+	sqlDB, err := pool.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, d.searchPathStmt(key.schema, false)); err != nil {
+		discardConn(conn)
+		return nil, err
+	}
+	if key.tenantID > 0 {
+		if _, err := conn.ExecContext(ctx, "SELECT set_config($1, $2, false)", tenantIDSetting, strconv.FormatInt(key.tenantID, 10)); err != nil {
+			discardConn(conn)
+			return nil, err
+		}
+	}
+	s.conn = conn
+	s.key = key
+	return conn, nil
+}
+
+// lease returns the pool of the dedicated database ref, leasing it from pools on first use.
+func (s *connScope) lease(ctx context.Context, pools TenantPools, ref string) (*gorm.DB, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.released {
+		return nil, ErrNoConnScope
+	}
+	if l, ok := s.leases[ref]; ok {
+		return l.db, nil
+	}
+	db, release, err := pools.Acquire(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if s.leases == nil {
+		s.leases = map[string]poolLease{}
+	}
+	s.leases[ref] = poolLease{db: db, release: release}
+	return db, nil
+}
+
+// pinned returns the pinned connection if it serves key, without pinning a new one.
+func (s *connScope) pinned(key connKey) *sql.Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released || s.conn == nil || s.key != key {
+		return nil
+	}
+	return s.conn
+}
+
+// release resets the pinned connection's settings and returns it to the pool, then gives
+// back the leased tenant pools. If the reset fails the connection is discarded rather than
+// handed to the next user.
+func (s *connScope) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.released {
+		return
+	}
+	s.released = true
+	defer func() {
+		for ref, l := range s.leases {
+			l.release()
+			delete(s.leases, ref)
+		}
+	}()
+	if s.conn == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := s.conn.ExecContext(ctx, "RESET ALL"); err != nil {
+		discardConn(s.conn)
+	} else {
+		_ = s.conn.Close()
+	}
+	s.conn = nil
+}
+
+// discardConn closes conn and tells database/sql not to reuse the underlying driver connection.
+func discardConn(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
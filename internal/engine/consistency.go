@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type (
+	rwKey      struct{}
+	primaryKey struct{}
+	replicaKey struct{}
+)
+
+// rwState records whether the unit of work behind a context has written to the primary.
+type rwState struct {
+	wrote atomic.Bool
+}
+
+// WithReadYourWrites marks ctx as one unit of work (a request, a job run): once it writes,
+// its later reads go to the primary so they see their own writes. Nested calls reuse
+// the outer marker.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(rwKey{}).(*rwState); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, rwKey{}, &rwState{})
+}
+
+// MarkWritten records a write for the unit of work in ctx.
+func MarkWritten(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	if st, ok := ctx.Value(rwKey{}).(*rwState); ok {
+		st.wrote.Store(true)
+	}
+}
+
+// WithPrimary forces reads made with ctx to the primary.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// WithReplicaReads allows reads of tenant sessions to use replicas. Tenant sessions run on a
+// pinned primary connection whose search_path selects the tenant; replicas only have the main
+// search_path, so opt in only where every statement is schema-qualified (ORM queries and raw
+// SQL with the {{tenant}} placeholder).
+func WithReplicaReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaKey{}, true)
+}
+
+// ReadsFromPrimary reports whether reads with ctx must go to the primary.
+func ReadsFromPrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	if v, _ := ctx.Value(primaryKey{}).(bool); v {
+		return true
+	}
+	st, ok := ctx.Value(rwKey{}).(*rwState)
+	return ok && st.wrote.Load()
+}
+
+// ReplicaReadsAllowed reports whether ctx opted tenant sessions into replica reads.
+func ReplicaReadsAllowed(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	v, _ := ctx.Value(replicaKey{}).(bool)
+	return v
+}
@@ -12,21 +12,96 @@ type Cache interface {
 	Get(ctx context.Context, key string) ([]byte, bool, error)
 	// Set stores bytes with TTL; ttl<=0 semantics depend on implementation.
 	Set(ctx context.Context, key string, val []byte, ttl time.Duration) error
+	// SetNX stores bytes with TTL only if the key is absent. The bool reports whether it was stored.
+	SetNX(ctx context.Context, key string, val []byte, ttl time.Duration) (bool, error)
 	// Del removes a cache entry.
 	Del(ctx context.Context, key string) error
 	// Exists reports whether a key is present.
 	Exists(ctx context.Context, key string) (bool, error)
 }
 
+// KeyScanner is implemented by caches that can enumerate and bulk-delete keys
+// (Redis, MemoryCache, TieredCache). Needed to flush a key space such as one tenant.
+type KeyScanner interface {
+	// ScanKeys returns keys matching a glob pattern (*, ?, [...], \ escapes) from cursor,
+	// and the cursor to continue from (0 when done). count is a batch size hint.
+	ScanKeys(ctx context.Context, pattern string, count int, cursor uint64) ([]string, uint64, error)
+	// DelMany removes keys.
+	DelMany(ctx context.Context, keys []string) error
+}
+
+// CompareDeleter is implemented by caches that can delete a key only while it still holds a
+// given value, in one atomic step (Redis, MemoryCache, TieredCache). Releasing a token-owned
+// key with Get+Del could delete a value another instance wrote in between.
+type CompareDeleter interface {
+	// DelIfValue deletes key if its value equals val and reports whether it did.
+	DelIfValue(ctx context.Context, key string, val []byte) (bool, error)
+}
+
+// Expirer is implemented by caches that can change a key's TTL without rewriting its value
+// (Redis, MemoryCache, TieredCache). Refreshing with Get+Set could bring back a value another
+// instance replaced in between.
+type Expirer interface {
+	// Expire sets key's TTL (Set semantics) if the key exists and reports whether it did.
+	Expire(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
 type DB interface {
 	// WithContext returns a new session bound to the supplied context
 	// (search_path/schema adjustments are applied by implementations).
+	// Inside TxManager.Run/Execute it returns the active transaction.
 	WithContext(ctx context.Context) *gorm.DB
 	// Main returns the name of the primary schema/database.
 	Main() string
 }
 
+// TenantPools opens and caches the connection pools of tenants with a dedicated database.
+type TenantPools interface {
+	// Acquire leases the pool for ref (see tenantContext.WithDatabase), opening it on first use.
+	// A leased pool is neither evicted nor closed; release must be called exactly once when
+	// the unit of work is done with it.
+	Acquire(ctx context.Context, ref string) (db *gorm.DB, release func(), err error)
+}
+
+// Locker provides mutual exclusion across instances with TTL leases (see RedisLocker,
+// MemoryLocker). Leases are renewed in the background until unlocked unless WithoutRenewal.
+type Locker interface {
+	// TryLock acquires key once; it fails with ErrLockHeld when another owner holds it.
+	TryLock(ctx context.Context, key string, opts ...LockOption) (Lock, error)
+	// Lock waits for key with backoff until acquired or ctx is done.
+	Lock(ctx context.Context, key string, opts ...LockOption) (Lock, error)
+}
+
+// Lock is a held lease.
+type Lock interface {
+	Key() string
+	// Token identifies this owner; only it can refresh or release the lease.
+	Token() string
+	// Fence grows with every acquisition of the key. Storage that remembers the highest fence
+	// it has seen can reject writes of an owner whose lease already expired.
+	Fence() int64
+	// Refresh extends the lease to ttl from now. It fails with ErrLockLost if the lease expired
+	// or was taken over.
+	Refresh(ctx context.Context, ttl time.Duration) error
+	// Lost is closed when the lease ends: unlocked, or renewal failed.
+	Lost() <-chan struct{}
+	// Unlock releases the lease; ErrLockLost if it was no longer held.
+	Unlock(ctx context.Context) error
+}
+
+// Lifecycle lets components take part in graceful shutdown (see kernel.Lifecycle).
+type Lifecycle interface {
+	// OnStop registers fn to run at shutdown, after the HTTP server drained. Hooks run in reverse registration order, so a component registered after its
+	// dependencies stops before them. ctx carries the shutdown deadline.
+	OnStop(name string, fn func(ctx context.Context) error)
+	// Ready reports false once shutdown started; readiness probes should fail then.
+	Ready() bool
+}
+
 type TransactionManager interface {
 	// Execute runs fn inside a transaction, committing on success and rolling back on errors/panics.
-	Execute(ctx context.Context, fn func(tx *gorm.DB) error) error
+	Execute(ctx context.Context, fn func(tx *gorm.DB) error, opts ...TxOption) error
+	// Run is Execute for code that works with ctx: the transaction travels in the ctx passed
+	// to fn, so repositories calling DB.WithContext(ctx) join it.
+	Run(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error
 }
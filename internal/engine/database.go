@@ -2,8 +2,12 @@ package engine
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	tenantContext "skyrix/internal/engine/tenantPackage/context"
+	"strconv"
 	"strings"
 
 	"gorm.io/gorm"
@@ -12,8 +16,13 @@ import (
 type Database struct {
 	*gorm.DB
 	MainSchema string
+	// Pools serves tenants with a dedicated database; nil disables them.
+	Pools TenantPools
 }
 
+// ErrNoTenantPools is returned for a tenant with a dedicated database when Pools is not configured.
+var ErrNoTenantPools = errors.New("tenant has a dedicated database but tenant pools are not configured")
+
 // NewDatabaseService wraps a base GORM connection with schema switching helpers.
 func NewDatabaseService(mainDB *gorm.DB, mainSchema string) *Database {
 	return &Database{DB: mainDB, MainSchema: mainSchema}
@@ -26,28 +35,65 @@ func quoteIdent(s string) string {
 	return `"` + s + `"`
 }
 
-// SetSchema applies PostgreSQL search_path for the given tenant.
-// If tenant is empty, it uses only the main schema + public.
-// This MUST be called on the *session* (db) that will execute queries.
-func (d *Database) SetSchema(db *gorm.DB, tenant string) error {
+// searchPathStmt builds the search_path statement for tenant ("" = main schema + public).
+// local selects SET LOCAL, which only lasts until the end of the current transaction.
+func (d *Database) searchPathStmt(tenant string, local bool) string {
 	tenant = strings.ToLower(strings.TrimSpace(tenant))
 	main := strings.ToLower(strings.TrimSpace(d.MainSchema))
 
-	var stmt string
-	if tenant == "" {
+	set := "SET"
+	if local {
+		set = "SET LOCAL"
+	}
+	if tenant == "" || tenant == main {
 		// Core DB path: main schema + public
-		stmt = fmt.Sprintf(`SET search_path = %s, public`, quoteIdent(main))
-	} else {
-		// Tenant DB path: tenant schema + main schema + public
-		stmt = fmt.Sprintf(`SET search_path = %s, %s, public`, quoteIdent(tenant), quoteIdent(main))
+		return fmt.Sprintf(`%s search_path = %s, public`, set, quoteIdent(main))
 	}
+	// Tenant DB path: tenant schema + main schema + public
+	return fmt.Sprintf(`%s search_path = %s, %s, public`, set, quoteIdent(tenant), quoteIdent(main))
+}
 
-	return db.Exec(stmt).Error
+// isTenant reports whether schema names a tenant schema (not empty, not the main schema).
+func (d *Database) isTenant(schema string) bool {
+	schema = strings.ToLower(strings.TrimSpace(schema))
+	return schema != "" && schema != strings.ToLower(strings.TrimSpace(d.MainSchema))
+}
+
+// SetSchema applies PostgreSQL search_path for the given tenant.
+// If tenant is empty, it uses only the main schema + public.
+// The setting sticks to the physical connection, so db MUST be pinned to one
+// (a transaction or a connection scope); on the shared pool it would leak to other requests.
+func (d *Database) SetSchema(db *gorm.DB, tenant string) error {
+	return db.Exec(d.searchPathStmt(tenant, false)).Error
 }
 
 func (d *Database) Main() string { return d.MainSchema }
 
+// poolFor returns the pool serving ctx: the tenant's dedicated database or the main pool.
+// A dedicated pool is leased by the connection scope in ctx, which keeps it open until the
+// unit of work is released; without a scope the session fails with ErrNoConnScope.
+func (d *Database) poolFor(ctx context.Context) (*gorm.DB, error) {
+	ref := tenantContext.DatabaseFrom(ctx)
+	if ref == "" {
+		return d.DB, nil
+	}
+	if d.Pools == nil {
+		return nil, ErrNoTenantPools
+	}
+	scope := connScopeFrom(ctx)
+	if scope == nil {
+		return nil, ErrNoConnScope
+	}
+	return scope.lease(ctx, d.Pools, ref)
+}
+
+// Close closes plugins holding resources (replica pools) and the primary pool.
 func (d *Database) Close() error {
+	for _, p := range d.DB.Config.Plugins {
+		if c, ok := p.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
 	sqlDB, err := d.DB.DB()
 	if err != nil {
 		return err
@@ -55,17 +101,116 @@ func (d *Database) Close() error {
 	return sqlDB.Close()
 }
 
-// WithContext returns a GORM session bound to the given context AND
-// automatically sets PostgreSQL search_path based on the tenant in context.
-// - If tenant is present in ctx: search_path = tenant, main, public
-// - If tenant is absent:         search_path = main, public
-// WithContext returns a GORM session bound to the given context AND
-// automatically sets PostgreSQL search_path based on the tenant in context.
-// - If tenant is present in ctx: search_path = tenant, main, public
-// - If tenant is absent:         search_path = main, public
+// WithContext returns a GORM session bound to the given context. Inside TxManager.Run/Execute
+// it is the active transaction; otherwise queries run against the tenant schema in ctx:
+//   - no tenant (or the main schema): the pooled connection, whose default search_path is main.
+//   - tenant inside WithConnScope: the scope's pinned connection (search_path = tenant, main, public;
+//     app.tenant_id for shared-schema tenants).
+//   - tenant without a scope: the session carries ErrNoConnScope and runs nothing.
+//
+// Tenants with a dedicated database (tenantContext.WithDatabase) follow the same rules on
+// their own pool, which always needs a scope: the scope holds the pool's lease.
+//
+// search_path is never set on the shared pool, so one request's tenant cannot leak
+// into another request that reuses the connection.
 func (d *Database) WithContext(ctx context.Context) *gorm.DB {
-	db := d.DB.WithContext(ctx)
+	if tx, ok := TxFrom(ctx); ok {
+		return tx
+	}
+	pool, err := d.poolFor(ctx)
+	if err != nil {
+		db := d.DB.WithContext(ctx)
+		_ = db.AddError(err)
+		return db
+	}
+	db := pool.WithContext(ctx)
 	schema := tenantContext.SchemaFrom(ctx)
-	_ = d.SetSchema(db, schema)
+	_, shared := tenantContext.TenantIDFrom(ctx)
+	if !d.isTenant(schema) && !shared {
+		return db
+	}
+
+	scope := connScopeFrom(ctx)
+	if scope == nil {
+		_ = db.AddError(ErrNoConnScope)
+		return db
+	}
+	conn, err := scope.acquire(ctx, d, pool, connKeyFrom(ctx))
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	db.Statement.ConnPool = conn
 	return db
 }
+
+// connKeyFrom returns the key a connection scope pins for the tenant in ctx.
+func connKeyFrom(ctx context.Context) connKey {
+	tenantID, _ := tenantContext.TenantIDFrom(ctx)
+	return connKey{
+		database: tenantContext.DatabaseFrom(ctx),
+		schema:   strings.ToLower(strings.TrimSpace(tenantContext.SchemaFrom(ctx))),
+		tenantID: tenantID,
+	}
+}
+
+// readerPool is implemented by the replica router plugin.
+type readerPool interface {
+	ReaderPool(ctx context.Context) *sql.DB
+}
+
+// BeginTx starts a transaction for the tenant in ctx. search_path (and app.tenant_id for
+// shared-schema tenants) is applied transaction-locally and reverts on commit or rollback.
+//
+// When ctx carries a connection scope that already pinned a connection for this tenant, the
+// transaction runs on that connection: a unit of work never holds two pooled connections at
+// once, so a full pool cannot deadlock requests that read first and write later. Otherwise the
+// transaction takes its own connection; read-only transactions on the main database start on
+// a read replica when one is healthy. Dedicated tenant databases need a scope in ctx to lease
+// their pool; TxManager opens one for the transaction when the caller has none.
+func (d *Database) BeginTx(ctx context.Context, opts ...*sql.TxOptions) *gorm.DB {
+	pool, err := d.poolFor(ctx)
+	if err != nil {
+		session := d.DB.WithContext(ctx)
+		_ = session.AddError(err)
+		return session
+	}
+	session := pool.WithContext(ctx)
+	if conn := d.pinnedConn(ctx); conn != nil {
+		session.Statement.ConnPool = conn
+	} else if pool == d.DB && len(opts) > 0 && opts[0] != nil && opts[0].ReadOnly {
+		for _, p := range d.DB.Config.Plugins {
+			if rp, ok := p.(readerPool); ok {
+				if pool := rp.ReaderPool(ctx); pool != nil {
+					session.Statement.ConnPool = pool
+				}
+				break
+			}
+		}
+	}
+	tx := session.Begin(opts...)
+	if tx.Error != nil {
+		return tx
+	}
+	if err := tx.Exec(d.searchPathStmt(tenantContext.SchemaFrom(ctx), true)).Error; err != nil {
+		tx.Rollback()
+		_ = tx.AddError(err)
+		return tx
+	}
+	if id, ok := tenantContext.TenantIDFrom(ctx); ok {
+		if err := tx.Exec("SELECT set_config(?, ?, true)", tenantIDSetting, strconv.FormatInt(id, 10)).Error; err != nil {
+			tx.Rollback()
+			_ = tx.AddError(err)
+		}
+	}
+	return tx
+}
+
+// pinnedConn returns the connection the scope in ctx pinned for ctx's tenant, if any.
+func (d *Database) pinnedConn(ctx context.Context) *sql.Conn {
+	scope := connScopeFrom(ctx)
+	if scope == nil {
+		return nil
+	}
+	return scope.pinned(connKeyFrom(ctx))
+}
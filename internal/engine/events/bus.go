@@ -0,0 +1,173 @@
+// Package events is an in-process, typed domain event bus.
+//
+// Domains publish events and subscribe to other domains' events by type, so they can
+// react to each other without importing each other. Three kinds of subscribers exist:
+//
+//   - Subscribe: synchronous, runs inside the publisher's transaction; an error rolls it back.
+//   - SubscribeAfterCommit: runs in-process once the transaction committed (engine.AfterCommit).
+//   - SubscribeAsync: enqueues a job after commit; the job receives the event as args.
+//
+// Events are value types matched by EventName: implement it on the value receiver and
+// subscribe and publish T, never *T. Subscribing with a pointer or interface type panics and
+// publishing a pointer fails, so a T/*T mismatch cannot silently miss its handlers.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"skyrix/internal/engine"
+	engineJobs "skyrix/internal/engine/jobs"
+	"skyrix/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// Event is a domain event. EventName must be stable ("order.placed"); it is the routing key.
+type Event interface {
+	EventName() string
+}
+
+// Publisher is what domain services depend on to emit events.
+type Publisher interface {
+	// Publish dispatches e. Pass the current transaction (from TxManager.Execute) as tx;
+	// with tx == nil synchronous handlers run without a transaction and after-commit
+	// handlers run immediately.
+	Publish(ctx context.Context, tx *gorm.DB, e Event) error
+}
+
+type syncHandler func(ctx context.Context, tx *gorm.DB, e Event) error
+type afterHandler func(ctx context.Context, e Event)
+
+// Bus is the Publisher implementation.
+type Bus struct {
+	jobs engineJobs.Enqueuer
+	log  logger.Interface
+
+	mu    sync.RWMutex
+	sync  map[string][]syncHandler
+	after map[string][]afterHandler
+	async map[string][]string // event name -> job names
+}
+
+func NewBus(jobs engineJobs.Enqueuer, log logger.Interface) *Bus {
+	return &Bus{
+		jobs:  jobs,
+		log:   log,
+		sync:  make(map[string][]syncHandler),
+		after: make(map[string][]afterHandler),
+		async: make(map[string][]string),
+	}
+}
+
+// nameOf returns the routing key of event type E. E must be a value type: EventName is called
+// on its zero value.
+func nameOf[E Event]() string {
+	if t := reflect.TypeFor[E](); t.Kind() == reflect.Pointer || t.Kind() == reflect.Interface {
+		panic(fmt.Sprintf("events: subscribe with a value event type, not %s", t))
+	}
+	var zero E
+	return zero.EventName()
+}
+
+// Subscribe registers a handler that runs synchronously inside the publisher's transaction.
+// Returning an error aborts Publish and rolls the transaction back.
+func Subscribe[E Event](b *Bus, h func(ctx context.Context, tx *gorm.DB, e E) error) {
+	name := nameOf[E]()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sync[name] = append(b.sync[name], func(ctx context.Context, tx *gorm.DB, ev Event) error {
+		e, ok := ev.(E)
+		if !ok {
+			return fmt.Errorf("events: %s: unexpected payload type %T", name, ev)
+		}
+		return h(ctx, tx, e)
+	})
+}
+
+// SubscribeAfterCommit registers a handler that runs after the publisher's transaction commits.
+// It cannot fail the publisher; panics are recovered and logged by TxManager.
+func SubscribeAfterCommit[E Event](b *Bus, h func(ctx context.Context, e E)) {
+	name := nameOf[E]()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.after[name] = append(b.after[name], func(ctx context.Context, ev Event) {
+		if e, ok := ev.(E); ok {
+			h(ctx, e)
+		}
+	})
+}
+
+// SubscribeAsync enqueues job after the publisher's transaction commits.
+// Job args: "event" (name) and "payload" (the event JSON-decoded into map[string]any).
+func SubscribeAsync[E Event](b *Bus, job string) {
+	name := nameOf[E]()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.async[name] = append(b.async[name], job)
+}
+
+// Publish implements Publisher.
+func (b *Bus) Publish(ctx context.Context, tx *gorm.DB, e Event) error {
+	if e == nil {
+		return fmt.Errorf("events: nil event")
+	}
+	if t := reflect.TypeOf(e); t.Kind() == reflect.Pointer {
+		return fmt.Errorf("events: publish %s by value, not as %s", t.Elem(), t)
+	}
+	name := e.EventName()
+
+	b.mu.RLock()
+	syncHs := b.sync[name]
+	afterHs := b.after[name]
+	jobs := b.async[name]
+	b.mu.RUnlock()
+
+	for _, h := range syncHs {
+		if err := h(ctx, tx, e); err != nil {
+			return fmt.Errorf("events: %s handler: %w", name, err)
+		}
+	}
+
+	for _, h := range afterHs {
+		engine.AfterCommit(ctx, tx, func(ctx context.Context) { h(ctx, e) })
+	}
+
+	if len(jobs) > 0 {
+		args, err := eventArgs(name, e)
+		if err != nil {
+			return err
+		}
+		engine.AfterCommit(ctx, tx, func(ctx context.Context) {
+			for _, job := range jobs {
+				if _, err := b.jobs.Enqueue(ctx, job, args); err != nil {
+					b.log.Error("failed to enqueue event job", "event", name, "job", job, "error", err)
+				}
+			}
+		})
+	}
+	return nil
+}
+
+func eventArgs(name string, e Event) (map[string]any, error) {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("events: encode %s: %w", name, err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("events: encode %s: %w", name, err)
+	}
+	return map[string]any{"event": name, "payload": payload}, nil
+}
+
+// Subscriber is implemented by components that register their handlers on the bus.
+// Subscribers are bundled and registered in providers (see providers.EventSubscribers).
+type Subscriber interface {
+	Subscribe(b *Bus)
+}
+
+var _ Publisher = (*Bus)(nil)
@@ -0,0 +1,211 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"slices"
+	"testing"
+
+	"skyrix/internal/engine"
+	engineJobs "skyrix/internal/engine/jobs"
+	"skyrix/internal/engine/sqltest"
+	"skyrix/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+type thingHappened struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func (thingHappened) EventName() string { return "thing.happened" }
+
+type enqueued struct {
+	job  string
+	args map[string]any
+}
+
+type fakeEnqueuer struct {
+	calls []enqueued
+}
+
+func (e *fakeEnqueuer) Enqueue(_ context.Context, name string, args map[string]any, _ ...engineJobs.EnqueueOption) (engineJobs.EnqueueStatus, error) {
+	e.calls = append(e.calls, enqueued{job: name, args: args})
+	return engineJobs.Enqueued, nil
+}
+
+func newTestBus(t *testing.T) (*Bus, engine.TransactionManager, *sqltest.Recorder, *fakeEnqueuer) {
+	t.Helper()
+	log := logger.NewSlogWrapper(slog.New(slog.DiscardHandler))
+	gdb, rec := sqltest.Open(t)
+	jobs := &fakeEnqueuer{}
+	return NewBus(jobs, log), engine.NewTxManager(engine.NewDatabaseService(gdb, "core"), log), rec, jobs
+}
+
+func TestSyncHandlerErrorRollsBack(t *testing.T) {
+	bus, tm, rec, _ := newTestBus(t)
+	boom := errors.New("boom")
+	var got thingHappened
+	Subscribe(bus, func(_ context.Context, tx *gorm.DB, e thingHappened) error {
+		if tx == nil {
+			t.Error("sync handler got no transaction")
+		}
+		got = e
+		return boom
+	})
+	after := 0
+	SubscribeAfterCommit(bus, func(context.Context, thingHappened) { after++ })
+
+	ctx := context.Background()
+	err := tm.Execute(ctx, func(tx *gorm.DB) error {
+		return bus.Publish(ctx, tx, thingHappened{ID: 7})
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Execute: got %v, want the handler error", err)
+	}
+	if got.ID != 7 {
+		t.Errorf("handler got %+v", got)
+	}
+	stmts := rec.Statements()
+	if !slices.Contains(stmts, "ROLLBACK") || slices.Contains(stmts, "COMMIT") {
+		t.Errorf("statements %q, want a rollback and no commit", stmts)
+	}
+	if after != 0 {
+		t.Errorf("after-commit handler ran %d times after a rollback", after)
+	}
+}
+
+func TestAfterCommitRunsOnlyOnCommit(t *testing.T) {
+	bus, tm, rec, _ := newTestBus(t)
+	var committed []bool
+	SubscribeAfterCommit(bus, func(context.Context, thingHappened) {
+		committed = append(committed, slices.Contains(rec.Statements(), "COMMIT"))
+	})
+	ctx := context.Background()
+
+	if err := tm.Execute(ctx, func(tx *gorm.DB) error {
+		if err := bus.Publish(ctx, tx, thingHappened{}); err != nil {
+			return err
+		}
+		if len(committed) != 0 {
+			t.Error("after-commit handler ran inside the transaction")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(committed, []bool{true}) {
+		t.Fatalf("after-commit runs = %v, want one after COMMIT", committed)
+	}
+
+	rolledBack := errors.New("rolled back")
+	err := tm.Execute(ctx, func(tx *gorm.DB) error {
+		if err := bus.Publish(ctx, tx, thingHappened{}); err != nil {
+			return err
+		}
+		return rolledBack
+	})
+	if !errors.Is(err, rolledBack) {
+		t.Fatal(err)
+	}
+	if len(committed) != 1 {
+		t.Errorf("after-commit handler ran for a rolled back transaction")
+	}
+}
+
+func TestSavepointRollbackDropsAfterCommit(t *testing.T) {
+	bus, tm, rec, _ := newTestBus(t)
+	var seen []int64
+	SubscribeAfterCommit(bus, func(_ context.Context, e thingHappened) { seen = append(seen, e.ID) })
+
+	failed := errors.New("nested failure")
+	err := tm.Run(context.Background(), func(ctx context.Context) error {
+		// Rolled back to its savepoint: the event it published never happened.
+		err := tm.Run(ctx, func(ctx context.Context) error {
+			if err := bus.Publish(ctx, nil, thingHappened{ID: 1}); err != nil {
+				return err
+			}
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Errorf("nested Run: %v", err)
+		}
+		// Released: its event is handed to the outer transaction.
+		return tm.Run(ctx, func(ctx context.Context) error {
+			return bus.Publish(ctx, nil, thingHappened{ID: 2})
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(seen, []int64{2}) {
+		t.Errorf("after-commit events = %v, want [2]", seen)
+	}
+	stmts := rec.Statements()
+	for _, want := range []string{"ROLLBACK TO SAVEPOINT sp_1", "RELEASE SAVEPOINT sp_2", "COMMIT"} {
+		if !slices.Contains(stmts, want) {
+			t.Errorf("statements %q do not contain %q", stmts, want)
+		}
+	}
+}
+
+func TestAsyncEnqueuesAfterCommit(t *testing.T) {
+	bus, tm, _, jobs := newTestBus(t)
+	SubscribeAsync[thingHappened](bus, "notify")
+	SubscribeAsync[thingHappened](bus, "audit")
+	ctx := context.Background()
+
+	if err := tm.Execute(ctx, func(tx *gorm.DB) error {
+		if err := bus.Publish(ctx, tx, thingHappened{ID: 7, Name: "x"}); err != nil {
+			return err
+		}
+		if len(jobs.calls) != 0 {
+			t.Error("job enqueued before commit")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	args := map[string]any{
+		"event":   "thing.happened",
+		"payload": map[string]any{"id": float64(7), "name": "x"},
+	}
+	want := []enqueued{{job: "notify", args: args}, {job: "audit", args: args}}
+	if !reflect.DeepEqual(jobs.calls, want) {
+		t.Errorf("enqueued %v, want %v", jobs.calls, want)
+	}
+}
+
+func TestEventsAreValueTypes(t *testing.T) {
+	bus, _, _, _ := newTestBus(t)
+
+	for name, subscribe := range map[string]func(){
+		"pointer":   func() { SubscribeAsync[*thingHappened](bus, "job") },
+		"interface": func() { SubscribeAsync[Event](bus, "job") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("subscribing with a %s type did not panic", name)
+				}
+			}()
+			subscribe()
+		}()
+	}
+
+	called := false
+	Subscribe(bus, func(context.Context, *gorm.DB, thingHappened) error {
+		called = true
+		return nil
+	})
+	if err := bus.Publish(context.Background(), nil, &thingHappened{}); err == nil {
+		t.Error("publishing a pointer event succeeded")
+	}
+	if err := bus.Publish(context.Background(), nil, thingHappened{}); err != nil || !called {
+		t.Errorf("publishing a value event: err %v, handler called %v", err, called)
+	}
+}
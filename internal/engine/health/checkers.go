@@ -0,0 +1,152 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"skyrix/internal/engine"
+)
+
+// DatabaseChecker pings the main Postgres pool.
+type DatabaseChecker struct {
+	db *engine.Database
+}
+
+func NewDatabaseChecker(db *engine.Database) *DatabaseChecker {
+	return &DatabaseChecker{db: db}
+}
+
+func (c *DatabaseChecker) Info() Info {
+	return Info{Name: "postgres:ping", ComponentType: ComponentDatastore}
+}
+
+func (c *DatabaseChecker) Check(ctx context.Context) error {
+	sqlDB, err := c.db.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// SchemaChecker reports whether the database schema is migrated: the main schema and the
+// listed tables in it must exist. Tables are created by SQL migrations outside the app, so
+// a pod started against an unmigrated database stays out of rotation.
+type SchemaChecker struct {
+	db     *engine.Database
+	tables []string
+}
+
+func NewSchemaChecker(db *engine.Database, tables []string) *SchemaChecker {
+	out := make([]string, 0, len(tables))
+	for _, t := range tables {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return &SchemaChecker{db: db, tables: out}
+}
+
+func (c *SchemaChecker) Info() Info {
+	return Info{Name: "postgres:migrations", ComponentType: ComponentDatastore}
+}
+
+func (c *SchemaChecker) Check(ctx context.Context) error {
+	schema := c.db.Main()
+	var n int64
+	err := c.db.DB.WithContext(ctx).
+		Raw("SELECT count(*) FROM information_schema.schemata WHERE schema_name = ?", schema).
+		Scan(&n).Error
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("schema %q does not exist", schema)
+	}
+	if len(c.tables) == 0 {
+		return nil
+	}
+
+	var found []string
+	err = c.db.DB.WithContext(ctx).
+		Raw("SELECT table_name FROM information_schema.tables WHERE table_schema = ? AND table_name IN ?", schema, c.tables).
+		Scan(&found).Error
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(found))
+	for _, t := range found {
+		have[t] = true
+	}
+	var missing []string
+	for _, t := range c.tables {
+		if !have[t] {
+			missing = append(missing, t)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing tables in %s: %s", schema, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// RedisChecker pings Redis.
+type RedisChecker struct {
+	redis *engine.Redis
+}
+
+func NewRedisChecker(r *engine.Redis) *RedisChecker {
+	return &RedisChecker{redis: r}
+}
+
+func (c *RedisChecker) Info() Info {
+	return Info{Name: "redis:ping", ComponentType: ComponentDatastore}
+}
+
+func (c *RedisChecker) Check(ctx context.Context) error {
+	return c.redis.Ping(ctx)
+}
+
+// QueueChecker dials the message queue (QUEUE_HOST:QUEUE_PORT) over TCP.
+type QueueChecker struct {
+	addr string
+}
+
+func NewQueueChecker(host string, port int) *QueueChecker {
+	return &QueueChecker{addr: net.JoinHostPort(host, fmt.Sprint(port))}
+}
+
+func (c *QueueChecker) Info() Info {
+	return Info{Name: "queue:connectivity", ComponentType: ComponentComponent}
+}
+
+func (c *QueueChecker) Check(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// LifecycleChecker fails readiness once graceful shutdown started.
+type LifecycleChecker struct {
+	lifecycle engine.Lifecycle
+}
+
+func NewLifecycleChecker(l engine.Lifecycle) *LifecycleChecker {
+	return &LifecycleChecker{lifecycle: l}
+}
+
+func (c *LifecycleChecker) Info() Info {
+	return Info{Name: "app:lifecycle", ComponentType: ComponentSystem, NoCache: true}
+}
+
+func (c *LifecycleChecker) Check(context.Context) error {
+	if !c.lifecycle.Ready() {
+		return errors.New("shutting down")
+	}
+	return nil
+}
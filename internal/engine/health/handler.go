@@ -0,0 +1,38 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of health check responses.
+const ContentType = "application/health+json"
+
+// LiveHandler serves the liveness report (GET /health/live).
+func (r *Registry) LiveHandler() http.HandlerFunc {
+	return r.handler(r.Live)
+}
+
+// ReadyHandler serves the readiness report (GET /health/ready).
+func (r *Registry) ReadyHandler() http.HandlerFunc {
+	return r.handler(r.Ready)
+}
+
+// handler writes the report: 200 for pass and warn, 503 for fail.
+func (r *Registry) handler(run func(ctx context.Context) Report) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		rep := run(req.Context())
+		status := http.StatusOK
+		if rep.Status == StatusFail {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		if req.Method == http.MethodHead {
+			return
+		}
+		_ = json.NewEncoder(w).Encode(rep)
+	}
+}
@@ -0,0 +1,283 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"skyrix/internal/logger"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Check statuses of the health check response format
+// (draft-inadarei-api-health-check: pass, warn, fail).
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Component types used in reports.
+const (
+	ComponentDatastore = "datastore"
+	ComponentComponent = "component"
+	ComponentSystem    = "system"
+)
+
+// Kind selects the probes a check belongs to.
+type Kind int
+
+const (
+	// Readiness checks decide whether the instance should receive traffic (dependencies).
+	Readiness Kind = iota
+	// Liveness checks decide whether the process must be restarted. They also count for
+	// readiness. Keep them free of external dependencies, or an outage restarts every pod.
+	Liveness
+)
+
+// Info describes a check. Zero Timeout and CacheTTL take the registry defaults.
+type Info struct {
+	// Name is the key in the report, "<component>:<measurement>" (e.g. "postgres:ping").
+	Name          string
+	ComponentType string
+	Kind          Kind
+	// Optional checks report warn instead of fail and do not fail the probe.
+	Optional bool
+	Timeout  time.Duration
+	CacheTTL time.Duration
+	// NoCache runs the check on every probe (for cheap in-process checks).
+	NoCache bool
+}
+
+// Checker is a pluggable health check. Check returns nil when the component is healthy.
+type Checker interface {
+	Info() Info
+	Check(ctx context.Context) error
+}
+
+// Options configure a Registry and the top level of its reports.
+type Options struct {
+	Timeout   time.Duration // per check, default 2s
+	CacheTTL  time.Duration // how long a result is reused, default 5s
+	Version   string        // public version of the service
+	ReleaseID string
+	ServiceID string
+}
+
+// Result is one check's entry in a Report.
+type Result struct {
+	ComponentType string    `json:"componentType,omitempty"`
+	Status        string    `json:"status"`
+	ObservedValue float64   `json:"observedValue"`
+	ObservedUnit  string    `json:"observedUnit"`
+	Time          time.Time `json:"time"`
+	Output        string    `json:"output,omitempty"`
+}
+
+// Report is the health check response body. Checks maps each check name to its results
+// (one per check here; the format allows several).
+type Report struct {
+	Status    string              `json:"status"`
+	Version   string              `json:"version,omitempty"`
+	ReleaseID string              `json:"releaseId,omitempty"`
+	ServiceID string              `json:"serviceId,omitempty"`
+	Checks    map[string][]Result `json:"checks,omitempty"`
+}
+
+type entry struct {
+	checker Checker
+	info    Info
+
+	mu     sync.Mutex
+	last   Result
+	expiry time.Time
+}
+
+// Registry runs registered checks concurrently, each with its own timeout, and caches the
+// results for CacheTTL so frequent probes do not hammer dependencies.
+type Registry struct {
+	opts Options
+	log  logger.Interface
+
+	mu      sync.RWMutex
+	entries []*entry
+	names   map[string]bool
+
+	group singleflight.Group
+}
+
+func NewRegistry(opts Options, log logger.Interface) *Registry {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Second
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = 5 * time.Second
+	}
+	return &Registry{opts: opts, log: log, names: make(map[string]bool)}
+}
+
+// Register adds checkers. Names must be unique.
+func (r *Registry) Register(checkers ...Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range checkers {
+		info := c.Info()
+		if info.Name == "" {
+			panic("health check name is empty")
+		}
+		if r.names[info.Name] {
+			panic("health check already registered: " + info.Name)
+		}
+		if info.Timeout <= 0 {
+			info.Timeout = r.opts.Timeout
+		}
+		if info.CacheTTL <= 0 {
+			info.CacheTTL = r.opts.CacheTTL
+		}
+		r.names[info.Name] = true
+		r.entries = append(r.entries, &entry{checker: c, info: info})
+	}
+}
+
+// Live runs the liveness checks.
+func (r *Registry) Live(ctx context.Context) Report {
+	return r.run(ctx, Liveness)
+}
+
+// Ready runs all checks.
+func (r *Registry) Ready(ctx context.Context) Report {
+	return r.run(ctx, Readiness)
+}
+
+func (r *Registry) run(ctx context.Context, kind Kind) Report {
+	r.mu.RLock()
+	entries := make([]*entry, 0, len(r.entries))
+	for _, e := range r.entries {
+		if kind == Readiness || e.info.Kind == Liveness {
+			entries = append(entries, e)
+		}
+	}
+	r.mu.RUnlock()
+
+	results := make([]Result, len(entries))
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		wg.Add(1)
+		go func(i int, e *entry) {
+			defer wg.Done()
+			results[i] = r.result(ctx, e)
+		}(i, e)
+	}
+	wg.Wait()
+
+	rep := Report{
+		Status:    StatusPass,
+		Version:   r.opts.Version,
+		ReleaseID: r.opts.ReleaseID,
+		ServiceID: r.opts.ServiceID,
+		Checks:    make(map[string][]Result, len(entries)),
+	}
+	for i, e := range entries {
+		res := results[i]
+		rep.Checks[e.info.Name] = []Result{res}
+		switch {
+		case res.Status == StatusFail:
+			rep.Status = StatusFail
+		case res.Status == StatusWarn && rep.Status == StatusPass:
+			rep.Status = StatusWarn
+		}
+	}
+	return rep
+}
+
+// result returns the cached result of e, or runs it once for all concurrent callers.
+func (r *Registry) result(ctx context.Context, e *entry) Result {
+	if !e.info.NoCache {
+		e.mu.Lock()
+		res, fresh := e.last, time.Now().Before(e.expiry)
+		e.mu.Unlock()
+		if fresh {
+			return res
+		}
+	}
+	// The check must not be cut short by the first caller going away.
+	ch := r.group.DoChan(e.info.Name, func() (any, error) {
+		res := r.execute(context.WithoutCancel(ctx), e)
+		if !e.info.NoCache {
+			e.mu.Lock()
+			e.last, e.expiry = res, res.Time.Add(e.info.CacheTTL)
+			e.mu.Unlock()
+		}
+		return res, nil
+	})
+	select {
+	case v := <-ch:
+		return v.Val.(Result)
+	case <-ctx.Done():
+		return Result{
+			ComponentType: e.info.ComponentType,
+			Status:        r.failStatus(e),
+			ObservedUnit:  "ms",
+			Time:          time.Now().UTC(),
+			Output:        ctx.Err().Error(),
+		}
+	}
+}
+
+func (r *Registry) execute(ctx context.Context, e *entry) Result {
+	ctx, cancel := context.WithTimeout(ctx, e.info.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := runCheck(ctx, e.checker)
+	res := Result{
+		ComponentType: e.info.ComponentType,
+		Status:        StatusPass,
+		ObservedValue: float64(time.Since(start).Microseconds()) / 1000,
+		ObservedUnit:  "ms",
+		Time:          start.UTC(),
+	}
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", e.info.Timeout)
+		}
+		res.Status = r.failStatus(e)
+		res.Output = err.Error()
+		if r.log != nil {
+			r.log.Warn("health check failed", "check", e.info.Name, "error", err)
+		}
+	}
+	return res
+}
+
+func (r *Registry) failStatus(e *entry) string {
+	if e.info.Optional {
+		return StatusWarn
+	}
+	return StatusFail
+}
+
+// runCheck runs c in its own goroutine so a check ignoring ctx cannot outlive its timeout.
+func runCheck(ctx context.Context, c Checker) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- c.Check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,197 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeCheck returns err and counts its runs.
+type fakeCheck struct {
+	info  Info
+	err   error
+	delay time.Duration
+	runs  atomic.Int32
+}
+
+func (c *fakeCheck) Info() Info { return c.info }
+
+func (c *fakeCheck) Check(ctx context.Context) error {
+	c.runs.Add(1)
+	if c.delay > 0 {
+		select {
+		case <-time.After(c.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return c.err
+}
+
+func serve(h http.HandlerFunc, method string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(method, "/health/ready", nil))
+	return w
+}
+
+func TestReportFormat(t *testing.T) {
+	reg := NewRegistry(Options{Version: "1.2.3", ReleaseID: "1.2.3-abc", ServiceID: "skyrix"}, nil)
+	reg.Register(
+		&fakeCheck{info: Info{Name: "postgres:ping", ComponentType: ComponentDatastore}},
+		&fakeCheck{info: Info{Name: "queue:connectivity", ComponentType: ComponentComponent}, err: errors.New("connection refused")},
+	)
+
+	w := serve(reg.ReadyHandler(), http.MethodGet)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status code %d, want 503", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type %q, want %q", ct, ContentType)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("Cache-Control %q, want no-store", cc)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]any{"status": "fail", "version": "1.2.3", "releaseId": "1.2.3-abc", "serviceId": "skyrix"} {
+		if body[key] != want {
+			t.Errorf("%s = %v, want %v", key, body[key], want)
+		}
+	}
+	checks := body["checks"].(map[string]any)
+	pg := checks["postgres:ping"].([]any)[0].(map[string]any)
+	if pg["status"] != "pass" || pg["componentType"] != "datastore" || pg["observedUnit"] != "ms" {
+		t.Errorf("postgres:ping = %v", pg)
+	}
+	if _, ok := pg["observedValue"].(float64); !ok {
+		t.Errorf("observedValue %v is not a number", pg["observedValue"])
+	}
+	if _, err := time.Parse(time.RFC3339Nano, pg["time"].(string)); err != nil {
+		t.Errorf("time: %v", err)
+	}
+	if _, ok := pg["output"]; ok {
+		t.Error("a passing check has an output")
+	}
+	q := checks["queue:connectivity"].([]any)[0].(map[string]any)
+	if q["status"] != "fail" || q["output"] != "connection refused" {
+		t.Errorf("queue:connectivity = %v", q)
+	}
+
+	if head := serve(reg.ReadyHandler(), http.MethodHead); head.Body.Len() != 0 || head.Code != http.StatusServiceUnavailable {
+		t.Errorf("HEAD: %d with %d body bytes", head.Code, head.Body.Len())
+	}
+}
+
+func TestOptionalCheckWarns(t *testing.T) {
+	reg := NewRegistry(Options{}, nil)
+	reg.Register(
+		&fakeCheck{info: Info{Name: "a:ok"}},
+		&fakeCheck{info: Info{Name: "b:optional", Optional: true}, err: errors.New("down")},
+	)
+	w := serve(reg.ReadyHandler(), http.MethodGet)
+	if w.Code != http.StatusOK {
+		t.Errorf("status code %d, want 200", w.Code)
+	}
+	var rep Report
+	if err := json.Unmarshal(w.Body.Bytes(), &rep); err != nil {
+		t.Fatal(err)
+	}
+	if rep.Status != StatusWarn || rep.Checks["b:optional"][0].Status != StatusWarn {
+		t.Errorf("report = %+v, want warn", rep)
+	}
+}
+
+func TestLivenessRunsOnlyLivenessChecks(t *testing.T) {
+	reg := NewRegistry(Options{}, nil)
+	db := &fakeCheck{info: Info{Name: "postgres:ping"}, err: errors.New("down")}
+	proc := &fakeCheck{info: Info{Name: "app:process", Kind: Liveness}}
+	reg.Register(db, proc)
+
+	live := reg.Live(context.Background())
+	if live.Status != StatusPass || len(live.Checks) != 1 || live.Checks["app:process"] == nil {
+		t.Errorf("liveness = %+v, want only app:process passing", live)
+	}
+	if ready := reg.Ready(context.Background()); ready.Status != StatusFail || len(ready.Checks) != 2 {
+		t.Errorf("readiness = %+v, want both checks and fail", ready)
+	}
+}
+
+func TestCheckTimeoutAndPanic(t *testing.T) {
+	reg := NewRegistry(Options{Timeout: 20 * time.Millisecond}, nil)
+	reg.Register(
+		&fakeCheck{info: Info{Name: "slow:ping"}, delay: time.Second},
+		panicCheck{},
+	)
+	rep := reg.Ready(context.Background())
+	if out := rep.Checks["slow:ping"][0].Output; out != "timed out after 20ms" {
+		t.Errorf("slow check output %q", out)
+	}
+	if out := rep.Checks["panic:check"][0].Output; !strings.Contains(out, "panicked") {
+		t.Errorf("panicking check output %q", out)
+	}
+}
+
+type panicCheck struct{}
+
+func (panicCheck) Info() Info                  { return Info{Name: "panic:check"} }
+func (panicCheck) Check(context.Context) error { panic("boom") }
+
+func TestResultsAreCached(t *testing.T) {
+	reg := NewRegistry(Options{CacheTTL: time.Hour}, nil)
+	cached := &fakeCheck{info: Info{Name: "postgres:ping"}}
+	live := &fakeCheck{info: Info{Name: "app:lifecycle", NoCache: true}}
+	reg.Register(cached, live)
+
+	for range 3 {
+		reg.Ready(context.Background())
+	}
+	if cached.runs.Load() != 1 || live.runs.Load() != 3 {
+		t.Errorf("runs: cached %d (want 1), NoCache %d (want 3)", cached.runs.Load(), live.runs.Load())
+	}
+}
+
+type fakeLifecycle struct{ ready bool }
+
+func (l *fakeLifecycle) OnStop(string, func(context.Context) error) {}
+func (l *fakeLifecycle) Ready() bool                                { return l.ready }
+
+func TestLifecycleChecker(t *testing.T) {
+	lc := &fakeLifecycle{ready: true}
+	reg := NewRegistry(Options{}, nil)
+	reg.Register(NewLifecycleChecker(lc))
+
+	if rep := reg.Ready(context.Background()); rep.Status != StatusPass {
+		t.Fatalf("ready app: %s", rep.Status)
+	}
+	lc.ready = false
+	w := serve(reg.ReadyHandler(), http.MethodGet)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "shutting down") {
+		t.Errorf("draining app: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestQueueChecker(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().(*net.TCPAddr)
+	c := NewQueueChecker("127.0.0.1", addr.Port)
+	if err := c.Check(context.Background()); err != nil {
+		t.Errorf("open port: %v", err)
+	}
+	_ = ln.Close()
+	if err := c.Check(context.Background()); err == nil {
+		t.Error("closed port passed")
+	}
+}
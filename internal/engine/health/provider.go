@@ -0,0 +1,39 @@
+package health
+
+import (
+	"strings"
+
+	"skyrix/internal/config"
+	"skyrix/internal/engine"
+
+	"github.com/google/wire"
+)
+
+// ProviderSet provides the built-in checkers. Optional ones are nil when disabled by config.
+var ProviderSet = wire.NewSet(
+	NewDatabaseChecker,
+	ProvideSchemaChecker,
+	ProvideRedisChecker,
+	ProvideQueueChecker,
+	NewLifecycleChecker,
+)
+
+func ProvideSchemaChecker(db *engine.Database, cfg *config.Config) *SchemaChecker {
+	return NewSchemaChecker(db, cfg.Health.RequiredTables)
+}
+
+// ProvideRedisChecker returns nil for the memory cache backend, which does not use Redis.
+func ProvideRedisChecker(r *engine.Redis, cfg *config.Config) *RedisChecker {
+	if strings.EqualFold(strings.TrimSpace(cfg.Cache.Backend), engine.CacheMemory) {
+		return nil
+	}
+	return NewRedisChecker(r)
+}
+
+// ProvideQueueChecker returns nil unless HEALTH_CHECK_QUEUE is set.
+func ProvideQueueChecker(cfg *config.Config) *QueueChecker {
+	if !cfg.Health.CheckQueue {
+		return nil
+	}
+	return NewQueueChecker(cfg.Queue.Host, cfg.Queue.Port)
+}
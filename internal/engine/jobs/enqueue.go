@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"context"
+	"time"
+)
+
+// EnqueueStatus reports what happened to an Enqueue call.
+type EnqueueStatus string
+
+const (
+	// Enqueued means the job will run (now, after a delay, or when the debounce window closes).
+	Enqueued EnqueueStatus = "enqueued"
+	// Duplicate means another run with the same unique key is pending or running.
+	Duplicate EnqueueStatus = "duplicate"
+	// Throttled means a run with the same key already started within the throttle window.
+	Throttled EnqueueStatus = "throttled"
+)
+
+// EnqueueOptions is the resolved set of options for a single Enqueue call.
+type EnqueueOptions struct {
+	// UniqueKey drops the call while another run with the same key is pending or running.
+	UniqueKey string
+	// UniqueTTL bounds how long the unique lock may be held once the run is due (protects
+	// against crashed workers); delayed runs hold it for their delay on top of it.
+	UniqueTTL time.Duration
+
+	// DebounceKey/DebounceWindow: only the last call in a burst runs, once the window has been quiet.
+	DebounceKey    string
+	DebounceWindow time.Duration
+
+	// ThrottleKey/ThrottleWindow: at most one run per window; extra calls are dropped.
+	ThrottleKey    string
+	ThrottleWindow time.Duration
+
+	// RunAt schedules the run; zero means immediately.
+	RunAt time.Time
+}
+
+// EnqueueOption configures an Enqueue call.
+type EnqueueOption func(*EnqueueOptions)
+
+// WithUnique drops duplicates while a run with the same key is pending or running.
+// ttl<=0 uses the enqueuer default.
+func WithUnique(key string, ttl time.Duration) EnqueueOption {
+	return func(o *EnqueueOptions) {
+		o.UniqueKey = key
+		o.UniqueTTL = ttl
+	}
+}
+
+// WithDebounce delays the run until no other call with the same key arrived for window.
+func WithDebounce(key string, window time.Duration) EnqueueOption {
+	return func(o *EnqueueOptions) {
+		o.DebounceKey = key
+		o.DebounceWindow = window
+	}
+}
+
+// WithThrottle allows at most one run with the same key per window.
+func WithThrottle(key string, window time.Duration) EnqueueOption {
+	return func(o *EnqueueOptions) {
+		o.ThrottleKey = key
+		o.ThrottleWindow = window
+	}
+}
+
+// WithDelay runs the job after d.
+func WithDelay(d time.Duration) EnqueueOption {
+	return func(o *EnqueueOptions) {
+		o.RunAt = time.Now().Add(d)
+	}
+}
+
+// WithRunAt runs the job at t (immediately if t is in the past).
+func WithRunAt(t time.Time) EnqueueOption {
+	return func(o *EnqueueOptions) {
+		o.RunAt = t
+	}
+}
+
+// Enqueuer dispatches registered jobs asynchronously with dedup and scheduling options.
+type Enqueuer interface {
+	Enqueue(ctx context.Context, name string, args map[string]any, opts ...EnqueueOption) (EnqueueStatus, error)
+}
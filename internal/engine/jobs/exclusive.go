@@ -0,0 +1,14 @@
+package jobs
+
+// Exclusive is implemented by jobs that must never run twice at once, across all instances.
+// Runners hold an engine.Locker lease on "jobs:<name>:<tenant key>" while the job runs
+// (tenant-scoped jobs are exclusive per tenant) and fail a run that finds it held.
+type Exclusive interface {
+	Exclusive() bool
+}
+
+// IsExclusive reports whether job declared itself exclusive.
+func IsExclusive(job Job) bool {
+	e, ok := job.(Exclusive)
+	return ok && e.Exclusive()
+}
@@ -3,13 +3,16 @@ package jobs
 import (
 	"context"
 	"fmt"
+	"skyrix/internal/engine"
+	"skyrix/internal/engine/metrics"
 	"skyrix/internal/logger"
 	"time"
 )
 
 // ExecuteJob runs a job synchronously with retry/backoff and panic protection.
 // Retries are limited by Job.RetryCount() with a linear backoff (100ms * attempt).
-func ExecuteJob(ctx context.Context, job Job, log logger.Interface, args map[string]any) (err error) {
+// Runs are recorded in m, which may be nil.
+func ExecuteJob(ctx context.Context, job Job, log logger.Interface, m *metrics.Metrics, args map[string]any) (err error) {
 	if job == nil {
 		if log != nil {
 			log.Error("job is nil")
@@ -17,15 +20,26 @@ func ExecuteJob(ctx context.Context, job Job, log logger.Interface, args map[str
 		return fmt.Errorf("job is nil")
 	}
 
+	start := time.Now()
+	outcome := "failure"
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("job %q panicked: %v", job.Name(), r)
+			outcome = "panic"
 			if log != nil {
 				log.Error("job panicked", "name", job.Name())
 			}
 		}
+		if m != nil {
+			m.JobRuns.With(job.Name(), outcome).Inc()
+			m.JobDuration.With(job.Name(), outcome).Observe(time.Since(start).Seconds())
+		}
 	}()
 
+	// One pinned connection per run (all attempts) for tenant-scoped queries.
+	ctx, release := engine.WithConnScope(engine.WithReadYourWrites(ctx))
+	defer release()
+
 	maxRetries := job.RetryCount()
 	if maxRetries < 0 {
 		maxRetries = 0
@@ -34,8 +48,12 @@ func ExecuteJob(ctx context.Context, job Job, log logger.Interface, args map[str
 	var attempt int
 	for {
 		attempt++
+		if attempt > 1 && m != nil {
+			m.JobRetries.With(job.Name()).Inc()
+		}
 		err = job.Execute(ctx, args)
 		if err == nil {
+			outcome = "success"
 			return nil
 		}
 
@@ -47,30 +65,3 @@ func ExecuteJob(ctx context.Context, job Job, log logger.Interface, args map[str
 		time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
 	}
 }
-
-// ExecuteJobAsync spawns ExecuteJob in a goroutine and emits lifecycle logs.
-func ExecuteJobAsync(ctx context.Context, job Job, log logger.Interface, args map[string]any) {
-	if job == nil {
-		if log != nil {
-			log.Error("async job is nil")
-		}
-		return
-	}
-
-	go func() {
-		if log != nil {
-			log.Info("async job started", "job", job.Name())
-		}
-
-		if err := ExecuteJob(ctx, job, log, args); err != nil {
-			if log != nil {
-				log.Error("async job failed", "job", job.Name(), "error", err)
-			}
-			return
-		}
-
-		if log != nil {
-			log.Info("async job finished", "job", job.Name())
-		}
-	}()
-}
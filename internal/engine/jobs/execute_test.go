@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"skyrix/internal/engine/metrics"
+	"skyrix/internal/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type flakyJob struct{ fails int }
+
+func (*flakyJob) Name() string    { return "flaky" }
+func (*flakyJob) RetryCount() int { return 1 }
+func (j *flakyJob) Execute(context.Context, map[string]any) error {
+	if j.fails > 0 {
+		j.fails--
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func TestExecuteJobRecordsMetrics(t *testing.T) {
+	m, err := metrics.New(prometheus.NewRegistry(), metrics.Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := logger.NewSlogWrapper(slog.New(slog.DiscardHandler))
+
+	if err := ExecuteJob(context.Background(), &flakyJob{fails: 1}, log, m, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExecuteJob(context.Background(), &flakyJob{fails: 2}, log, m, nil); err == nil {
+		t.Fatal("job failing past its retries succeeded")
+	}
+
+	if got := testutil.ToFloat64(m.JobRuns.With("flaky", "success")); got != 1 {
+		t.Errorf("successful runs = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.JobRuns.With("flaky", "failure")); got != 1 {
+		t.Errorf("failed runs = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.JobRetries.With("flaky")); got != 2 {
+		t.Errorf("retries = %v, want 2", got)
+	}
+	// nil metrics: the run is not recorded anywhere.
+	if err := ExecuteJob(context.Background(), &flakyJob{}, log, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,34 @@
+package jobs
+
+// Scope tells runners which schema a job operates on.
+type Scope int
+
+const (
+	// ScopeMain jobs run once against the main schema.
+	ScopeMain Scope = iota
+	// ScopeTenant jobs run once per tenant schema (fan-out) or for a single chosen tenant.
+	ScopeTenant
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeTenant:
+		return "tenant"
+	default:
+		return "main"
+	}
+}
+
+// Scoped is implemented by jobs that declare their scope.
+// Jobs that do not implement it are treated as main-scoped.
+type Scoped interface {
+	Scope() Scope
+}
+
+// ScopeOf returns the declared scope of a job (ScopeMain by default).
+func ScopeOf(job Job) Scope {
+	if s, ok := job.(Scoped); ok {
+		return s.Scope()
+	}
+	return ScopeMain
+}
@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrDraining is returned by Tracker.Start and Tracker.Go once Drain was called.
+var ErrDraining = errors.New("jobs: draining, not accepting new runs")
+
+// Tracker counts running jobs so shutdown can wait for them. Once Drain was called it refuses
+// new runs, so nothing starts after Drain returned.
+type Tracker struct {
+	mu       sync.Mutex
+	running  int
+	draining bool
+	idle     chan struct{} // closed when running drops to 0 while draining
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// Start registers a run and returns the func to call when it returned. It fails with
+// ErrDraining once Drain was called.
+func (t *Tracker) Start() (done func(), err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return nil, ErrDraining
+	}
+	t.running++
+	var once sync.Once
+	return func() { once.Do(t.done) }, nil
+}
+
+func (t *Tracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running--
+	if t.running == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// Go runs fn in a tracked goroutine.
+func (t *Tracker) Go(fn func()) error {
+	done, err := t.Start()
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer done()
+		fn()
+	}()
+	return nil
+}
+
+// Running returns the number of runs that have not returned yet.
+func (t *Tracker) Running() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.running
+}
+
+// Drain stops accepting runs and waits until the running ones returned or ctx is done, in
+// which case it returns ctx.Err() and the runs keep going. It may be called more than once.
+func (t *Tracker) Drain(ctx context.Context) error {
+	t.mu.Lock()
+	t.draining = true
+	if t.running == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrackerDrainWaitsForRuns(t *testing.T) {
+	tr := NewTracker()
+	release := make(chan struct{})
+	if err := tr.Go(func() { <-release }); err != nil {
+		t.Fatal(err)
+	}
+	if tr.Running() != 1 {
+		t.Fatalf("Running = %d, want 1", tr.Running())
+	}
+
+	drained := make(chan error)
+	go func() { drained <- tr.Drain(context.Background()) }()
+	select {
+	case err := <-drained:
+		t.Fatalf("Drain returned %v while a run was going", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Draining already started: new runs are refused instead of racing the wait.
+	if err := tr.Go(func() {}); !errors.Is(err, ErrDraining) {
+		t.Errorf("Go while draining: got %v, want ErrDraining", err)
+	}
+	if _, err := tr.Start(); !errors.Is(err, ErrDraining) {
+		t.Errorf("Start while draining: got %v, want ErrDraining", err)
+	}
+
+	close(release)
+	if err := <-drained; err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Drain(context.Background()); err != nil {
+		t.Errorf("second Drain: %v", err)
+	}
+}
+
+func TestTrackerDrainDeadline(t *testing.T) {
+	tr := NewTracker()
+	done, err := tr.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tr.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain: got %v, want context.DeadlineExceeded", err)
+	}
+
+	done()
+	done() // idempotent
+	if tr.Running() != 0 {
+		t.Errorf("Running = %d after done, want 0", tr.Running())
+	}
+}
@@ -0,0 +1,296 @@
+package engine
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mrand "math/rand/v2"
+	"sync"
+	"time"
+
+	"skyrix/internal/logger"
+)
+
+var (
+	// ErrLockHeld is returned by TryLock when another owner holds the key.
+	ErrLockHeld = errors.New("lock is held by another owner")
+	// ErrLockLost is returned when a lease expired or was taken over before it was refreshed
+	// or released.
+	ErrLockLost = errors.New("lock lease lost")
+)
+
+const (
+	defaultLeaseTTL     = 30 * time.Second
+	defaultLockRetryMin = 50 * time.Millisecond
+	defaultLockRetryMax = time.Second
+)
+
+// LockOptions configures one TryLock/Lock call.
+type LockOptions struct {
+	// TTL is the lease length (default 30s). A crashed owner blocks the key at most this long.
+	TTL time.Duration
+	// NoRenew disables background renewal: the lease ends after TTL unless refreshed by hand.
+	NoRenew bool
+	// RetryMin/RetryMax bound the jittered exponential backoff of Lock (default 50ms..1s).
+	RetryMin, RetryMax time.Duration
+}
+
+type LockOption func(*LockOptions)
+
+// WithLeaseTTL sets the lease length.
+func WithLeaseTTL(ttl time.Duration) LockOption {
+	return func(o *LockOptions) { o.TTL = ttl }
+}
+
+// WithoutRenewal keeps the lease at its TTL; the owner must Refresh it for longer work.
+func WithoutRenewal() LockOption {
+	return func(o *LockOptions) { o.NoRenew = true }
+}
+
+// WithLockBackoff sets the wait between attempts of Lock.
+func WithLockBackoff(min, max time.Duration) LockOption {
+	return func(o *LockOptions) { o.RetryMin, o.RetryMax = min, max }
+}
+
+func lockOptions(opts []LockOption) LockOptions {
+	o := LockOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.TTL <= 0 {
+		o.TTL = defaultLeaseTTL
+	}
+	if o.RetryMin <= 0 {
+		o.RetryMin = defaultLockRetryMin
+	}
+	if o.RetryMax < o.RetryMin {
+		o.RetryMax = max(defaultLockRetryMax, o.RetryMin)
+	}
+	return o
+}
+
+// WithLock runs fn while holding key. fn's ctx is cancelled if the lease is lost, so work
+// stops before another owner can start. It waits for the key unless ctx is done; use
+// TryLock directly to skip work already in progress elsewhere.
+func WithLock(ctx context.Context, l Locker, key string, fn func(ctx context.Context) error, opts ...LockOption) error {
+	lock, err := l.Lock(ctx, key, opts...)
+	if err != nil {
+		return err
+	}
+	return RunLocked(ctx, lock, fn)
+}
+
+// RunLocked runs fn with a ctx cancelled when lock is lost, then unlocks it.
+// A lease lost during fn is reported as ErrLockLost.
+func RunLocked(ctx context.Context, lock Lock, fn func(ctx context.Context) error) error {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		select {
+		case <-lock.Lost():
+			cancel(ErrLockLost)
+		case <-runCtx.Done():
+		}
+	}()
+
+	err := fn(runCtx)
+	lost := false
+	select {
+	case <-lock.Lost():
+		lost = true
+	default:
+	}
+	if uerr := lock.Unlock(context.WithoutCancel(ctx)); uerr != nil && !errors.Is(uerr, ErrLockLost) && err == nil {
+		err = uerr
+	}
+	if lost {
+		return errors.Join(fmt.Errorf("%s: %w", lock.Key(), ErrLockLost), err)
+	}
+	return err
+}
+
+// lockBackend stores leases. Each method is atomic for its key.
+type lockBackend interface {
+	// acquire takes key for token if free and returns the new fence; ok is false if held.
+	acquire(ctx context.Context, key, token string, ttl time.Duration) (fence int64, ok bool, err error)
+	// refresh extends key's lease if token still owns it.
+	refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	// release deletes key if token still owns it.
+	release(ctx context.Context, key, token string) (bool, error)
+}
+
+// locker implements Locker on top of a lockBackend.
+type locker struct {
+	backend lockBackend
+	log     logger.Interface
+}
+
+func (l *locker) TryLock(ctx context.Context, key string, opts ...LockOption) (Lock, error) {
+	return l.try(ctx, key, lockOptions(opts))
+}
+
+func (l *locker) Lock(ctx context.Context, key string, opts ...LockOption) (Lock, error) {
+	o := lockOptions(opts)
+	delay := o.RetryMin
+	for {
+		lock, err := l.try(ctx, key, o)
+		if !errors.Is(err, ErrLockHeld) {
+			return lock, err
+		}
+		t := time.NewTimer(delay/2 + mrand.N(delay/2+1))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, fmt.Errorf("lock %s: %w", key, context.Cause(ctx))
+		case <-t.C:
+		}
+		if delay *= 2; delay > o.RetryMax {
+			delay = o.RetryMax
+		}
+	}
+}
+
+func (l *locker) try(ctx context.Context, key string, o LockOptions) (Lock, error) {
+	token := newLockToken()
+	fence, ok, err := l.backend.acquire(ctx, key, token, o.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("lock %s: %w", key, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("lock %s: %w", key, ErrLockHeld)
+	}
+	ls := &lease{
+		backend: l.backend,
+		log:     l.log,
+		key:     key,
+		token:   token,
+		fence:   fence,
+		ttl:     o.TTL,
+		lost:    make(chan struct{}),
+	}
+	if !o.NoRenew {
+		ls.startRenewal()
+	}
+	return ls, nil
+}
+
+func newLockToken() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// lease is a held Lock. Renewal runs every TTL/3; the lease counts as lost when the backend
+// reports another owner or no renewal succeeded for a whole TTL.
+type lease struct {
+	backend lockBackend
+	log     logger.Interface
+	key     string
+	token   string
+	fence   int64
+
+	mu       sync.Mutex
+	ttl      time.Duration
+	lost     chan struct{}
+	lostOnce sync.Once
+	stop     context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func (l *lease) Key() string           { return l.key }
+func (l *lease) Token() string         { return l.token }
+func (l *lease) Fence() int64          { return l.fence }
+func (l *lease) Lost() <-chan struct{} { return l.lost }
+
+func (l *lease) Refresh(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = defaultLeaseTTL
+	}
+	ok, err := l.backend.refresh(ctx, l.key, l.token, ttl)
+	if err != nil {
+		return fmt.Errorf("lock %s: %w", l.key, err)
+	}
+	if !ok {
+		l.markLost()
+		return fmt.Errorf("lock %s: %w", l.key, ErrLockLost)
+	}
+	l.mu.Lock()
+	l.ttl = ttl
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *lease) Unlock(ctx context.Context) error {
+	if l.stop != nil {
+		l.stop()
+		l.wg.Wait()
+	}
+	defer l.markLost()
+	ok, err := l.backend.release(ctx, l.key, l.token)
+	if err != nil {
+		return fmt.Errorf("unlock %s: %w", l.key, err)
+	}
+	if !ok {
+		return fmt.Errorf("unlock %s: %w", l.key, ErrLockLost)
+	}
+	return nil
+}
+
+func (l *lease) markLost() { l.lostOnce.Do(func() { close(l.lost) }) }
+
+func (l *lease) startRenewal() {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.stop = cancel
+	l.wg.Add(1)
+	go l.renew(ctx)
+}
+
+func (l *lease) renew(ctx context.Context) {
+	defer l.wg.Done()
+	renewed := time.Now()
+	for {
+		l.mu.Lock()
+		ttl := l.ttl
+		l.mu.Unlock()
+
+		t := time.NewTimer(ttl / 3)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-l.lost:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+
+		attempt, cancel := context.WithTimeout(ctx, ttl/3)
+		err := l.Refresh(attempt, ttl)
+		cancel()
+		switch {
+		case err == nil:
+			renewed = time.Now()
+		case errors.Is(err, ErrLockLost):
+			l.logLost(err)
+			return
+		case ctx.Err() != nil:
+			return
+		case time.Since(renewed) >= ttl:
+			l.markLost()
+			l.logLost(err)
+			return
+		default:
+			if l.log != nil {
+				l.log.Warn("lock renewal failed, retrying", "key", l.key, "error", err)
+			}
+		}
+	}
+}
+
+func (l *lease) logLost(err error) {
+	if l.log != nil {
+		l.log.Error("lock lease lost", "key", l.key, "fence", l.fence, "error", err)
+	}
+}
@@ -0,0 +1,383 @@
+package engine
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"skyrix/internal/engine/metrics"
+)
+
+// MemoryOpts configures a MemoryCache.
+type MemoryOpts struct {
+	MaxEntries    int              // 0 = unbounded
+	MaxBytes      int64            // keys + values; 0 = unbounded
+	DefaultTTL    time.Duration    // used for ttl < 0, like Redis StatusTTL (default: 10 minutes)
+	SweepInterval time.Duration    // how often expired entries are purged (default: 1 minute)
+	Metrics       *metrics.Metrics // Counts reads in cache_requests_total as backend "memory" (nil: not recorded)
+}
+
+// CacheStats is a point-in-time view of a MemoryCache.
+type CacheStats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64 // removed to respect MaxEntries/MaxBytes
+	Expirations uint64
+	Entries     int
+	Bytes       int64
+}
+
+type memEntry struct {
+	key     string
+	val     []byte
+	expires time.Time // zero = no expiry
+}
+
+func (e *memEntry) size() int64 { return int64(len(e.key) + len(e.val)) }
+
+// MemoryCache is an in-process LRU Cache with per-key TTL. TTL semantics match Redis:
+// ttl > 0 expires, ttl == 0 is persistent, ttl < 0 uses DefaultTTL.
+// Values are copied in and out, so callers may reuse their slices.
+type MemoryCache struct {
+	mu         sync.Mutex
+	ll         *list.List // most recently used first
+	items      map[string]*list.Element
+	bytes      int64
+	maxEntries int
+	maxBytes   int64
+	defaultTTL time.Duration
+
+	hits, misses, evictions, expirations atomic.Uint64
+	metrics                              *metrics.Metrics
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+var (
+	_ Cache          = (*MemoryCache)(nil)
+	_ KeyScanner     = (*MemoryCache)(nil)
+	_ CompareDeleter = (*MemoryCache)(nil)
+	_ Expirer        = (*MemoryCache)(nil)
+)
+
+func NewMemoryCache(opts MemoryOpts) *MemoryCache {
+	if opts.DefaultTTL <= 0 {
+		opts.DefaultTTL = 10 * time.Minute
+	}
+	if opts.SweepInterval <= 0 {
+		opts.SweepInterval = time.Minute
+	}
+	c := &MemoryCache{
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+		maxEntries: opts.MaxEntries,
+		maxBytes:   opts.MaxBytes,
+		defaultTTL: opts.DefaultTTL,
+		metrics:    opts.Metrics,
+		stop:       make(chan struct{}),
+	}
+	c.wg.Add(1)
+	go c.sweepLoop(opts.SweepInterval)
+	return c
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.liveLocked(key, time.Now())
+	if !ok {
+		c.misses.Add(1)
+		c.countGet("miss")
+		return nil, false, nil
+	}
+	c.hits.Add(1)
+	c.countGet("hit")
+	return append([]byte(nil), e.val...), true, nil
+}
+
+func (c *MemoryCache) countGet(result string) {
+	if c.metrics != nil {
+		c.metrics.CacheRequests.With("memory", result).Inc()
+	}
+}
+
+func (c *MemoryCache) Set(_ context.Context, key string, val []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(key, val, ttl)
+	return nil
+}
+
+func (c *MemoryCache) SetNX(_ context.Context, key string, val []byte, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.liveLocked(key, time.Now()); ok {
+		return false, nil
+	}
+	return c.setLocked(key, val, ttl), nil
+}
+
+func (c *MemoryCache) Del(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.removeLocked(el)
+	}
+	return nil
+}
+
+func (c *MemoryCache) DelIfValue(_ context.Context, key string, val []byte) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.liveLocked(key, time.Now())
+	if !ok || !bytes.Equal(e.val, val) {
+		return false, nil
+	}
+	c.removeLocked(c.items[key])
+	return true, nil
+}
+
+func (c *MemoryCache) Expire(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.liveLocked(key, time.Now())
+	if !ok {
+		return false, nil
+	}
+	if ttl < 0 {
+		ttl = c.defaultTTL
+	}
+	e.expires = time.Time{}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	return true, nil
+}
+
+func (c *MemoryCache) Exists(_ context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.liveLocked(key, time.Now())
+	return ok, nil
+}
+
+// ScanKeys returns all live keys matching pattern (Redis glob syntax) in one batch,
+// so the returned cursor is always 0.
+func (c *MemoryCache) ScanKeys(_ context.Context, pattern string, _ int, _ uint64) ([]string, uint64, error) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var keys []string
+	for key, el := range c.items {
+		if e := el.Value.(*memEntry); !e.expires.IsZero() && !now.Before(e.expires) {
+			continue
+		}
+		if globMatch(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, 0, nil
+}
+
+func (c *MemoryCache) DelMany(_ context.Context, keys []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.removeLocked(el)
+		}
+	}
+	return nil
+}
+
+// Stats returns hit/miss/eviction counters and the current size.
+func (c *MemoryCache) Stats() CacheStats {
+	c.mu.Lock()
+	entries, bytes := c.ll.Len(), c.bytes
+	c.mu.Unlock()
+	return CacheStats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Entries:     entries,
+		Bytes:       bytes,
+	}
+}
+
+// Close stops the background sweep. The cache stays usable.
+func (c *MemoryCache) Close() error {
+	c.once.Do(func() { close(c.stop) })
+	c.wg.Wait()
+	return nil
+}
+
+// liveLocked returns the unexpired entry for key and marks it recently used.
+func (c *MemoryCache) liveLocked(key string, now time.Time) (*memEntry, bool) {
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*memEntry)
+	if !e.expires.IsZero() && !now.Before(e.expires) {
+		c.removeLocked(el)
+		c.expirations.Add(1)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return e, true
+}
+
+// setLocked stores the entry and evicts least recently used ones beyond the bounds.
+// An entry larger than MaxBytes is not stored (and drops the previous value); it reports false.
+func (c *MemoryCache) setLocked(key string, val []byte, ttl time.Duration) bool {
+	if ttl < 0 {
+		ttl = c.defaultTTL
+	}
+	e := &memEntry{key: key, val: append([]byte(nil), val...)}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	if el, ok := c.items[key]; ok {
+		c.removeLocked(el)
+	}
+	if c.maxBytes > 0 && e.size() > c.maxBytes {
+		return false
+	}
+
+	c.items[key] = c.ll.PushFront(e)
+	c.bytes += e.size()
+	for c.ll.Len() > 1 && ((c.maxEntries > 0 && c.ll.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes)) {
+		c.removeLocked(c.ll.Back())
+		c.evictions.Add(1)
+	}
+	return true
+}
+
+func (c *MemoryCache) removeLocked(el *list.Element) {
+	e := c.ll.Remove(el).(*memEntry)
+	delete(c.items, e.key)
+	c.bytes -= e.size()
+}
+
+func (c *MemoryCache) sweepLoop(interval time.Duration) {
+	defer c.wg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-t.C:
+			c.sweep()
+		}
+	}
+}
+
+func (c *MemoryCache) sweep() {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for el := c.ll.Back(); el != nil; {
+		prev := el.Prev()
+		if e := el.Value.(*memEntry); !e.expires.IsZero() && !now.Before(e.expires) {
+			c.removeLocked(el)
+			c.expirations.Add(1)
+		}
+		el = prev
+	}
+}
+
+// globMatch matches s against a Redis-style glob: * any run, ? one byte, [abc] / [a-z] / [^a]
+// a class, \ escapes the next byte.
+func globMatch(pattern, s string) bool {
+	// Backtracking over the last *, which is enough for glob patterns.
+	var starP, starS = -1, 0
+	p, i := 0, 0
+	for i < len(s) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				starP, starS = p, i
+				p++
+				continue
+			case '?':
+				p++
+				i++
+				continue
+			case '[':
+				if n, ok := matchClass(pattern[p:], s[i]); n > 0 {
+					if ok {
+						p += n
+						i++
+						continue
+					}
+				} else if s[i] == '[' { // unterminated class: literal '['
+					p++
+					i++
+					continue
+				}
+			case '\\':
+				if p+1 < len(pattern) && pattern[p+1] == s[i] {
+					p += 2
+					i++
+					continue
+				}
+			default:
+				if pattern[p] == s[i] {
+					p++
+					i++
+					continue
+				}
+			}
+		}
+		if starP < 0 {
+			return false
+		}
+		starS++
+		p, i = starP+1, starS
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchClass matches b against the class at the start of pattern ("[...]"). It returns the
+// class length (0 if unterminated) and whether b is in it.
+func matchClass(pattern string, b byte) (int, bool) {
+	i := 1
+	negate := i < len(pattern) && pattern[i] == '^'
+	if negate {
+		i++
+	}
+	matched := false
+	for i < len(pattern) && pattern[i] != ']' {
+		lo := pattern[i]
+		if lo == '\\' && i+1 < len(pattern) {
+			i++
+			lo = pattern[i]
+		}
+		hi := lo
+		if i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']' {
+			hi = pattern[i+2]
+			i += 2
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+		}
+		if lo <= b && b <= hi {
+			matched = true
+		}
+		i++
+	}
+	if i >= len(pattern) {
+		return 0, false
+	}
+	return i + 1, matched != negate
+}
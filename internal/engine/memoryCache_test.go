@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"skyrix/internal/config"
+	"skyrix/internal/engine/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestMemoryCache(t *testing.T, opts MemoryOpts) *MemoryCache {
+	t.Helper()
+	c := NewMemoryCache(opts)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestMemoryCacheCopiesValues(t *testing.T) {
+	ctx := context.Background()
+	c := newTestMemoryCache(t, MemoryOpts{})
+
+	val := []byte("abc")
+	_ = c.Set(ctx, "k", val, 0)
+	val[0] = 'x'
+	got, ok, _ := c.Get(ctx, "k")
+	if !ok || string(got) != "abc" {
+		t.Fatalf("Get = %q, %v; the caller's slice leaked in", got, ok)
+	}
+	got[0] = 'y'
+	if again, _, _ := c.Get(ctx, "k"); string(again) != "abc" {
+		t.Fatalf("Get = %q; the returned slice aliases the entry", again)
+	}
+}
+
+func TestMemoryCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	c := newTestMemoryCache(t, MemoryOpts{DefaultTTL: 20 * time.Millisecond})
+
+	_ = c.Set(ctx, "short", []byte("1"), 20*time.Millisecond)
+	_ = c.Set(ctx, "default", []byte("1"), -1)
+	_ = c.Set(ctx, "forever", []byte("1"), 0)
+	time.Sleep(40 * time.Millisecond)
+
+	for key, want := range map[string]bool{"short": false, "default": false, "forever": true} {
+		if ok, _ := c.Exists(ctx, key); ok != want {
+			t.Errorf("Exists(%q) = %v, want %v", key, ok, want)
+		}
+	}
+	if s := c.Stats(); s.Expirations != 2 || s.Entries != 1 {
+		t.Fatalf("stats = %+v, want 2 expirations and 1 entry", s)
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := newTestMemoryCache(t, MemoryOpts{MaxEntries: 2})
+
+	_ = c.Set(ctx, "a", []byte("1"), 0)
+	_ = c.Set(ctx, "b", []byte("2"), 0)
+	_, _, _ = c.Get(ctx, "a") // b is now the least recently used
+	_ = c.Set(ctx, "c", []byte("3"), 0)
+
+	if ok, _ := c.Exists(ctx, "b"); ok {
+		t.Fatal("least recently used entry was kept")
+	}
+	for _, key := range []string{"a", "c"} {
+		if ok, _ := c.Exists(ctx, key); !ok {
+			t.Fatalf("%q was evicted", key)
+		}
+	}
+	if s := c.Stats(); s.Evictions != 1 {
+		t.Fatalf("evictions = %d, want 1", s.Evictions)
+	}
+}
+
+func TestMemoryCacheMaxBytes(t *testing.T) {
+	ctx := context.Background()
+	c := newTestMemoryCache(t, MemoryOpts{MaxBytes: 10})
+
+	_ = c.Set(ctx, "a", []byte("1234"), 0) // 5 bytes with the key
+	_ = c.Set(ctx, "b", []byte("1234"), 0)
+	_ = c.Set(ctx, "c", []byte("1234"), 0)
+	if s := c.Stats(); s.Bytes > 10 || s.Entries != 2 {
+		t.Fatalf("stats = %+v, want at most 10 bytes in 2 entries", s)
+	}
+
+	_ = c.Set(ctx, "big", []byte("far more than ten bytes"), 0)
+	if ok, _ := c.Exists(ctx, "big"); ok {
+		t.Fatal("entry larger than MaxBytes was stored")
+	}
+}
+
+func TestMemoryCacheConditionalWrites(t *testing.T) {
+	ctx := context.Background()
+	c := newTestMemoryCache(t, MemoryOpts{})
+
+	if ok, _ := c.SetNX(ctx, "k", []byte("a"), 0); !ok {
+		t.Fatal("SetNX on a missing key did not store")
+	}
+	if ok, _ := c.SetNX(ctx, "k", []byte("b"), 0); ok {
+		t.Fatal("SetNX overwrote a live key")
+	}
+	if ok, _ := c.DelIfValue(ctx, "k", []byte("b")); ok {
+		t.Fatal("DelIfValue deleted another owner's value")
+	}
+	if ok, _ := c.DelIfValue(ctx, "k", []byte("a")); !ok {
+		t.Fatal("DelIfValue kept the matching value")
+	}
+
+	_ = c.Set(ctx, "t", []byte("1"), 0)
+	if ok, _ := c.Expire(ctx, "t", 10*time.Millisecond); !ok {
+		t.Fatal("Expire on a live key reported false")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if ok, _ := c.Exists(ctx, "t"); ok {
+		t.Fatal("key outlived the TTL set by Expire")
+	}
+	if ok, _ := c.Expire(ctx, "t", time.Minute); ok {
+		t.Fatal("Expire on a missing key reported true")
+	}
+}
+
+func TestMemoryCacheScanKeys(t *testing.T) {
+	ctx := context.Background()
+	c := newTestMemoryCache(t, MemoryOpts{})
+	for _, key := range []string{"app:t:acme:1", "app:t:acme:2", "app:t:acme#7:1", "app:t:beta:1", "app:global:1"} {
+		_ = c.Set(ctx, key, []byte("1"), 0)
+	}
+
+	keys, cursor, err := c.ScanKeys(ctx, "app:t:acme:*", 10, 0)
+	if err != nil || cursor != 0 {
+		t.Fatalf("ScanKeys: cursor %d, err %v", cursor, err)
+	}
+	slices.Sort(keys)
+	if want := []string{"app:t:acme:1", "app:t:acme:2"}; !slices.Equal(keys, want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+
+	if err := c.DelMany(ctx, keys); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.Stats(); s.Entries != 3 {
+		t.Fatalf("entries after DelMany = %d, want 3", s.Entries)
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"a*c", "abbbc", true},
+		{"a*c", "abbbd", false},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"[a-c]x", "bx", true},
+		{"[^a-c]x", "bx", false},
+		{`a\*`, "a*", true},
+		{`a\*`, "ab", false},
+		{"t:acme#*", "t:acme#7:k", true},
+		{"[ab", "[ab", true},
+	}
+	for _, c := range cases {
+		if got := globMatch(c.pattern, c.s); got != c.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", c.pattern, c.s, got, c.want)
+		}
+	}
+}
+
+type nopLifecycle struct{}
+
+func (nopLifecycle) OnStop(string, func(context.Context) error) {}
+func (nopLifecycle) Ready() bool                                { return true }
+
+func TestMemoryCacheCountsCacheReads(t *testing.T) {
+	ctx := context.Background()
+	m, err := metrics.New(prometheus.NewRegistry(), metrics.Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Cache.Backend = CacheMemory
+	c, cleanup, err := ProvideCache(cfg, nil, testLogger(), nopLifecycle{}, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cleanup)
+
+	_ = c.Set(ctx, "k", []byte("v"), time.Minute)
+	_, _, _ = c.Get(ctx, "k")
+	_, _, _ = c.Get(ctx, "k")
+	_, _, _ = c.Get(ctx, "missing")
+
+	for result, want := range map[string]float64{"hit": 2, "miss": 1} {
+		if got := testutil.ToFloat64(m.CacheRequests.With("memory", result)); got != want {
+			t.Errorf("%s = %v, want %v", result, got, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"skyrix/internal/logger"
+)
+
+// MemoryLocker is a Locker for a single process (tests, local development with
+// CACHE_BACKEND=memory). It behaves like RedisLocker, including fencing tokens.
+type MemoryLocker struct {
+	*locker
+
+	mu     sync.Mutex
+	leases map[string]memLease
+	fences map[string]int64
+}
+
+type memLease struct {
+	token   string
+	expires time.Time
+}
+
+var _ Locker = (*MemoryLocker)(nil)
+
+func NewMemoryLocker(log logger.Interface) *MemoryLocker {
+	l := &MemoryLocker{leases: map[string]memLease{}, fences: map[string]int64{}}
+	l.locker = &locker{backend: l, log: log}
+	return l
+}
+
+func (l *MemoryLocker) acquire(_ context.Context, key, token string, ttl time.Duration) (int64, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if cur, ok := l.leases[key]; ok && now.Before(cur.expires) {
+		return 0, false, nil
+	}
+	l.leases[key] = memLease{token: token, expires: now.Add(ttl)}
+	l.fences[key]++
+	return l.fences[key], true, nil
+}
+
+func (l *MemoryLocker) refresh(_ context.Context, key, token string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	cur, ok := l.leases[key]
+	if !ok || cur.token != token || !now.Before(cur.expires) {
+		return false, nil
+	}
+	l.leases[key] = memLease{token: token, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (l *MemoryLocker) release(_ context.Context, key, token string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur, ok := l.leases[key]
+	if !ok || cur.token != token {
+		return false, nil
+	}
+	delete(l.leases, key)
+	return time.Now().Before(cur.expires), nil
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Buckets (seconds) for the duration histograms.
+var (
+	HTTPBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	DBBuckets   = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+	JobBuckets  = []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900}
+)
+
+// Opts configures the cardinality limits of Metrics.
+type Opts struct {
+	// MaxSeries caps the label combinations of every labelled metric (default 1000).
+	MaxSeries int
+	// MaxTenants caps the distinct values of the tenant label (default 100).
+	MaxTenants int
+}
+
+// Metrics holds the framework metrics. It is built once per process by New and injected into
+// the instrumented packages; a nil *Metrics records nothing.
+type Metrics struct {
+	HTTPRequests *CounterVec
+	HTTPDuration *HistogramVec
+	HTTPInFlight prometheus.Gauge
+
+	DBQueryDuration *HistogramVec
+	DBQueryErrors   *CounterVec
+
+	CacheRequests *CounterVec
+
+	JobRuns     *CounterVec
+	JobDuration *HistogramVec
+	JobRetries  *CounterVec
+
+	TenantResolutions *CounterVec
+
+	tenants *limiter
+}
+
+// New registers the framework metrics on reg. It fails if one of them is already registered.
+func New(reg prometheus.Registerer, opts Opts) (*Metrics, error) {
+	if opts.MaxSeries <= 0 {
+		opts.MaxSeries = 1000
+	}
+	if opts.MaxTenants <= 0 {
+		opts.MaxTenants = 100
+	}
+	f := &factory{reg: reg, maxSeries: opts.MaxSeries}
+	m := &Metrics{
+		HTTPRequests: f.counterVec("http_requests_total",
+			"HTTP requests by method, route pattern, status code and tenant.",
+			"method", "route", "status", "tenant"),
+		HTTPDuration: f.histogramVec("http_request_duration_seconds",
+			"HTTP request latency by method, route pattern and tenant.",
+			HTTPBuckets, "method", "route", "tenant"),
+		HTTPInFlight: f.gauge("http_requests_in_flight",
+			"HTTP requests being served."),
+
+		DBQueryDuration: f.histogramVec("db_query_duration_seconds",
+			"GORM statement duration by operation and table.",
+			DBBuckets, "operation", "table"),
+		DBQueryErrors: f.counterVec("db_query_errors_total",
+			"Failed GORM statements by operation and table (record not found is not an error).",
+			"operation", "table"),
+
+		CacheRequests: f.counterVec("cache_requests_total",
+			"Cache reads by backend (redis, memory) and result (hit, miss, error).",
+			"backend", "result"),
+
+		JobRuns: f.counterVec("job_runs_total",
+			"Job runs by job and outcome (success, failure, panic).",
+			"job", "outcome"),
+		JobDuration: f.histogramVec("job_duration_seconds",
+			"Job run duration including retries, by job and outcome.",
+			JobBuckets, "job", "outcome"),
+		JobRetries: f.counterVec("job_retries_total",
+			"Job attempts after the first one, by job.",
+			"job"),
+
+		TenantResolutions: f.counterVec("tenant_resolutions_total",
+			"Tenant resolution outcomes by resolver (resolved, missing, not_found, error).",
+			"resolver", "outcome"),
+
+		tenants: newLimiter(opts.MaxTenants, 1),
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return m, nil
+}
+
+// TenantLabel returns the tenant label for tenant: "none" when empty, tenant while under
+// Opts.MaxTenants, OtherLabel after.
+func (m *Metrics) TenantLabel(tenant string) string {
+	if tenant == "" {
+		return "none"
+	}
+	return m.tenants.labels([]string{tenant})[0]
+}
+
+// ---- request labels ----
+
+type requestKey struct{}
+
+// Request collects labels known only deeper in the handler chain (the tenant is resolved by
+// route-group middleware) for the HTTP metrics middleware.
+type Request struct {
+	mu     sync.Mutex
+	tenant string
+}
+
+// WithRequest attaches a Request to ctx.
+func WithRequest(ctx context.Context) (context.Context, *Request) {
+	req := &Request{}
+	return context.WithValue(ctx, requestKey{}, req), req
+}
+
+// Tenant returns the tenant recorded with TagTenant.
+func (r *Request) Tenant() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tenant
+}
+
+// TagTenant records the tenant of ctx (tenantContext.Key) on the request's metrics.
+// It does nothing outside an instrumented request.
+func TagTenant(ctx context.Context) {
+	if req, ok := ctx.Value(requestKey{}).(*Request); ok {
+		req.mu.Lock()
+		req.tenant = tenantContext.Key(ctx)
+		req.mu.Unlock()
+	}
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestMetrics(t *testing.T, opts Opts) (*prometheus.Registry, *Metrics) {
+	t.Helper()
+	reg := NewRegistry()
+	m, err := New(reg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reg, m
+}
+
+func TestHandlerServesRegistry(t *testing.T) {
+	reg, m := newTestMetrics(t, Opts{})
+	m.CacheRequests.With("redis", "hit").Inc()
+	m.JobDuration.With("ping", "success").Observe(0.2)
+
+	rec := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 200 {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want the text format", ct)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		"# TYPE cache_requests_total counter",
+		`cache_requests_total{backend="redis",result="hit"} 1`,
+		`job_duration_seconds_bucket{job="ping",outcome="success",le="0.5"} 1`,
+		"go_goroutines ",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("scrape is missing %q", want)
+		}
+	}
+}
+
+func TestNewFailsOnDuplicateRegistration(t *testing.T) {
+	reg, _ := newTestMetrics(t, Opts{})
+	if _, err := New(reg, Opts{}); err == nil {
+		t.Fatal("second New on the same registry succeeded")
+	}
+}
+
+func TestSeriesAreCapped(t *testing.T) {
+	_, m := newTestMetrics(t, Opts{MaxSeries: 2})
+	m.JobRuns.With("a", "success").Inc()
+	m.JobRuns.With("b", "success").Inc()
+	m.JobRuns.With("c", "success").Inc()
+	m.JobRuns.With("d", "failure").Inc()
+	m.JobRuns.With("a", "success").Inc() // known series are still counted on their own
+
+	if got := testutil.CollectAndCount(m.JobRuns.vec); got != 3 {
+		t.Errorf("series = %d, want 2 plus the overflow series", got)
+	}
+	if got := testutil.ToFloat64(m.JobRuns.vec.WithLabelValues(OtherLabel, OtherLabel)); got != 2 {
+		t.Errorf("overflow series = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.JobRuns.vec.WithLabelValues("a", "success")); got != 2 {
+		t.Errorf("series a = %v, want 2", got)
+	}
+}
+
+func TestTenantLabelIsCapped(t *testing.T) {
+	_, m := newTestMetrics(t, Opts{MaxTenants: 1})
+	if got := m.TenantLabel(""); got != "none" {
+		t.Errorf("TenantLabel(\"\") = %q, want none", got)
+	}
+	if got := m.TenantLabel("acme"); got != "acme" {
+		t.Errorf("first tenant = %q, want acme", got)
+	}
+	if got := m.TenantLabel("globex"); got != OtherLabel {
+		t.Errorf("tenant over the limit = %q, want %q", got, OtherLabel)
+	}
+	if got := m.TenantLabel("acme"); got != "acme" {
+		t.Errorf("known tenant = %q, want acme", got)
+	}
+}
@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"skyrix/internal/config"
+
+	"github.com/google/wire"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var ProviderSet = wire.NewSet(
+	NewRegistry,
+	ProvideMetrics,
+)
+
+// ProvideMetrics registers the framework metrics on reg with the configured cardinality limits.
+func ProvideMetrics(reg *prometheus.Registry, cfg *config.Metrics) (*Metrics, error) {
+	return New(reg, Opts{MaxSeries: cfg.MaxSeries, MaxTenants: cfg.MaxTenants})
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// OtherLabel replaces label values once a metric or the tenant label hits its limit.
+const OtherLabel = "_other"
+
+// NewRegistry returns a Prometheus registry with the Go runtime and process collectors.
+// The framework metrics are registered on it by New.
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return reg
+}
+
+// Handler serves the metrics of reg for Prometheus scrapes.
+func Handler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg})
+}
+
+// CounterVec is a prometheus.CounterVec capped at a number of label combinations. Further
+// combinations are counted in one series whose labels are all OtherLabel, so a bad label
+// cannot exhaust memory.
+type CounterVec struct {
+	vec   *prometheus.CounterVec
+	limit *limiter
+}
+
+// With returns the counter of the label values, in the order the labels were declared.
+func (v *CounterVec) With(values ...string) prometheus.Counter {
+	return v.vec.WithLabelValues(v.limit.labels(values)...)
+}
+
+// HistogramVec is a prometheus.HistogramVec capped like CounterVec.
+type HistogramVec struct {
+	vec   *prometheus.HistogramVec
+	limit *limiter
+}
+
+// With returns the histogram of the label values, in the order the labels were declared.
+func (v *HistogramVec) With(values ...string) prometheus.Observer {
+	return v.vec.WithLabelValues(v.limit.labels(values)...)
+}
+
+// limiter admits the first max distinct label combinations.
+type limiter struct {
+	max   int
+	other []string
+
+	mu   sync.RWMutex
+	seen map[string]struct{}
+}
+
+func newLimiter(max, labels int) *limiter {
+	other := make([]string, labels)
+	for i := range other {
+		other[i] = OtherLabel
+	}
+	return &limiter{max: max, other: other, seen: make(map[string]struct{})}
+}
+
+// labels returns values while they are known or under the limit, and OtherLabel for every
+// label after.
+func (l *limiter) labels(values []string) []string {
+	key := strings.Join(values, "\xff")
+	l.mu.RLock()
+	_, ok := l.seen[key]
+	l.mu.RUnlock()
+	if ok {
+		return values
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.seen[key]; ok {
+		return values
+	}
+	if len(l.seen) >= l.max {
+		return l.other
+	}
+	l.seen[key] = struct{}{}
+	return values
+}
+
+// factory registers collectors and keeps the first registration error.
+type factory struct {
+	reg       prometheus.Registerer
+	maxSeries int
+	err       error
+}
+
+func (f *factory) register(c prometheus.Collector) {
+	if err := f.reg.Register(c); err != nil && f.err == nil {
+		f.err = err
+	}
+}
+
+func (f *factory) counterVec(name, help string, labels ...string) *CounterVec {
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+	f.register(vec)
+	return &CounterVec{vec: vec, limit: newLimiter(f.maxSeries, len(labels))}
+}
+
+func (f *factory) histogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	vec := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)
+	f.register(vec)
+	return &HistogramVec{vec: vec, limit: newLimiter(f.maxSeries, len(labels))}
+}
+
+func (f *factory) gauge(name, help string) prometheus.Gauge {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: help})
+	f.register(g)
+	return g
+}
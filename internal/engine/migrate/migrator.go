@@ -0,0 +1,139 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"skyrix/internal/engine"
+	"skyrix/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+const upSuffix = ".up.sql"
+
+// Migration is one "<version>_<name>.up.sql" file.
+type Migration struct {
+	Version string
+	Name    string
+	SQL     string
+}
+
+// Opts configures the Migrator.
+type Opts struct {
+	Dir string
+}
+
+// Migrator applies SQL migrations to the main database. Applied versions are recorded in
+// {{main}}.schema_migrations. Up holds the engine.Locker key "migrate:<main schema>" for the
+// whole run, so two instances never migrate at once: the second waits and then finds nothing
+// left to apply. Each migration runs in its own transaction together with its version row, and
+// a version that is already recorded is skipped, so a run that lost its lease cannot apply a
+// migration twice either.
+type Migrator struct {
+	DB     engine.DB
+	Tx     engine.TransactionManager
+	Locker engine.Locker
+	Logger logger.Interface
+	FS     fs.FS
+}
+
+func NewMigrator(db *engine.Database, tx engine.TransactionManager, locker engine.Locker, log logger.Interface, opts Opts) *Migrator {
+	dir := strings.TrimSpace(opts.Dir)
+	if dir == "" {
+		dir = "migrations"
+	}
+	return &Migrator{DB: db, Tx: tx, Locker: locker, Logger: log, FS: os.DirFS(dir)}
+}
+
+// Up applies every pending migration in version order and returns the applied versions.
+// Raw SQL may use the {{main}} placeholder; migrations run without a statement timeout.
+func (m *Migrator) Up(ctx context.Context) ([]string, error) {
+	migrations, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []string
+	err = engine.WithLock(ctx, m.Locker, "migrate:"+m.DB.Main(), func(ctx context.Context) error {
+		if err := m.DB.WithContext(ctx).Exec(`CREATE TABLE IF NOT EXISTS {{main}}.schema_migrations (
+	version text PRIMARY KEY,
+	name text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`).Error; err != nil {
+			return fmt.Errorf("create schema_migrations: %w", err)
+		}
+		for _, mg := range migrations {
+			ok, err := m.apply(ctx, mg)
+			if err != nil {
+				return fmt.Errorf("migration %s_%s: %w", mg.Version, mg.Name, err)
+			}
+			if ok {
+				m.Logger.Info("migration applied", "version", mg.Version, "name", mg.Name)
+				applied = append(applied, mg.Version)
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// apply runs mg unless its version is recorded; ok reports whether it ran.
+func (m *Migrator) apply(ctx context.Context, mg Migration) (ok bool, err error) {
+	err = m.Tx.Execute(ctx, func(tx *gorm.DB) error {
+		res := tx.Exec(`INSERT INTO {{main}}.schema_migrations (version, name) VALUES (?, ?) ON CONFLICT (version) DO NOTHING`, mg.Version, mg.Name)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Exec("SET LOCAL statement_timeout = 0").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(mg.SQL).Error; err != nil {
+			return err
+		}
+		ok = true
+		return nil
+	})
+	return ok, err
+}
+
+// Load reads the migrations from FS, sorted by version. Versions compare as strings, so
+// zero-pad them (0001, 0002) or use timestamps. Files not ending in ".up.sql" are ignored;
+// a file without a "<version>_" prefix or a repeated version is an error.
+func (m *Migrator) Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(m.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+	seen := make(map[string]string)
+	var out []Migration
+	for _, e := range entries {
+		file := e.Name()
+		if e.IsDir() || !strings.HasSuffix(file, upSuffix) {
+			continue
+		}
+		version, name, ok := strings.Cut(strings.TrimSuffix(file, upSuffix), "_")
+		if !ok || version == "" || name == "" {
+			return nil, fmt.Errorf("migration %s: want <version>_<name>%s", file, upSuffix)
+		}
+		if prev, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migration version %s used by %s and %s", version, prev, file)
+		}
+		seen[version] = file
+
+		body, err := fs.ReadFile(m.FS, file)
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", file, err)
+		}
+		out = append(out, Migration{Version: version, Name: name, SQL: string(body)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
@@ -0,0 +1,139 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"skyrix/internal/engine"
+	"skyrix/internal/kernel/db/scope"
+	"skyrix/internal/logger"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+const testSchema = "skyrix_test_migrate"
+
+func testLogger() logger.Interface {
+	return logger.NewSlogWrapper(slog.New(slog.DiscardHandler))
+}
+
+var testFS = fstest.MapFS{
+	"0002_add_email.up.sql":   {Data: []byte(`ALTER TABLE {{main}}.people ADD COLUMN email text`)},
+	"0001_people.up.sql":      {Data: []byte(`CREATE TABLE {{main}}.people (id bigserial PRIMARY KEY, name text NOT NULL)`)},
+	"0001_people.down.sql":    {Data: []byte(`DROP TABLE {{main}}.people`)},
+	"README.md":               {Data: []byte("ignored")},
+	"0003_seed/readme.up.sql": {Data: []byte("directories are ignored")},
+}
+
+func TestLoadSortsUpMigrations(t *testing.T) {
+	m := &Migrator{FS: testFS}
+	got, err := m.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []string
+	for _, mg := range got {
+		versions = append(versions, mg.Version+"_"+mg.Name)
+	}
+	if want := []string{"0001_people", "0002_add_email"}; !slices.Equal(versions, want) {
+		t.Errorf("migrations = %v, want %v", versions, want)
+	}
+}
+
+func TestLoadRejectsBadNames(t *testing.T) {
+	for name, fsys := range map[string]fstest.MapFS{
+		"no name":           {"0001.up.sql": {}},
+		"duplicate version": {"0001_a.up.sql": {}, "0001_b.up.sql": {}},
+	} {
+		if _, err := (&Migrator{FS: fsys}).Load(); err == nil {
+			t.Errorf("%s: Load succeeded", name)
+		}
+	}
+}
+
+// A second Up waits for the lock before it touches the database (DB is nil here).
+func TestUpWaitsForTheLock(t *testing.T) {
+	locker := engine.NewMemoryLocker(testLogger())
+	held, err := locker.TryLock(context.Background(), "migrate:"+testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Unlock(context.Background())
+
+	m := &Migrator{DB: mainOnly(testSchema), Locker: locker, Logger: testLogger(), FS: testFS}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := m.Up(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Up while locked: got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+// mainOnly is an engine.DB that only knows its main schema.
+type mainOnly string
+
+func (s mainOnly) Main() string                           { return string(s) }
+func (mainOnly) WithContext(ctx context.Context) *gorm.DB { panic("database used without the lock") }
+
+// Needs SKYRIX_TEST_POSTGRES_DSN; creates and drops the schema skyrix_test_migrate.
+func TestUpConcurrentRunsApplyOnce(t *testing.T) {
+	dsn := os.Getenv("SKYRIX_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("SKYRIX_TEST_POSTGRES_DSN is not set")
+	}
+	gdb, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormLogger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gdb.Use(scope.NewPlugin(testSchema, true)); err != nil {
+		t.Fatal(err)
+	}
+	drop := func() { gdb.Exec(`DROP SCHEMA IF EXISTS "` + testSchema + `" CASCADE`) }
+	drop()
+	if err := gdb.Exec(`CREATE SCHEMA "` + testSchema + `"`).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(drop)
+
+	db := engine.NewDatabaseService(gdb, testSchema)
+	locker := engine.NewMemoryLocker(testLogger())
+	newMigrator := func() *Migrator {
+		return &Migrator{DB: db, Tx: engine.NewTxManager(db, testLogger()), Locker: locker, Logger: testLogger(), FS: testFS}
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		applied []string
+	)
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := newMigrator().Up(context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			applied = append(applied, got...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	slices.Sort(applied)
+	if want := []string{"0001", "0002"}; !slices.Equal(applied, want) {
+		t.Errorf("applied = %v, want each version once: %v", applied, want)
+	}
+	if err := gdb.Exec(`INSERT INTO "` + testSchema + `".people (name, email) VALUES ('a', 'a@example.com')`).Error; err != nil {
+		t.Errorf("migrated table: %v", err)
+	}
+}
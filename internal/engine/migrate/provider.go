@@ -0,0 +1,17 @@
+package migrate
+
+import (
+	"skyrix/internal/config"
+
+	"github.com/google/wire"
+)
+
+// ProviderSet wires the Migrator used by migrate:up.
+var ProviderSet = wire.NewSet(
+	NewMigrator,
+	ProvideOpts,
+)
+
+func ProvideOpts(cfg *config.Config) Opts {
+	return Opts{Dir: cfg.Database.MigrationsDir}
+}
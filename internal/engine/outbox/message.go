@@ -0,0 +1,81 @@
+// Package outbox implements the transactional outbox pattern.
+//
+// Domain code writes events into the outbox table through Publisher inside the same
+// TxManager.Execute transaction as its state change. The Relay then polls the table
+// (FOR UPDATE SKIP LOCKED, so several instances can run side by side) and hands each
+// message to a Sink, retrying with backoff until it is published or marked dead.
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"skyrix/internal/kernel/db/scope"
+)
+
+// TableBase is the outbox table name; it always lives in the main schema (of the main database,
+// or of a tenant's dedicated database for that tenant's events).
+//
+//	CREATE TABLE <main>.outbox (
+//	    id            bigserial PRIMARY KEY,
+//	    topic         text        NOT NULL,
+//	    key           text        NOT NULL DEFAULT '',
+//	    payload       jsonb       NOT NULL,
+//	    headers       jsonb,
+//	    tenant_schema text        NOT NULL DEFAULT '',
+//	    tenant_id     bigint      NOT NULL DEFAULT 0,
+//	    status        text        NOT NULL DEFAULT 'pending',
+//	    attempts      int         NOT NULL DEFAULT 0,
+//	    last_error    text,
+//	    available_at  timestamptz NOT NULL DEFAULT now(),
+//	    created_at    timestamptz NOT NULL DEFAULT now(),
+//	    published_at  timestamptz
+//	);
+//	CREATE INDEX ix_outbox_pending ON <main>.outbox (available_at, id) WHERE status = 'pending';
+const TableBase = "outbox"
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusPublished Status = "published"
+	StatusDead      Status = "dead" // gave up after MaxAttempts
+)
+
+// Message is a stored outbox row.
+type Message struct {
+	scope.MainModel
+
+	ID           int64           `gorm:"column:id;primaryKey"`
+	Topic        string          `gorm:"column:topic;type:text;not null"`
+	Key          string          `gorm:"column:key;type:text;not null;default:''"`
+	Payload      json.RawMessage `gorm:"column:payload;type:jsonb;not null"`
+	Headers      json.RawMessage `gorm:"column:headers;type:jsonb"`
+	TenantSchema string          `gorm:"column:tenant_schema;type:text;not null;default:''"`
+	TenantID     int64           `gorm:"column:tenant_id;not null;default:0"` // shared-schema tenants only
+	Status       Status          `gorm:"column:status;type:text;not null;default:'pending';index:ix_outbox_pending,where:status = 'pending'"`
+	Attempts     int             `gorm:"column:attempts;not null;default:0"`
+	LastError    *string         `gorm:"column:last_error;type:text"`
+	AvailableAt  time.Time       `gorm:"column:available_at;not null;index:ix_outbox_pending"`
+	CreatedAt    time.Time       `gorm:"column:created_at;not null"`
+	PublishedAt  *time.Time      `gorm:"column:published_at"`
+}
+
+func (Message) TableName() string { return TableBase }
+
+// HeaderMap decodes Headers (empty map when unset or invalid).
+func (m *Message) HeaderMap() map[string]string {
+	out := map[string]string{}
+	if len(m.Headers) > 0 {
+		_ = json.Unmarshal(m.Headers, &out)
+	}
+	return out
+}
+
+// Event is what domain code publishes.
+type Event struct {
+	Topic   string
+	Key     string            // optional aggregate/partition key
+	Payload any               // JSON-encoded; json.RawMessage/[]byte are stored as-is
+	Headers map[string]string // optional metadata
+}
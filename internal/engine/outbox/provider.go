@@ -0,0 +1,50 @@
+package outbox
+
+import (
+	"fmt"
+	"strings"
+
+	"skyrix/internal/config"
+	"skyrix/internal/engine"
+	"skyrix/internal/logger"
+
+	"github.com/google/wire"
+)
+
+// ProviderSet wires the outbox: publisher for domain services, relay + sink for workers.
+var ProviderSet = wire.NewSet(
+	NewPublisher,
+	NewMemorySink,
+	ProvideRelay,
+	ProvideOpts,
+	ProvideSink,
+)
+
+// ProvideRelay builds the relay and stops it at shutdown, before the database it reads.
+func ProvideRelay(db *engine.Database, tx engine.TransactionManager, sink Sink, tenants TenantDatabases, lc engine.Lifecycle, log logger.Interface, opts Opts) *Relay {
+	r := NewRelay(db, tx, sink, tenants, log, opts)
+	lc.OnStop("outbox relay", r.Stop)
+	return r
+}
+
+func ProvideOpts(cfg *config.Config) Opts {
+	return Opts{
+		PollInterval:  cfg.Outbox.PollInterval,
+		BatchSize:     cfg.Outbox.BatchSize,
+		MaxAttempts:   cfg.Outbox.MaxAttempts,
+		Retention:     cfg.Outbox.Retention,
+		DeadRetention: cfg.Outbox.DeadRetention,
+	}
+}
+
+// ProvideSink selects the sink by config (OUTBOX_SINK: jobs|memory, default jobs).
+func ProvideSink(cfg *config.Config, mem *MemorySink, jobs JobRunner) (Sink, error) {
+	switch sink := strings.ToLower(strings.TrimSpace(cfg.Outbox.Sink)); sink {
+	case "", "jobs":
+		return NewJobSink(jobs), nil
+	case "memory":
+		return mem, nil
+	default:
+		return nil, fmt.Errorf("unknown outbox sink %q (want jobs or memory)", sink)
+	}
+}
@@ -0,0 +1,94 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"skyrix/internal/engine"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+
+	"gorm.io/gorm"
+)
+
+var ErrNoTransaction = errors.New("outbox: publish requires a transaction")
+
+// Publisher writes events into the outbox table.
+type Publisher struct {
+	DB engine.DB
+}
+
+func NewPublisher(db *engine.Database) *Publisher {
+	return &Publisher{DB: db}
+}
+
+// Publish stores events using tx, so they commit or roll back together with the caller's writes.
+// Call it with the tx handed to TxManager.Execute. The tenant from ctx (schema, and ID for
+// shared-schema tenants) is recorded on every message and restored in the context the sink receives.
+func (p *Publisher) Publish(ctx context.Context, tx *gorm.DB, events ...Event) error {
+	if tx == nil {
+		return ErrNoTransaction
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	schema := tenantContext.SchemaFrom(ctx)
+	tenantID, _ := tenantContext.TenantIDFrom(ctx)
+	now := time.Now().UTC()
+
+	rows := make([]Message, 0, len(events))
+	for _, ev := range events {
+		topic := strings.TrimSpace(ev.Topic)
+		if topic == "" {
+			return errors.New("outbox: event topic is empty")
+		}
+		payload, err := encodePayload(ev.Payload)
+		if err != nil {
+			return fmt.Errorf("outbox: encode %q payload: %w", topic, err)
+		}
+		var headers json.RawMessage
+		if len(ev.Headers) > 0 {
+			if headers, err = json.Marshal(ev.Headers); err != nil {
+				return fmt.Errorf("outbox: encode %q headers: %w", topic, err)
+			}
+		}
+		rows = append(rows, Message{
+			Topic:        topic,
+			Key:          ev.Key,
+			Payload:      payload,
+			Headers:      headers,
+			TenantSchema: schema,
+			TenantID:     tenantID,
+			Status:       StatusPending,
+			AvailableAt:  now,
+			CreatedAt:    now,
+		})
+	}
+
+	return tx.Table(table(p.DB)).Create(&rows).Error
+}
+
+// table returns the schema-qualified outbox table, independent of the session search_path.
+func table(db engine.DB) string {
+	return db.Main() + "." + TableBase
+}
+
+func encodePayload(v any) (json.RawMessage, error) {
+	switch p := v.(type) {
+	case nil:
+		return json.RawMessage("null"), nil
+	case json.RawMessage:
+		return p, nil
+	case []byte:
+		if !json.Valid(p) {
+			return nil, errors.New("payload is not valid JSON")
+		}
+		return p, nil
+	default:
+		return json.Marshal(v)
+	}
+}
@@ -0,0 +1,343 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"skyrix/internal/engine"
+	engineJobs "skyrix/internal/engine/jobs"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+	"skyrix/internal/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	baseBackoff = time.Second
+	maxBackoff  = 5 * time.Minute
+	// claimLease is how long a claimed message stays invisible to other relays.
+	claimLease = 5 * time.Minute
+	// databasesTTL is how long the list of dedicated tenant databases is reused.
+	databasesTTL = time.Minute
+)
+
+// Opts configures the Relay.
+type Opts struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	Retention    time.Duration // published rows
+	// DeadRetention is how long dead rows are kept for inspection, counted from their creation.
+	DeadRetention time.Duration
+}
+
+// TenantDatabases lists the references of the dedicated tenant databases, each of which has
+// its own outbox table (implemented by the tenant repository).
+type TenantDatabases interface {
+	ListDatabases(ctx context.Context) ([]string, error)
+}
+
+// Relay moves pending outbox messages to a Sink.
+type Relay struct {
+	DB      engine.DB
+	Tx      engine.TransactionManager
+	Sink    Sink
+	Logger  logger.Interface
+	Tenants TenantDatabases // nil: only the main database
+	opts    Opts
+
+	mu       sync.Mutex
+	refs     []string
+	refsNext time.Time
+
+	batches  *engineJobs.Tracker // running ProcessBatch/Cleanup calls
+	stopping chan struct{}
+	stopOnce sync.Once
+}
+
+// ErrStopped is returned by ProcessBatch and Cleanup once Stop was called.
+var ErrStopped = errors.New("outbox relay stopped")
+
+func NewRelay(db *engine.Database, tx engine.TransactionManager, sink Sink, tenants TenantDatabases, log logger.Interface, opts Opts) *Relay {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 10
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = 7 * 24 * time.Hour
+	}
+	if opts.DeadRetention <= 0 {
+		opts.DeadRetention = 30 * 24 * time.Hour
+	}
+	return &Relay{
+		DB:       db,
+		Tx:       tx,
+		Sink:     sink,
+		Tenants:  tenants,
+		Logger:   log,
+		opts:     opts,
+		batches:  engineJobs.NewTracker(),
+		stopping: make(chan struct{}),
+	}
+}
+
+// Stop ends Run loops after their current batch, refuses new batches and waits for running
+// ones until ctx is done. Messages of a batch cut off by the deadline keep their claim and are
+// retried after claimLease.
+func (r *Relay) Stop(ctx context.Context) error {
+	r.stopOnce.Do(func() { close(r.stopping) })
+	return r.batches.Drain(ctx)
+}
+
+// Run polls until ctx is cancelled or Stop is called. Full batches are followed immediately by
+// the next poll; otherwise the relay sleeps for PollInterval. Published and dead rows are
+// purged once per hour.
+func (r *Relay) Run(ctx context.Context) error {
+	r.Logger.Info("outbox relay started", "interval", r.opts.PollInterval, "batch", r.opts.BatchSize)
+	defer r.Logger.Info("outbox relay stopped")
+
+	nextCleanup := time.Now()
+	for {
+		select {
+		case <-r.stopping:
+			return nil
+		default:
+		}
+		if time.Now().After(nextCleanup) {
+			if _, err := r.Cleanup(ctx); err != nil && ctx.Err() == nil {
+				r.Logger.Warn("outbox cleanup failed", "error", err)
+			}
+			nextCleanup = time.Now().Add(time.Hour)
+		}
+
+		n, err := r.ProcessBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.Logger.Error("outbox batch failed", "error", err)
+		}
+		if n >= r.opts.BatchSize && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.stopping:
+			return nil
+		case <-time.After(r.opts.PollInterval):
+		}
+	}
+}
+
+// databases returns the contexts of every database holding an outbox: the main one and the
+// dedicated tenant databases listed in the tenants table, whether or not a request opened
+// their pool. The list is refreshed every databasesTTL; on error the previous one is kept.
+func (r *Relay) databases(ctx context.Context) []context.Context {
+	ctx = tenantContext.WithSchema(ctx, "") // outbox lives in the main schema
+	out := []context.Context{tenantContext.WithDatabase(ctx, "")}
+	if r.Tenants == nil {
+		return out
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Now().After(r.refsNext) {
+		refs, err := r.Tenants.ListDatabases(tenantContext.WithDatabase(ctx, ""))
+		if err != nil {
+			r.Logger.Warn("outbox: listing tenant databases failed", "error", err)
+		} else {
+			r.refs = refs
+			r.refsNext = time.Now().Add(databasesTTL)
+		}
+	}
+	for _, ref := range r.refs {
+		out = append(out, tenantContext.WithDatabase(ctx, ref))
+	}
+	return out
+}
+
+// ProcessBatch claims up to BatchSize due messages per database, publishes them and records
+// the outcome. Rows are claimed with FOR UPDATE SKIP LOCKED, so concurrent relays never pick
+// the same row. Returns the largest number of messages handled (published or failed) in one
+// database, so Run keeps polling without a pause while any database has a full batch.
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	done, err := r.batches.Start()
+	if err != nil {
+		return 0, ErrStopped
+	}
+	defer done()
+
+	var most int
+	var firstErr error
+	for _, dbCtx := range r.databases(ctx) {
+		dbCtx, release := engine.WithConnScope(dbCtx) // leases a tenant database's pool
+		n, err := r.processBatch(dbCtx)
+		release()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		most = max(most, n)
+	}
+	return most, firstErr
+}
+
+// processBatch claims a batch in a short transaction and publishes after it committed: no row
+// lock is held while the sink runs, and each outcome is recorded on its own, so one failed
+// status update cannot roll back messages that were already delivered.
+func (r *Relay) processBatch(ctx context.Context) (int, error) {
+	msgs, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var firstErr error
+	for i := range msgs {
+		if err := r.deliver(ctx, &msgs[i]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return len(msgs), firstErr
+}
+
+// claim locks due messages, counts the delivery attempt and pushes available_at past
+// claimLease. A relay that dies before recording the outcome leaves the rows pending, and
+// they are picked up again once the lease ran out.
+func (r *Relay) claim(ctx context.Context) ([]Message, error) {
+	var msgs []Message
+	err := r.Tx.Execute(ctx, func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		err := tx.Table(table(r.DB)).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND available_at <= ?", StatusPending, now).
+			Order("id").
+			Limit(r.opts.BatchSize).
+			Find(&msgs).Error
+		if err != nil || len(msgs) == 0 {
+			return err
+		}
+
+		ids := make([]int64, len(msgs))
+		for i := range msgs {
+			ids[i] = msgs[i].ID
+			msgs[i].Attempts++
+		}
+		return tx.Table(table(r.DB)).Where("id IN ?", ids).Updates(map[string]any{
+			"attempts":     gorm.Expr("attempts + 1"),
+			"available_at": now.Add(claimLease),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// deliver publishes one claimed message and records the outcome on its row. Only DB errors
+// are returned; sink errors are recorded on the row and retried later.
+func (r *Relay) deliver(ctx context.Context, msg *Message) error {
+	sinkCtx := tenantContext.WithSchema(ctx, msg.TenantSchema)
+	if msg.TenantID > 0 {
+		sinkCtx = tenantContext.WithTenantID(sinkCtx, msg.TenantID)
+	}
+	sinkCtx, release := engine.WithConnScope(sinkCtx)
+	pubErr := r.publish(sinkCtx, msg)
+	release()
+
+	updates := r.outcome(msg, pubErr, time.Now().UTC())
+	switch {
+	case pubErr == nil:
+	case updates["status"] == StatusDead:
+		r.Logger.Error("outbox message dead", "id", msg.ID, "topic", msg.Topic, "attempts", msg.Attempts, "error", pubErr)
+	default:
+		r.Logger.Warn("outbox publish failed", "id", msg.ID, "topic", msg.Topic, "attempt", msg.Attempts, "error", pubErr)
+	}
+
+	err := r.DB.WithContext(ctx).Table(table(r.DB)).
+		Where("id = ? AND status = ?", msg.ID, StatusPending).
+		Updates(updates).Error
+	if err != nil {
+		r.Logger.Error("outbox status update failed", "id", msg.ID, "published", pubErr == nil, "error", err)
+	}
+	return err
+}
+
+// outcome returns the column updates recording a publish attempt of a claimed message:
+// published, retried after backoff, or dead once MaxAttempts is reached.
+func (r *Relay) outcome(msg *Message, pubErr error, now time.Time) map[string]any {
+	if pubErr == nil {
+		return map[string]any{
+			"status":       StatusPublished,
+			"published_at": now,
+			"last_error":   nil,
+		}
+	}
+	updates := map[string]any{"last_error": pubErr.Error()}
+	if msg.Attempts >= r.opts.MaxAttempts {
+		updates["status"] = StatusDead
+	} else {
+		updates["available_at"] = now.Add(backoff(msg.Attempts))
+	}
+	return updates
+}
+
+// publish calls the sink with panic protection so one bad handler cannot stall the relay.
+func (r *Relay) publish(ctx context.Context, msg *Message) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("sink panicked: %v", rec)
+		}
+	}()
+	return r.Sink.Publish(ctx, *msg)
+}
+
+// Cleanup deletes published messages older than Retention and dead ones created more than
+// DeadRetention ago, and returns how many were removed.
+func (r *Relay) Cleanup(ctx context.Context) (int64, error) {
+	done, err := r.batches.Start()
+	if err != nil {
+		return 0, ErrStopped
+	}
+	defer done()
+
+	now := time.Now().UTC()
+	cutoff, deadCutoff := now.Add(-r.opts.Retention), now.Add(-r.opts.DeadRetention)
+	var deleted int64
+	var firstErr error
+	for _, dbCtx := range r.databases(ctx) {
+		dbCtx, release := engine.WithConnScope(dbCtx)
+		res := r.DB.WithContext(dbCtx).
+			Table(table(r.DB)).
+			Where("((status = ? AND published_at < ?) OR (status = ? AND created_at < ?))",
+				StatusPublished, cutoff, StatusDead, deadCutoff).
+			Delete(&Message{})
+		release()
+		if res.Error != nil && firstErr == nil {
+			firstErr = res.Error
+		}
+		deleted += res.RowsAffected
+	}
+	if deleted > 0 {
+		r.Logger.Info("outbox cleanup", "deleted", deleted)
+	}
+	return deleted, firstErr
+}
+
+// backoff grows exponentially from baseBackoff, capped at maxBackoff.
+func backoff(attempt int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
@@ -0,0 +1,105 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Sink delivers relayed messages. Returning an error schedules a retry.
+// ctx carries the tenant schema the message was written under.
+type Sink interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+// ErrNoHandler is returned by MemorySink for a topic nobody subscribed to. The relay retries
+// the message and finally marks it dead instead of dropping it as delivered.
+var ErrNoHandler = errors.New("outbox: no handler subscribed for topic")
+
+// Handler consumes messages from a MemorySink.
+type Handler func(ctx context.Context, msg Message) error
+
+// MemorySink delivers messages in-process to handlers subscribed by topic.
+// Handlers must be subscribed in every process that runs a relay (HTTP app and outbox:relay).
+type MemorySink struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers h for topic.
+func (s *MemorySink) Subscribe(topic string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[topic] = append(s.handlers[topic], h)
+}
+
+func (s *MemorySink) Publish(ctx context.Context, msg Message) error {
+	s.mu.RLock()
+	hs := s.handlers[msg.Topic]
+	s.mu.RUnlock()
+
+	if len(hs) == 0 {
+		return fmt.Errorf("%w %q", ErrNoHandler, msg.Topic)
+	}
+	for _, h := range hs {
+		if err := h(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JobRunner runs a registered job synchronously (the kernel job registry).
+type JobRunner interface {
+	Run(ctx context.Context, name string, args map[string]any) error
+}
+
+// JobSink runs the job named after the topic (or Route's result) with the message as args.
+// The job runs inside Publish, so the message is only marked delivered once the job succeeded;
+// a failing job or a topic without a registered job is retried and finally marked dead.
+// Delivery is at least once (a relay may die before recording the outcome), so jobs consuming
+// outbox messages must dedupe on outbox_id.
+//
+// Args passed to the job:
+//   - "outbox_id": int64
+//   - "topic", "key": string
+//   - "payload": decoded JSON payload
+//   - "headers": map[string]string
+type JobSink struct {
+	Jobs  JobRunner
+	Route func(topic string) string
+}
+
+func NewJobSink(jobs JobRunner) *JobSink {
+	return &JobSink{Jobs: jobs}
+}
+
+func (s *JobSink) Publish(ctx context.Context, msg Message) error {
+	name := msg.Topic
+	if s.Route != nil {
+		name = s.Route(msg.Topic)
+	}
+
+	var payload any
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return fmt.Errorf("decode payload: %w", err)
+	}
+	return s.Jobs.Run(ctx, name, map[string]any{
+		"outbox_id": msg.ID,
+		"topic":     msg.Topic,
+		"key":       msg.Key,
+		"payload":   payload,
+		"headers":   msg.HeaderMap(),
+	})
+}
+
+var (
+	_ Sink = (*MemorySink)(nil)
+	_ Sink = (*JobSink)(nil)
+)
@@ -0,0 +1,44 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type runnerFunc func(ctx context.Context, name string, args map[string]any) error
+
+func (f runnerFunc) Run(ctx context.Context, name string, args map[string]any) error {
+	return f(ctx, name, args)
+}
+
+func TestJobSinkRunsJobInPublish(t *testing.T) {
+	boom := errors.New("boom")
+	var (
+		ran  string
+		args map[string]any
+	)
+	sink := NewJobSink(runnerFunc(func(_ context.Context, name string, a map[string]any) error {
+		ran, args = name, a
+		return boom
+	}))
+	sink.Route = func(topic string) string { return "handle:" + topic }
+
+	msg := Message{ID: 42, Topic: "order.created", Key: "o-1", Payload: []byte(`{"total":10}`)}
+	// The job's error is the delivery error, so the relay retries the message.
+	if err := sink.Publish(context.Background(), msg); !errors.Is(err, boom) {
+		t.Fatalf("Publish = %v, want the job's error", err)
+	}
+	if ran != "handle:order.created" {
+		t.Errorf("ran %q", ran)
+	}
+	if args["outbox_id"] != int64(42) || args["key"] != "o-1" || args["payload"].(map[string]any)["total"] != float64(10) {
+		t.Errorf("args = %v", args)
+	}
+
+	msg.Payload = []byte("not json")
+	ran = ""
+	if err := sink.Publish(context.Background(), msg); err == nil || ran != "" {
+		t.Errorf("bad payload: err %v, ran %q", err, ran)
+	}
+}
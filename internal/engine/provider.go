@@ -1,8 +1,13 @@
 package engine
 
 import (
+	"context"
+	"fmt"
 	"skyrix/internal/config"
+	"skyrix/internal/engine/metrics"
 	"skyrix/internal/logger"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/wire"
@@ -15,19 +20,81 @@ import (
 var ProviderSet = wire.NewSet(
 	ProvideDatabaseService,
 	ProvideRedisService,
+	NewTxManager,
 
-	// Bind Cache interface to chosen implementation.
-	wire.Bind(new(Cache), new(*Redis)),
+	// Cache implementation is chosen by config (CACHE_BACKEND).
+	ProvideCache,
+	// Distributed locks follow the cache backend.
+	ProvideLocker,
 )
 
-func ProvideDatabaseService(db *gorm.DB, cfg *config.Config) *Database {
-	return NewDatabaseService(db, cfg.Database.MainSchema)
+func ProvideDatabaseService(db *gorm.DB, cfg *config.Config, pools TenantPools) *Database {
+	d := NewDatabaseService(db, cfg.Database.MainSchema)
+	d.Pools = pools
+	return d
 }
 
-func ProvideRedisService(redisClient *redis.Client, log logger.Interface, cfg *config.Config) *Redis {
+func ProvideRedisService(redisClient redis.UniversalClient, log logger.Interface, cfg *config.Config, m *metrics.Metrics) *Redis {
 	redisOpts := RedisOpts{
 		KeyPrefix: cfg.TenantCache.KeyPrefix,
 		StatusTTL: 5 * time.Minute,
+		Metrics:   m,
 	}
 	return NewRedisService(redisClient, log, redisOpts)
 }
+
+// Cache backends (config CACHE_BACKEND).
+const (
+	CacheRedis  = "redis"
+	CacheMemory = "memory" // single instance, no Redis needed (local development, tests)
+	CacheTiered = "tiered" // memory in front of Redis, invalidated across instances
+)
+
+// ProvideCache builds the configured backend. Reads are counted in cache_requests_total:
+// the tiered backend counts its memory layer as "memory" and its misses there as "redis" reads.
+func ProvideCache(cfg *config.Config, r *Redis, log logger.Interface, lc Lifecycle, m *metrics.Metrics) (Cache, func(), error) {
+	memOpts := MemoryOpts{
+		MaxEntries: cfg.Cache.MemoryMaxEntries,
+		MaxBytes:   cfg.Cache.MemoryMaxBytes,
+		DefaultTTL: 5 * time.Minute, // same as Redis StatusTTL
+		Metrics:    m,
+	}
+	switch backend := strings.ToLower(strings.TrimSpace(cfg.Cache.Backend)); backend {
+	case "", CacheRedis:
+		return r, func() {}, nil
+	case CacheMemory:
+		m := NewMemoryCache(memOpts)
+		return m, CloseOnStop(lc, log, "memory cache", m.Close), nil
+	case CacheTiered:
+		t := NewTieredCache(NewMemoryCache(memOpts), r, log, TieredOpts{L1TTL: cfg.Cache.L1TTL})
+		return t, CloseOnStop(lc, log, "tiered cache", t.Close), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown cache backend %q (want %s, %s or %s)", backend, CacheRedis, CacheMemory, CacheTiered)
+	}
+}
+
+// ProvideLocker returns the in-process MemoryLocker for the memory cache backend (one instance,
+// no Redis) and the RedisLocker otherwise.
+func ProvideLocker(cfg *config.Config, r *Redis, log logger.Interface) Locker {
+	if strings.EqualFold(strings.TrimSpace(cfg.Cache.Backend), CacheMemory) {
+		return NewMemoryLocker(log)
+	}
+	return NewRedisLocker(r, log)
+}
+
+// CloseOnStop registers close as the OnStop hook name and returns it as the Wire cleanup as
+// well, which still runs if the build fails before the app could stop. close runs once; the
+// caller that ran it gets its error.
+func CloseOnStop(lc Lifecycle, log logger.Interface, name string, close func() error) func() {
+	var once sync.Once
+	run := func() (err error) {
+		once.Do(func() { err = close() })
+		return err
+	}
+	lc.OnStop(name, func(context.Context) error { return run() })
+	return func() {
+		if err := run(); err != nil {
+			log.Warn("close failed", "component", name, "error", err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package readmodel
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Params are named query parameters, referenced as :name in SQL.
+type Params map[string]any
+
+// bind rewrites :name parameters to numbered $n placeholders and returns the ordered args.
+// String literals (E'...' escape strings and dollar-quoted bodies included), quoted
+// identifiers, comments and ::type casts are left alone. Slice values expand to
+// ($1, $2, $3), so "IN :ids" works.
+//
+// Placeholders are numbered rather than ?, so operators such as jsonb ?, ?| and ?& reach
+// Postgres as written; run the result with raw, which keeps gorm from rewriting it.
+func bind(sql string, params Params) (string, []any, error) {
+	var (
+		out  strings.Builder
+		args []any
+	)
+	out.Grow(len(sql))
+	placeholder := func(v any) {
+		args = append(args, v)
+		out.WriteByte('$')
+		out.WriteString(strconv.Itoa(len(args)))
+	}
+
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case ch == '\'' || ch == '"':
+			end := skipQuoted(sql, i, ch, ch == '\'' && isEscapeString(sql, i))
+			out.WriteString(sql[i:end])
+			i = end - 1
+		case ch == '$' && (i == 0 || !isNameChar(sql[i-1])) && dollarTag(sql, i) != "":
+			tag := dollarTag(sql, i)
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end += i + 2*len(tag)
+			}
+			out.WriteString(sql[i:end])
+			i = end - 1
+		case ch == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			out.WriteString(sql[i : i+end])
+			i += end - 1
+		case ch == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql) - i - 2
+			} else {
+				end += 2
+			}
+			out.WriteString(sql[i : i+2+end])
+			i += 1 + end
+		case ch == ':' && i+1 < len(sql) && sql[i+1] == ':':
+			out.WriteString("::")
+			i++
+		case ch == ':' && i+1 < len(sql) && isNameStart(sql[i+1]):
+			j := i + 1
+			for j < len(sql) && isNameChar(sql[j]) {
+				j++
+			}
+			name := sql[i+1 : j]
+			v, ok := params[name]
+			if !ok {
+				return "", nil, fmt.Errorf("readmodel: missing parameter :%s", name)
+			}
+			if elems, ok := expand(v); ok {
+				out.WriteByte('(')
+				if len(elems) == 0 {
+					out.WriteString("NULL")
+				}
+				for k, e := range elems {
+					if k > 0 {
+						out.WriteString(", ")
+					}
+					placeholder(e)
+				}
+				out.WriteByte(')')
+			} else {
+				placeholder(v)
+			}
+			i = j - 1
+		default:
+			out.WriteByte(ch)
+		}
+	}
+	return out.String(), args, nil
+}
+
+// expand returns the elements of slice and array values; []byte and driver.Valuer values
+// (e.g. Postgres array types) are single parameters.
+func expand(v any) ([]any, bool) {
+	if _, ok := v.(driver.Valuer); ok {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	out := make([]any, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out, true
+}
+
+// skipQuoted returns the index after the quoted section starting at i (a doubled quote escapes,
+// as does a backslash in an E'...' string).
+func skipQuoted(s string, i int, q byte, backslash bool) int {
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case backslash && s[j] == '\\':
+			j++
+		case s[j] == q:
+			if j+1 < len(s) && s[j+1] == q {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// isEscapeString reports whether the quote at i opens an E'...' string constant.
+func isEscapeString(s string, i int) bool {
+	return i > 0 && (s[i-1] == 'E' || s[i-1] == 'e') && (i == 1 || !isNameChar(s[i-2]))
+}
+
+// dollarTag returns the opening $tag$ (or $$) of a dollar-quoted string at i, or "".
+func dollarTag(s string, i int) string {
+	j := i + 1
+	if j < len(s) && isNameStart(s[j]) {
+		for j < len(s) && isNameChar(s[j]) {
+			j++
+		}
+	}
+	if j < len(s) && s[j] == '$' {
+		return s[i : j+1]
+	}
+	return ""
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,131 @@
+package readmodel
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tagList is a driver.Valuer slice, like the Postgres array types.
+type tagList []string
+
+func (l tagList) Value() (driver.Value, error) { return "{" + strings.Join(l, ",") + "}", nil }
+
+func TestBind(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		name     string
+		sql      string
+		params   Params
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "named parameters",
+			sql:      "SELECT * FROM orders WHERE status = :status AND created_at >= :since AND status <> :status",
+			params:   Params{"status": "open", "since": since},
+			wantSQL:  "SELECT * FROM orders WHERE status = $1 AND created_at >= $2 AND status <> $3",
+			wantArgs: []any{"open", since, "open"},
+		},
+		{
+			name:     "casts",
+			sql:      "SELECT :id::bigint, created_at::date FROM orders",
+			params:   Params{"id": 7},
+			wantSQL:  "SELECT $1::bigint, created_at::date FROM orders",
+			wantArgs: []any{7},
+		},
+		{
+			name:     "slice expands",
+			sql:      "SELECT * FROM orders WHERE id IN :ids AND status = :status",
+			params:   Params{"ids": []int64{1, 2, 3}, "status": "open"},
+			wantSQL:  "SELECT * FROM orders WHERE id IN ($1, $2, $3) AND status = $4",
+			wantArgs: []any{int64(1), int64(2), int64(3), "open"},
+		},
+		{
+			name:    "empty slice",
+			sql:     "SELECT * FROM orders WHERE id IN :ids",
+			params:  Params{"ids": []string{}},
+			wantSQL: "SELECT * FROM orders WHERE id IN (NULL)",
+		},
+		{
+			name:     "bytes and valuers are single values",
+			sql:      "SELECT * FROM files WHERE hash = :hash AND tags && :tags",
+			params:   Params{"hash": []byte("abc"), "tags": tagList{"a", "b"}},
+			wantSQL:  "SELECT * FROM files WHERE hash = $1 AND tags && $2",
+			wantArgs: []any{[]byte("abc"), tagList{"a", "b"}},
+		},
+		{
+			name:     "string literals",
+			sql:      "SELECT ':skip', 'it''s :skip', E'it\\'s :skip', :id",
+			params:   Params{"id": 1},
+			wantSQL:  "SELECT ':skip', 'it''s :skip', E'it\\'s :skip', $1",
+			wantArgs: []any{1},
+		},
+		{
+			name:     "quoted identifiers",
+			sql:      `SELECT "a:skip", "say ""x:skip""" FROM t WHERE id = :id`,
+			params:   Params{"id": 1},
+			wantSQL:  `SELECT "a:skip", "say ""x:skip""" FROM t WHERE id = $1`,
+			wantArgs: []any{1},
+		},
+		{
+			name:     "comments",
+			sql:      "SELECT 1 -- :skip\n/* :skip */ WHERE id = :id",
+			params:   Params{"id": 1},
+			wantSQL:  "SELECT 1 -- :skip\n/* :skip */ WHERE id = $1",
+			wantArgs: []any{1},
+		},
+		{
+			name:     "dollar quoting",
+			sql:      "SELECT $$it's :skip$$, $fn$ :skip $$ ' $fn$, :id",
+			params:   Params{"id": 1},
+			wantSQL:  "SELECT $$it's :skip$$, $fn$ :skip $$ ' $fn$, $1",
+			wantArgs: []any{1},
+		},
+		{
+			name:     "dollar in identifiers",
+			sql:      "SELECT a$b$ FROM t WHERE id = :id",
+			params:   Params{"id": 1},
+			wantSQL:  "SELECT a$b$ FROM t WHERE id = $1",
+			wantArgs: []any{1},
+		},
+		{
+			name:     "jsonb question mark operators",
+			sql:      "SELECT * FROM docs WHERE data ? 'a' AND data ?| array['b'] AND data ?& :keys AND data @> :doc",
+			params:   Params{"keys": tagList{"c"}, "doc": `{"d":1}`},
+			wantSQL:  "SELECT * FROM docs WHERE data ? 'a' AND data ?| array['b'] AND data ?& $1 AND data @> $2",
+			wantArgs: []any{tagList{"c"}, `{"d":1}`},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			sql, args, err := bind(c.sql, c.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != c.wantSQL {
+				t.Errorf("sql:\n got %s\nwant %s", sql, c.wantSQL)
+			}
+			if !reflect.DeepEqual(args, c.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, c.wantArgs)
+			}
+		})
+	}
+}
+
+func TestBindMissingParameter(t *testing.T) {
+	_, _, err := bind("SELECT * FROM orders WHERE id = :id", Params{"ID": 1})
+	if err == nil || !strings.Contains(err.Error(), ":id") {
+		t.Errorf("got %v, want a missing :id error", err)
+	}
+}
+
+func TestBindUnterminated(t *testing.T) {
+	for _, sql := range []string{"SELECT ':id", `SELECT ":id`, "SELECT $$ :id", "SELECT /* :id"} {
+		got, args, err := bind(sql, nil)
+		if err != nil || got != sql || len(args) != 0 {
+			t.Errorf("bind(%q) = %q, %v, %v; want it unchanged", sql, got, args, err)
+		}
+	}
+}
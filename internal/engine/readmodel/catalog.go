@@ -0,0 +1,128 @@
+// Package readmodel is the raw-SQL read path: named queries loaded from embedded .sql files,
+// named parameters, and generic scanning into DTOs.
+//
+//	//go:embed sql/*.sql
+//	var queries embed.FS
+//
+//	catalog := readmodel.MustLoadCatalog(queries, "sql/*.sql")
+//	reader := readmodel.NewReader(db, catalog)
+//	rows, err := readmodel.List[OrderRow](ctx, reader, "orders.recent", readmodel.Params{"since": t})
+//
+// Queries run through engine.DB.WithContext(ctx), so they join the transaction in ctx, use the
+// tenant's pinned connection (search_path = tenant, main, public) and may be served by replicas.
+// {{tenant}} and {{main}} placeholders are replaced with the quoted schema names.
+package readmodel
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var reQueryName = regexp.MustCompile(`^--\s*name:\s*(\S+)\s*$`)
+
+// Catalog holds named SQL statements.
+//
+// A file may hold several queries, each introduced by a "-- name: <query>" line; a file without
+// such markers is a single query named after the file (without the .sql extension).
+type Catalog struct {
+	queries map[string]string
+}
+
+// LoadCatalog reads every file matching patterns (fs.Glob syntax) from fsys.
+func LoadCatalog(fsys fs.FS, patterns ...string) (*Catalog, error) {
+	c := &Catalog{queries: make(map[string]string)}
+	if len(patterns) == 0 {
+		patterns = []string{"*.sql"}
+	}
+	for _, pattern := range patterns {
+		files, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("readmodel: %s: %w", pattern, err)
+		}
+		for _, file := range files {
+			raw, err := fs.ReadFile(fsys, file)
+			if err != nil {
+				return nil, fmt.Errorf("readmodel: %s: %w", file, err)
+			}
+			if err := c.add(file, string(raw)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// MustLoadCatalog is LoadCatalog for package-level initialization; it panics on error.
+func MustLoadCatalog(fsys fs.FS, patterns ...string) *Catalog {
+	c, err := LoadCatalog(fsys, patterns...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (c *Catalog) add(file, content string) error {
+	var (
+		name string
+		buf  strings.Builder
+	)
+	flush := func() error {
+		sql := strings.TrimSpace(buf.String())
+		buf.Reset()
+		if name == "" {
+			if sql == "" {
+				return nil
+			}
+			name = strings.TrimSuffix(path.Base(file), path.Ext(file))
+		}
+		if sql == "" {
+			return fmt.Errorf("readmodel: %s: query %q is empty", file, name)
+		}
+		if _, dup := c.queries[name]; dup {
+			return fmt.Errorf("readmodel: %s: duplicate query %q", file, name)
+		}
+		c.queries[name] = sql
+		return nil
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(content))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if m := reQueryName.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			if err := flush(); err != nil {
+				return err
+			}
+			name = m[1]
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("readmodel: %s: %w", file, err)
+	}
+	return flush()
+}
+
+// SQL returns the statement registered under name.
+func (c *Catalog) SQL(name string) (string, error) {
+	sql, ok := c.queries[name]
+	if !ok {
+		return "", fmt.Errorf("readmodel: unknown query %q", name)
+	}
+	return sql, nil
+}
+
+// Names returns the registered query names.
+func (c *Catalog) Names() []string {
+	out := make([]string, 0, len(c.queries))
+	for name := range c.queries {
+		out = append(out, name)
+	}
+	return out
+}
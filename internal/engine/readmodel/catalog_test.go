@@ -0,0 +1,81 @@
+package readmodel
+
+import (
+	"embed"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+//go:embed testdata/*.sql
+var testQueries embed.FS
+
+func TestLoadCatalogFromEmbeddedFiles(t *testing.T) {
+	c := MustLoadCatalog(testQueries, "testdata/*.sql")
+
+	names := c.Names()
+	slices.Sort(names)
+	if want := []string{"orders.by_ids", "orders.recent", "orders_count"}; !slices.Equal(names, want) {
+		t.Fatalf("names = %v, want %v", names, want)
+	}
+
+	for name, want := range map[string]string{
+		"orders.recent": "SELECT id, status, created_at\nFROM {{tenant}}.orders\nWHERE created_at >= :since;",
+		"orders.by_ids": "SELECT id, status FROM {{tenant}}.orders WHERE id IN :ids",
+		"orders_count":  "SELECT count(*) AS n FROM {{tenant}}.orders WHERE status = :status",
+	} {
+		got, err := c.SQL(name)
+		if err != nil || got != want {
+			t.Errorf("SQL(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	if _, err := c.SQL("orders.missing"); err == nil {
+		t.Error("unknown query resolved")
+	}
+}
+
+func TestLoadCatalogErrors(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{
+			name: "duplicate in one file",
+			files: fstest.MapFS{"q.sql": {Data: []byte(
+				"-- name: a\nSELECT 1\n-- name: a\nSELECT 2\n")}},
+			want: `duplicate query "a"`,
+		},
+		{
+			name: "duplicate across files",
+			files: fstest.MapFS{
+				"a.sql": {Data: []byte("SELECT 1\n")},
+				"b.sql": {Data: []byte("-- name: a\nSELECT 2\n")},
+			},
+			want: `duplicate query "a"`,
+		},
+		{
+			name:  "empty named query",
+			files: fstest.MapFS{"q.sql": {Data: []byte("-- name: a\n\n-- name: b\nSELECT 1\n")}},
+			want:  `query "a" is empty`,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := LoadCatalog(c.files)
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("got %v, want %s", err, c.want)
+			}
+		})
+	}
+}
+
+func TestMustLoadCatalogPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustLoadCatalog did not panic on a bad pattern")
+		}
+	}()
+	MustLoadCatalog(fstest.MapFS{}, "[")
+}
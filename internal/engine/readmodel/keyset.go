@@ -0,0 +1,224 @@
+package readmodel
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 1000
+)
+
+var reColumn = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// ErrInvalidCursor is returned for cursors that were not produced by Keyset with the same keys.
+var ErrInvalidCursor = errors.New("readmodel: invalid cursor")
+
+// Key is one sort column of a keyset page. Column must be a column of the query result.
+type Key struct {
+	Column string
+	Desc   bool
+}
+
+// PageRequest asks for Limit rows after the opaque cursor After ("" = first page).
+type PageRequest struct {
+	Limit int
+	After string
+}
+
+// Page is one keyset page. Next is the cursor for the following page ("" on the last one).
+type Page[T any] struct {
+	Items   []T
+	Next    string
+	HasMore bool
+}
+
+// Keyset pages through query name ordered by keys. The query is wrapped as a subquery:
+//
+//	SELECT * FROM (<query>) AS keyset WHERE <after cursor> ORDER BY <keys> LIMIT n+1
+//
+// so it must not rely on its own ORDER BY/LIMIT. The last key should be unique (e.g. id)
+// for a stable order. T must expose every key column (matched like gorm column names).
+func Keyset[T any](ctx context.Context, r *Reader, name string, params Params, keys []Key, req PageRequest) (Page[T], error) {
+	var page Page[T]
+	if len(keys) == 0 {
+		return page, fmt.Errorf("readmodel: %s: keyset needs at least one key", name)
+	}
+	for _, k := range keys {
+		if !reColumn.MatchString(k.Column) {
+			return page, fmt.Errorf("readmodel: %s: invalid key column %q", name, k.Column)
+		}
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	db, inner, args, err := r.prepare(ctx, name, params)
+	if err != nil {
+		return page, err
+	}
+
+	var sql strings.Builder
+	sql.WriteString("SELECT * FROM (\n")
+	sql.WriteString(strings.TrimRight(strings.TrimSpace(inner), ";"))
+	sql.WriteString("\n) AS keyset")
+
+	if req.After != "" {
+		after, err := DecodeCursor(req.After, len(keys))
+		if err != nil {
+			return page, err
+		}
+		n := len(args)
+		cond, condArgs := afterCondition(keys, after, func() string {
+			n++
+			return "$" + strconv.Itoa(n)
+		})
+		sql.WriteString(" WHERE ")
+		sql.WriteString(cond)
+		args = append(args, condArgs...)
+	}
+
+	sql.WriteString(" ORDER BY ")
+	for i, k := range keys {
+		if i > 0 {
+			sql.WriteString(", ")
+		}
+		sql.WriteString(k.Column)
+		if k.Desc {
+			sql.WriteString(" DESC")
+		}
+	}
+	sql.WriteString(fmt.Sprintf(" LIMIT %d", limit+1))
+
+	err = stream(db, name, sql.String(), args, func(item T) error {
+		page.Items = append(page.Items, item)
+		return nil
+	})
+	if err != nil {
+		return page, err
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		page.HasMore = true
+		page.Next, err = encodeCursor(ctx, page.Items[limit-1], keys, db.NamingStrategy)
+		if err != nil {
+			return page, fmt.Errorf("readmodel: %s: %w", name, err)
+		}
+	}
+	return page, nil
+}
+
+// AfterCondition expands (k1, k2, ...) > (v1, v2, ...) per key direction:
+// k1 > v1 OR (k1 = v1 AND k2 > v2) OR ...
+// Key columns are written as is; callers validate them. Values use ? placeholders for gorm's
+// Where.
+func AfterCondition(keys []Key, values []any) (string, []any) {
+	return afterCondition(keys, values, func() string { return "?" })
+}
+
+// afterCondition is AfterCondition with placeholders from next, called once per arg in order.
+func afterCondition(keys []Key, values []any, next func() string) (string, []any) {
+	var (
+		ors  []string
+		args []any
+	)
+	for i := range keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, keys[j].Column+" = "+next())
+			args = append(args, values[j])
+		}
+		op := ">"
+		if keys[i].Desc {
+			op = "<"
+		}
+		ands = append(ands, keys[i].Column+" "+op+" "+next())
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+var schemaCache sync.Map
+
+func encodeCursor[T any](ctx context.Context, item T, keys []Key, namer schema.Namer) (string, error) {
+	rv := reflect.ValueOf(&item).Elem()
+	for rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+
+	var values []any
+	if rv.Kind() == reflect.Map {
+		for _, k := range keys {
+			v := rv.MapIndex(reflect.ValueOf(k.Column))
+			if !v.IsValid() {
+				return "", fmt.Errorf("key column %q not in row", k.Column)
+			}
+			values = append(values, v.Interface())
+		}
+	} else {
+		s, err := schema.Parse(&item, &schemaCache, namer)
+		if err != nil {
+			return "", err
+		}
+		for _, k := range keys {
+			f := s.LookUpField(k.Column)
+			if f == nil {
+				return "", fmt.Errorf("key column %q not in %s", k.Column, s.Name)
+			}
+			v, _ := f.ValueOf(ctx, rv)
+			values = append(values, v)
+		}
+	}
+
+	return EncodeCursor(values)
+}
+
+// EncodeCursor packs key values into an opaque URL-safe cursor.
+func EncodeCursor(values []any) (string, error) {
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// DecodeCursor unpacks a cursor made by EncodeCursor, expecting n key values.
+func DecodeCursor(cursor string, n int) ([]any, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.UseNumber()
+	var values []any
+	if err := dec.Decode(&values); err != nil || len(values) != n {
+		return nil, ErrInvalidCursor
+	}
+	for i, v := range values {
+		if num, ok := v.(json.Number); ok {
+			if n, err := num.Int64(); err == nil {
+				values[i] = n
+			} else if f, err := num.Float64(); err == nil {
+				values[i] = f
+			}
+		}
+	}
+	return values, nil
+}
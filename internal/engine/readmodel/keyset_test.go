@@ -0,0 +1,194 @@
+package readmodel
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"skyrix/internal/engine"
+	"skyrix/internal/engine/sqltest"
+
+	"gorm.io/gorm/schema"
+)
+
+type orderRow struct {
+	ID        int64
+	Status    string
+	CreatedAt time.Time
+}
+
+// newTestReader returns a Reader over testdata/*.sql whose statements are recorded, not run.
+func newTestReader(t *testing.T) (*Reader, *sqltest.Recorder) {
+	t.Helper()
+	gdb, rec := sqltest.Open(t)
+	return NewReader(engine.NewDatabaseService(gdb, "core"), MustLoadCatalog(testQueries, "testdata/*.sql")), rec
+}
+
+// lastSelect returns the last SELECT the recorder saw and its args.
+func lastSelect(t *testing.T, rec *sqltest.Recorder) (string, []any) {
+	t.Helper()
+	stmts, args := rec.Statements(), rec.Args()
+	for i := len(stmts) - 1; i >= 0; i-- {
+		if strings.HasPrefix(stmts[i], "SELECT") {
+			return stmts[i], args[i]
+		}
+	}
+	t.Fatalf("no SELECT in %q", stmts)
+	return "", nil
+}
+
+func TestKeysetQuery(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	keys := []Key{{Column: "created_at", Desc: true}, {Column: "id"}}
+	after, err := EncodeCursor([]any{"2026-02-01T00:00:00Z", 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name     string
+		req      PageRequest
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name: "first page",
+			req:  PageRequest{},
+			wantSQL: "SELECT * FROM (\nSELECT id, status, created_at\nFROM {{tenant}}.orders\nWHERE created_at >= $1\n) AS keyset" +
+				" ORDER BY created_at DESC, id LIMIT 51",
+			wantArgs: []any{since},
+		},
+		{
+			name: "after a cursor",
+			req:  PageRequest{Limit: 10, After: after},
+			wantSQL: "SELECT * FROM (\nSELECT id, status, created_at\nFROM {{tenant}}.orders\nWHERE created_at >= $1\n) AS keyset" +
+				" WHERE ((created_at < $2) OR (created_at = $3 AND id > $4)) ORDER BY created_at DESC, id LIMIT 11",
+			wantArgs: []any{since, "2026-02-01T00:00:00Z", "2026-02-01T00:00:00Z", int64(42)},
+		},
+		{
+			name: "limit is capped",
+			req:  PageRequest{Limit: 5000},
+			wantSQL: "SELECT * FROM (\nSELECT id, status, created_at\nFROM {{tenant}}.orders\nWHERE created_at >= $1\n) AS keyset" +
+				" ORDER BY created_at DESC, id LIMIT 1001",
+			wantArgs: []any{since},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r, rec := newTestReader(t)
+			page, err := Keyset[orderRow](context.Background(), r, "orders.recent", Params{"since": since}, keys, c.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Items) != 0 || page.HasMore || page.Next != "" {
+				t.Errorf("page = %+v, want an empty last page", page)
+			}
+			sql, args := lastSelect(t, rec)
+			if sql != c.wantSQL {
+				t.Errorf("sql:\n got %s\nwant %s", sql, c.wantSQL)
+			}
+			if !reflect.DeepEqual(args, c.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, c.wantArgs)
+			}
+		})
+	}
+}
+
+func TestKeysetRejectsBadInput(t *testing.T) {
+	r, rec := newTestReader(t)
+	ctx := context.Background()
+	params := Params{"since": time.Now()}
+
+	for name, run := range map[string]func() error{
+		"no keys": func() error {
+			_, err := Keyset[orderRow](ctx, r, "orders.recent", params, nil, PageRequest{})
+			return err
+		},
+		"key column": func() error {
+			_, err := Keyset[orderRow](ctx, r, "orders.recent", params, []Key{{Column: "id; DROP TABLE x"}}, PageRequest{})
+			return err
+		},
+		"cursor": func() error {
+			_, err := Keyset[orderRow](ctx, r, "orders.recent", params, []Key{{Column: "id"}}, PageRequest{After: "!!"})
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("bad cursor: %v, want ErrInvalidCursor", err)
+			}
+			return err
+		},
+	} {
+		if run() == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	if stmts := rec.Statements(); slices.ContainsFunc(stmts, func(s string) bool { return strings.HasPrefix(s, "SELECT") }) {
+		t.Errorf("rejected requests ran %q", stmts)
+	}
+}
+
+func TestBoundQueriesKeepQuestionMarks(t *testing.T) {
+	gdb, rec := sqltest.Open(t)
+	r := NewReader(engine.NewDatabaseService(gdb, "core"), &Catalog{queries: map[string]string{
+		"docs.tagged": "SELECT id FROM docs WHERE data ? 'tag' AND data @> :doc AND owner = :owner",
+	}})
+
+	if _, err := List[orderRow](context.Background(), r, "docs.tagged", Params{"doc": `{"a":1}`, "owner": 7}); err != nil {
+		t.Fatal(err)
+	}
+	sql, args := lastSelect(t, rec)
+	if want := "SELECT id FROM docs WHERE data ? 'tag' AND data @> $1 AND owner = $2"; sql != want {
+		t.Errorf("sql:\n got %s\nwant %s", sql, want)
+	}
+	if want := []any{`{"a":1}`, 7}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %#v, want %#v", args, want)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	keys := []Key{{Column: "status"}, {Column: "id"}}
+	namer := schema.NamingStrategy{}
+
+	fromStruct, err := encodeCursor(context.Background(), orderRow{ID: 9, Status: "open"}, keys, namer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromMap, err := encodeCursor(context.Background(), map[string]any{"id": 9, "status": "open"}, keys, namer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromStruct != fromMap {
+		t.Errorf("struct cursor %s != map cursor %s", fromStruct, fromMap)
+	}
+
+	got, err := DecodeCursor(fromStruct, len(keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{"open", int64(9)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %#v, want %#v", got, want)
+	}
+
+	if _, err := encodeCursor(context.Background(), orderRow{}, []Key{{Column: "missing"}}, namer); err == nil {
+		t.Error("cursor encoded a column the row does not have")
+	}
+	for _, bad := range []string{"!!", "bm90IGpzb24", fromStruct[:len(fromStruct)-3]} {
+		if _, err := DecodeCursor(bad, len(keys)); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) = %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+	if _, err := DecodeCursor(fromStruct, 3); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("cursor with 2 keys decoded for 3: %v", err)
+	}
+}
+
+func TestAfterCondition(t *testing.T) {
+	cond, args := AfterCondition([]Key{{Column: "a"}, {Column: "b", Desc: true}, {Column: "c"}}, []any{1, 2, 3})
+	if want := "((a > ?) OR (a = ? AND b < ?) OR (a = ? AND b = ? AND c > ?))"; cond != want {
+		t.Errorf("cond = %s, want %s", cond, want)
+	}
+	if want := []any{1, 1, 2, 1, 2, 3}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+}
@@ -0,0 +1,101 @@
+package readmodel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"skyrix/internal/engine"
+
+	"gorm.io/gorm"
+)
+
+// Reader executes catalog queries.
+type Reader struct {
+	DB      engine.DB
+	Catalog *Catalog
+}
+
+func NewReader(db engine.DB, catalog *Catalog) *Reader {
+	return &Reader{DB: db, Catalog: catalog}
+}
+
+// prepare resolves name and binds params on a session for ctx.
+func (r *Reader) prepare(ctx context.Context, name string, params Params) (*gorm.DB, string, []any, error) {
+	sql, err := r.Catalog.SQL(name)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	bound, args, err := bind(sql, params)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("%w (query %q)", err, name)
+	}
+	return r.DB.WithContext(ctx), bound, args, nil
+}
+
+// raw runs sql as written with args for its $n placeholders. gorm's Raw would otherwise read
+// ? and @name in it as its own placeholders.
+func raw(db *gorm.DB, sql string, args []any) *gorm.DB {
+	tx := db.Raw(sql)
+	tx.Statement.Vars = append(tx.Statement.Vars, args...)
+	return tx
+}
+
+// List runs query name and scans every row into T.
+func List[T any](ctx context.Context, r *Reader, name string, params Params) ([]T, error) {
+	db, sql, args, err := r.prepare(ctx, name, params)
+	if err != nil {
+		return nil, err
+	}
+	var out []T
+	if err := raw(db, sql, args).Scan(&out).Error; err != nil {
+		return nil, fmt.Errorf("readmodel: %s: %w", name, err)
+	}
+	return out, nil
+}
+
+// Get runs query name and scans the first row into T. ok is false when there are no rows.
+func Get[T any](ctx context.Context, r *Reader, name string, params Params) (item T, ok bool, err error) {
+	err = Stream(ctx, r, name, params, func(row T) error {
+		item, ok = row, true
+		return errStop
+	})
+	return item, ok, err
+}
+
+var errStop = errors.New("readmodel: stop")
+
+// Stream runs query name and calls fn for each row without loading the result into memory.
+// Returning an error from fn stops the iteration and is returned as is.
+func Stream[T any](ctx context.Context, r *Reader, name string, params Params, fn func(T) error) error {
+	db, sql, args, err := r.prepare(ctx, name, params)
+	if err != nil {
+		return err
+	}
+	return stream(db, name, sql, args, fn)
+}
+
+func stream[T any](db *gorm.DB, name, sql string, args []any, fn func(T) error) error {
+	rows, err := raw(db, sql, args).Rows()
+	if err != nil {
+		return fmt.Errorf("readmodel: %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item T
+		if err := db.ScanRows(rows, &item); err != nil {
+			return fmt.Errorf("readmodel: %s: scan: %w", name, err)
+		}
+		if err := fn(item); err != nil {
+			if errors.Is(err, errStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("readmodel: %s: %w", name, err)
+	}
+	return nil
+}
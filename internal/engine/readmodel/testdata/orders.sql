@@ -0,0 +1,7 @@
+-- name: orders.recent
+SELECT id, status, created_at
+FROM {{tenant}}.orders
+WHERE created_at >= :since;
+
+-- name: orders.by_ids
+SELECT id, status FROM {{tenant}}.orders WHERE id IN :ids
@@ -0,0 +1 @@
+SELECT count(*) AS n FROM {{tenant}}.orders WHERE status = :status
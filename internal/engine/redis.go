@@ -3,31 +3,44 @@ package engine
 import (
 	"context"
 	"errors"
+	"fmt"
+	"skyrix/internal/engine/metrics"
 	"skyrix/internal/logger"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// delIfValueScript deletes KEYS[1] only while it holds ARGV[1].
+var delIfValueScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`)
+
 // Redis is a generic Redis wrapper for cache/KV operations.
 type Redis struct {
-	client    *redis.Client
+	client    redis.UniversalClient
 	logger    logger.Interface
 	keyPrefix string
 	statusTTL time.Duration
+	metrics   *metrics.Metrics
 }
 
 // RedisOpts contains configuration options for Redis service initialization.
 type RedisOpts struct {
-	KeyPrefix string        // Prefix for all Redis keys ()
-	StatusTTL time.Duration // Default TTL for status-related keys (default: 10 minutes)
+	KeyPrefix string           // Prefix for all Redis keys ()
+	StatusTTL time.Duration    // Default TTL for status-related keys (default: 10 minutes)
+	Metrics   *metrics.Metrics // Counts reads in cache_requests_total (nil: not recorded)
 }
 
 // NewRedisService creates a new Redis service instance.
 // Key prefix is normalized (trailing colons removed) and defaults to "skyrix-delivery" if empty.
 // StatusTTL defaults to 10 minutes if not specified or zero.
-func NewRedisService(client *redis.Client, lg logger.Interface, redisOpts RedisOpts) *Redis {
+func NewRedisService(client redis.UniversalClient, lg logger.Interface, redisOpts RedisOpts) *Redis {
 	prefix := strings.TrimSuffix(strings.TrimSpace(redisOpts.KeyPrefix), ":")
 	if prefix == "" {
 		prefix = "skyrix-delivery"
@@ -36,7 +49,7 @@ func NewRedisService(client *redis.Client, lg logger.Interface, redisOpts RedisO
 	if ttl <= 0 {
 		ttl = 10 * time.Minute
 	}
-	return &Redis{client: client, logger: lg, keyPrefix: prefix, statusTTL: ttl}
+	return &Redis{client: client, logger: lg, keyPrefix: prefix, statusTTL: ttl, metrics: redisOpts.Metrics}
 }
 
 // Get retrieves raw bytes from Redis by key.
@@ -44,17 +57,26 @@ func NewRedisService(client *redis.Client, lg logger.Interface, redisOpts RedisO
 func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
 	b, err := r.client.Get(ctx, key).Bytes()
 	if errors.Is(err, redis.Nil) {
+		r.countGet("miss")
 		return nil, false, nil
 	}
 	if err != nil {
+		r.countGet("error")
 		if r.logger != nil {
 			r.logger.Warn("Get Error", "key", key, "err", err)
 		}
 		return nil, false, err
 	}
+	r.countGet("hit")
 	return b, true, nil
 }
 
+func (r *Redis) countGet(result string) {
+	if r.metrics != nil {
+		r.metrics.CacheRequests.With("redis", result).Inc()
+	}
+}
+
 // Set stores raw bytes in Redis with the specified TTL.
 //
 // Semantics:
@@ -70,11 +92,37 @@ func (r *Redis) Set(ctx context.Context, key string, data []byte, ttl time.Durat
 	return r.client.Set(ctx, key, data, ttl).Err()
 }
 
+// SetNX stores raw bytes only if the key does not exist yet (SET NX).
+// TTL semantics match Set. Returns true if the value was stored.
+func (r *Redis) SetNX(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error) {
+	if ttl < 0 {
+		ttl = r.statusTTL
+	}
+	return r.client.SetNX(ctx, key, data, ttl).Result()
+}
+
 // Del removes a key from Redis.
 func (r *Redis) Del(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
 }
 
+// DelIfValue deletes key only if it holds val (compare-and-delete in one Lua call).
+func (r *Redis) DelIfValue(ctx context.Context, key string, val []byte) (bool, error) {
+	n, err := delIfValueScript.Run(ctx, r.client, []string{key}, val).Int64()
+	return n == 1, err
+}
+
+// Expire sets the TTL of an existing key; TTL semantics match Set.
+func (r *Redis) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if ttl < 0 {
+		ttl = r.statusTTL
+	}
+	if ttl == 0 {
+		return r.client.Persist(ctx, key).Result()
+	}
+	return r.client.Expire(ctx, key, ttl).Result()
+}
+
 // Exists checks if a key exists in Redis.
 func (r *Redis) Exists(ctx context.Context, key string) (bool, error) {
 	n, err := r.client.Exists(ctx, key).Result()
@@ -84,22 +132,89 @@ func (r *Redis) Exists(ctx context.Context, key string) (bool, error) {
 	return n > 0, nil
 }
 
+var (
+	_ KeyScanner     = (*Redis)(nil)
+	_ CompareDeleter = (*Redis)(nil)
+	_ Expirer        = (*Redis)(nil)
+)
+
 // KeyPrefix returns the key prefix used by this Redis service instance.
 func (r *Redis) KeyPrefix() string { return r.keyPrefix }
 
+// Cluster cursors returned by ScanKeys carry the index of the master being scanned (masters
+// ordered by address) in the top 16 bits and that master's SCAN cursor in the rest.
+const (
+	clusterCursorBits = 48
+	clusterCursorMask = 1<<clusterCursorBits - 1
+)
+
 // ScanKeys scans Redis for keys matching a pattern using the SCAN command.
 // Returns keys in batches along with the cursor for the next iteration (0 when done).
+// In cluster mode each call runs one SCAN on one master and the cursor moves on to the next
+// master once it is done, so no call holds more than a batch. As with SCAN itself, keys may
+// be skipped or repeated if the cluster fails over or reshards during the iteration.
 func (r *Redis) ScanKeys(ctx context.Context, pattern string, count int, cursor uint64) ([]string, uint64, error) {
-	return r.client.Scan(ctx, cursor, pattern, int64(count)).Result()
+	cluster, ok := r.client.(*redis.ClusterClient)
+	if !ok {
+		return r.client.Scan(ctx, cursor, pattern, int64(count)).Result()
+	}
+	masters, err := clusterMasters(ctx, cluster)
+	if err != nil {
+		return nil, 0, err
+	}
+	idx := int(cursor >> clusterCursorBits)
+	if idx >= len(masters) {
+		return nil, 0, fmt.Errorf("redis: scan cursor %d is past the cluster's %d masters", cursor, len(masters))
+	}
+	node := masters[idx]
+	keys, next, err := node.Scan(ctx, cursor&clusterCursorMask, pattern, int64(count)).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case next > clusterCursorMask:
+		return nil, 0, fmt.Errorf("redis: scan cursor %d of %s does not fit a cluster cursor", next, node.Options().Addr)
+	case next != 0:
+		return keys, uint64(idx)<<clusterCursorBits | next, nil
+	case idx+1 < len(masters):
+		return keys, uint64(idx+1) << clusterCursorBits, nil
+	default:
+		return keys, 0, nil
+	}
+}
+
+// clusterMasters returns the cluster's masters ordered by address, so a master's index stays
+// the same across ScanKeys calls while the topology does.
+func clusterMasters(ctx context.Context, cluster *redis.ClusterClient) ([]*redis.Client, error) {
+	var (
+		mu      sync.Mutex
+		masters []*redis.Client
+	)
+	err := cluster.ForEachMaster(ctx, func(_ context.Context, node *redis.Client) error {
+		mu.Lock()
+		masters = append(masters, node)
+		mu.Unlock()
+		return nil
+	})
+	slices.SortFunc(masters, func(a, b *redis.Client) int {
+		return strings.Compare(a.Options().Addr, b.Options().Addr)
+	})
+	return masters, err
 }
 
 // DelMany deletes multiple keys from Redis in a single transaction using pipeline.
-// Returns immediately if keys slice is empty.
+// In cluster mode keys may live in different hash slots, so they are pipelined without a
+// transaction. Returns immediately if keys slice is empty.
 func (r *Redis) DelMany(ctx context.Context, keys []string) error {
 	if len(keys) == 0 {
 		return nil
 	}
-	pipe := r.client.TxPipeline()
+	var pipe redis.Pipeliner
+	if _, cluster := r.client.(*redis.ClusterClient); cluster {
+		pipe = r.client.Pipeline()
+	} else {
+		pipe = r.client.TxPipeline()
+	}
 	for _, k := range keys {
 		pipe.Del(ctx, k)
 	}
@@ -115,3 +230,23 @@ func (r *Redis) Close() error {
 	}
 	return r.client.Close()
 }
+
+// Ping checks the connection; in cluster mode every master must answer.
+func (r *Redis) Ping(ctx context.Context) error {
+	if cc, ok := r.client.(*redis.ClusterClient); ok {
+		return cc.ForEachMaster(ctx, func(ctx context.Context, c *redis.Client) error {
+			return c.Ping(ctx).Err()
+		})
+	}
+	return r.client.Ping(ctx).Err()
+}
+
+// Publish sends msg to a Redis pub/sub channel.
+func (r *Redis) Publish(ctx context.Context, channel string, msg []byte) error {
+	return r.client.Publish(ctx, channel, msg).Err()
+}
+
+// Subscribe subscribes to a Redis pub/sub channel. The caller must close the returned PubSub.
+func (r *Redis) Subscribe(ctx context.Context, channel string) *redis.PubSub {
+	return r.client.Subscribe(ctx, channel)
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"skyrix/internal/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Lua keeps each step atomic: the lease is only extended or deleted by the token that owns it,
+// so an owner whose lease expired cannot release the next owner's lock.
+var (
+	// KEYS[1] lease, KEYS[2] fence counter; ARGV[1] token, ARGV[2] ttl ms.
+	lockAcquireScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+return 0`)
+	lockRefreshScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`)
+	lockReleaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`)
+)
+
+// RedisLocker is a Locker on Redis. A lease is "<prefix>:lock:{<key>}" holding the owner token;
+// its fence counter "<prefix>:lock:{<key>}:fence" shares the hash slot and never expires.
+type RedisLocker struct {
+	*locker
+	redis *Redis
+}
+
+var _ Locker = (*RedisLocker)(nil)
+
+func NewRedisLocker(r *Redis, log logger.Interface) *RedisLocker {
+	l := &RedisLocker{redis: r}
+	l.locker = &locker{backend: l, log: log}
+	return l
+}
+
+func (l *RedisLocker) leaseKey(key string) string {
+	return l.redis.KeyPrefix() + ":lock:{" + key + "}"
+}
+
+func (l *RedisLocker) acquire(ctx context.Context, key, token string, ttl time.Duration) (int64, bool, error) {
+	lk := l.leaseKey(key)
+	fence, err := lockAcquireScript.Run(ctx, l.redis.client, []string{lk, lk + ":fence"}, token, ttlMillis(ttl)).Int64()
+	if err != nil {
+		return 0, false, err
+	}
+	return fence, fence > 0, nil
+}
+
+func (l *RedisLocker) refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	n, err := lockRefreshScript.Run(ctx, l.redis.client, []string{l.leaseKey(key)}, token, ttlMillis(ttl)).Int64()
+	return n == 1, err
+}
+
+func (l *RedisLocker) release(ctx context.Context, key, token string) (bool, error) {
+	n, err := lockReleaseScript.Run(ctx, l.redis.client, []string{l.leaseKey(key)}, token).Int64()
+	return n == 1, err
+}
+
+func ttlMillis(ttl time.Duration) int64 {
+	return max(ttl.Milliseconds(), 1)
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func testRedisLocker(t *testing.T) (*miniredis.Miniredis, *RedisLocker) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, NewRedisLocker(NewRedisService(client, testLogger(), RedisOpts{KeyPrefix: "test"}), testLogger())
+}
+
+func TestRedisLockerAcquireAndRelease(t *testing.T) {
+	ctx := context.Background()
+	mr, l := testRedisLocker(t)
+
+	a, err := l.TryLock(ctx, "k", WithLeaseTTL(time.Minute), WithoutRenewal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := mr.Get("test:lock:{k}"); got != a.Token() {
+		t.Fatalf("lease holds %q, want the owner token %q", got, a.Token())
+	}
+	if ttl := mr.TTL("test:lock:{k}"); ttl != time.Minute {
+		t.Errorf("lease TTL = %v, want 1m", ttl)
+	}
+	if _, err := l.TryLock(ctx, "k"); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("second TryLock: got %v, want ErrLockHeld", err)
+	}
+
+	if err := a.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("test:lock:{k}") {
+		t.Fatal("lease still exists after Unlock")
+	}
+	select {
+	case <-a.Lost():
+	default:
+		t.Error("Lost() is not closed after Unlock")
+	}
+
+	b, err := l.TryLock(ctx, "k", WithoutRenewal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Unlock(ctx)
+	if a.Fence() != 1 || b.Fence() != 2 {
+		t.Errorf("fences = %d, %d; want 1, 2", a.Fence(), b.Fence())
+	}
+}
+
+// An owner whose lease expired cannot refresh or release the next owner's lease.
+func TestRedisLockerExpiredOwner(t *testing.T) {
+	ctx := context.Background()
+	mr, l := testRedisLocker(t)
+
+	a, err := l.TryLock(ctx, "k", WithLeaseTTL(time.Second), WithoutRenewal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(2 * time.Second)
+
+	b, err := l.TryLock(ctx, "k", WithLeaseTTL(time.Minute), WithoutRenewal())
+	if err != nil {
+		t.Fatalf("TryLock after expiry: %v", err)
+	}
+	if err := a.Refresh(ctx, time.Hour); !errors.Is(err, ErrLockLost) {
+		t.Errorf("stale Refresh: got %v, want ErrLockLost", err)
+	}
+	if err := a.Unlock(ctx); !errors.Is(err, ErrLockLost) {
+		t.Errorf("stale Unlock: got %v, want ErrLockLost", err)
+	}
+	if got, _ := mr.Get("test:lock:{k}"); got != b.Token() {
+		t.Errorf("lease holds %q, want the new owner's token", got)
+	}
+	if ttl := mr.TTL("test:lock:{k}"); ttl != time.Minute {
+		t.Errorf("lease TTL = %v, want the new owner's 1m", ttl)
+	}
+	if b.Fence() <= a.Fence() {
+		t.Errorf("new fence %d is not above the old one %d", b.Fence(), a.Fence())
+	}
+}
+
+func TestRedisLockerRenewsLease(t *testing.T) {
+	ctx := context.Background()
+	mr, l := testRedisLocker(t)
+
+	lock, err := l.TryLock(ctx, "k", WithLeaseTTL(300*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Unlock(ctx)
+
+	mr.FastForward(250 * time.Millisecond)
+	eventually(t, func() bool { return mr.TTL("test:lock:{k}") > 100*time.Millisecond }, "lease was not renewed")
+}
+
+func TestRunLockedCancelsWhenLeaseIsLost(t *testing.T) {
+	ctx := context.Background()
+	mr, l := testRedisLocker(t)
+
+	lock, err := l.TryLock(ctx, "k", WithLeaseTTL(150*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Someone else took the key over: the next renewal sees a foreign token.
+	mr.Set("test:lock:{k}", "other")
+
+	err = RunLocked(ctx, lock, func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-time.After(2 * time.Second):
+			return errors.New("work was not cancelled")
+		}
+	})
+	if !errors.Is(err, ErrLockLost) {
+		t.Fatalf("RunLocked: got %v, want ErrLockLost", err)
+	}
+	if got, _ := mr.Get("test:lock:{k}"); got != "other" {
+		t.Errorf("the other owner's lease was touched: %q", got)
+	}
+}
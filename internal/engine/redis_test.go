@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"skyrix/internal/engine/metrics"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisCountsCacheReads(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	m, err := metrics.New(prometheus.NewRegistry(), metrics.Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRedisService(client, testLogger(), RedisOpts{KeyPrefix: "test", Metrics: m})
+
+	_ = r.Set(ctx, "k", []byte("v"), time.Minute)
+	_, _, _ = r.Get(ctx, "k")
+	_, _, _ = r.Get(ctx, "k")
+	_, _, _ = r.Get(ctx, "missing")
+	mr.Close()
+	_, _, _ = r.Get(ctx, "k")
+
+	for result, want := range map[string]float64{"hit": 2, "miss": 1, "error": 1} {
+		if got := testutil.ToFloat64(m.CacheRequests.With("redis", result)); got != want {
+			t.Errorf("%s = %v, want %v", result, got, want)
+		}
+	}
+}
+
+// testCluster returns a cluster client whose slots are split between two miniredis masters.
+func testCluster(t *testing.T) (*redis.ClusterClient, []*miniredis.Miniredis) {
+	t.Helper()
+	masters := []*miniredis.Miniredis{miniredis.RunT(t), miniredis.RunT(t)}
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func(context.Context) ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{
+				{Start: 0, End: 8191, Nodes: []redis.ClusterNode{{Addr: masters[0].Addr()}}},
+				{Start: 8192, End: 16383, Nodes: []redis.ClusterNode{{Addr: masters[1].Addr()}}},
+			}, nil
+		},
+		MaxRedirects: -1,
+	})
+	t.Cleanup(func() { _ = client.Close() })
+	return client, masters
+}
+
+// scanAll pages through ScanKeys until the cursor is 0 and returns the keys and the call count.
+func scanAll(t *testing.T, r *Redis, pattern string, count int) ([]string, int) {
+	t.Helper()
+	var (
+		keys   []string
+		cursor uint64
+	)
+	for calls := 1; ; calls++ {
+		batch, next, err := r.ScanKeys(context.Background(), pattern, count, cursor)
+		if err != nil {
+			t.Fatalf("ScanKeys(cursor %d): %v", cursor, err)
+		}
+		keys = append(keys, batch...)
+		if cursor = next; cursor == 0 {
+			slices.Sort(keys)
+			return keys, calls
+		}
+		if calls > 1000 {
+			t.Fatal("ScanKeys did not finish")
+		}
+	}
+}
+
+func TestRedisScanKeysCluster(t *testing.T) {
+	ctx := context.Background()
+	client, masters := testCluster(t)
+	r := NewRedisService(client, testLogger(), RedisOpts{KeyPrefix: "test"})
+
+	var want []string
+	for i := range 40 {
+		key := fmt.Sprintf("app:t:acme:%d", i)
+		want = append(want, key)
+		if err := client.Set(ctx, key, "v", 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Set(ctx, fmt.Sprintf("app:t:other:%d", i), "v", 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	slices.Sort(want)
+	for i, m := range masters {
+		if len(m.Keys()) == 0 {
+			t.Fatalf("master %d holds no keys; the test needs both", i)
+		}
+	}
+
+	keys, calls := scanAll(t, r, "app:t:acme:*", 5)
+	if !slices.Equal(keys, want) {
+		t.Errorf("scanned %v, want %v", keys, want)
+	}
+	if calls < len(masters) {
+		t.Errorf("scanned in %d calls, want at least one per master", calls)
+	}
+
+	if err := r.DelMany(ctx, keys); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := scanAll(t, r, "app:t:acme:*", 5); len(keys) != 0 {
+		t.Errorf("keys left after DelMany: %v", keys)
+	}
+	if others, _ := scanAll(t, r, "app:t:other:*", 100); len(others) != 40 {
+		t.Errorf("%d other keys left, want 40", len(others))
+	}
+
+	if _, _, err := r.ScanKeys(ctx, "*", 10, uint64(len(masters))<<clusterCursorBits); err == nil {
+		t.Error("cursor past the last master accepted")
+	}
+}
+
+func TestRedisScanKeysStandalone(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	r := NewRedisService(client, testLogger(), RedisOpts{KeyPrefix: "test"})
+
+	var want []string
+	for i := range 10 {
+		key := fmt.Sprintf("k:%d", i)
+		want = append(want, key)
+		_ = mr.Set(key, "v")
+	}
+	_ = mr.Set("other", "v")
+	slices.Sort(want)
+
+	if keys, _ := scanAll(t, r, "k:*", 3); !slices.Equal(keys, want) {
+		t.Errorf("scanned %v, want %v", keys, want)
+	}
+}
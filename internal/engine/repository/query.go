@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidQuery is returned for filters, sorts or paging values the Spec does not allow.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Op is a filter operator.
+type Op string
+
+const (
+	OpEq   Op = "eq"
+	OpNe   Op = "ne"
+	OpGt   Op = "gt"
+	OpGte  Op = "gte"
+	OpLt   Op = "lt"
+	OpLte  Op = "lte"
+	OpLike Op = "like" // case-insensitive "contains"
+	OpIn   Op = "in"   // comma-separated values
+	OpNull Op = "null" // "true" = IS NULL, "false" = IS NOT NULL
+)
+
+var sqlOps = map[Op]string{OpEq: "=", OpNe: "<>", OpGt: ">", OpGte: ">=", OpLt: "<", OpLte: "<="}
+
+// Filter is one whitelisted condition.
+type Filter struct {
+	Column string
+	Op     Op
+	Value  any
+}
+
+// Sort is one whitelisted order column.
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// Query is a parsed list request.
+type Query struct {
+	Filters []Filter
+	Sort    []Sort
+	Limit   int
+	Page    int    // offset pagination, 1-based
+	Cursor  string // cursor pagination; takes precedence over Page in ListCursor
+}
+
+// Spec whitelists what clients may filter and sort by. Keys are query param names,
+// values are column names; anything else is rejected, so params never reach SQL unchecked.
+type Spec struct {
+	Filters      map[string]string
+	Sorts        map[string]string
+	DefaultSort  []Sort
+	DefaultLimit int
+	MaxLimit     int
+}
+
+var (
+	reParam  = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_.]*)(?:\[([a-z]+)\])?$`)
+	reColumn = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+)
+
+// reserved query params handled by ParseQuery itself.
+var reserved = map[string]bool{"sort": true, "limit": true, "page": true, "cursor": true}
+
+// ParseQuery reads a list request from URL query params:
+//
+//	?status=active&created_at[gte]=2024-01-01&name[like]=acme&id[in]=1,2,3
+//	&sort=-created_at,id&limit=20&page=2   (or &cursor=...)
+//
+// Unknown plain params are ignored (they may belong to the handler); an operator on a field
+// that is not whitelisted, an unknown operator or an unknown sort field is ErrInvalidQuery.
+func ParseQuery(values url.Values, spec Spec) (Query, error) {
+	q := Query{Cursor: values.Get("cursor")}
+
+	for key, vals := range values {
+		if reserved[key] || len(vals) == 0 {
+			continue
+		}
+		m := reParam.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+		column, ok := spec.Filters[m[1]]
+		if !ok {
+			if m[2] != "" {
+				return q, fmt.Errorf("%w: filter on %q is not allowed", ErrInvalidQuery, m[1])
+			}
+			continue
+		}
+		op := Op(m[2])
+		if op == "" {
+			op = OpEq
+		}
+		f, err := newFilter(column, op, vals[0])
+		if err != nil {
+			return q, err
+		}
+		q.Filters = append(q.Filters, f)
+	}
+
+	if raw := strings.TrimSpace(values.Get("sort")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			part = strings.TrimSpace(part)
+			desc := strings.HasPrefix(part, "-")
+			column, ok := spec.Sorts[strings.TrimPrefix(part, "-")]
+			if !ok {
+				return q, fmt.Errorf("%w: sort by %q is not allowed", ErrInvalidQuery, part)
+			}
+			q.Sort = append(q.Sort, Sort{Column: column, Desc: desc})
+		}
+	} else {
+		q.Sort = append(q.Sort, spec.DefaultSort...)
+	}
+
+	var err error
+	if q.Limit, err = intParam(values, "limit", spec.DefaultLimit); err != nil {
+		return q, err
+	}
+	if spec.MaxLimit > 0 && q.Limit > spec.MaxLimit {
+		q.Limit = spec.MaxLimit
+	}
+	if q.Page, err = intParam(values, "page", 1); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+func newFilter(column string, op Op, raw string) (Filter, error) {
+	if !reColumn.MatchString(column) {
+		return Filter{}, fmt.Errorf("%w: invalid column %q", ErrInvalidQuery, column)
+	}
+	switch op {
+	case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpLike:
+		return Filter{Column: column, Op: op, Value: raw}, nil
+	case OpIn:
+		parts := strings.Split(raw, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		return Filter{Column: column, Op: op, Value: parts}, nil
+	case OpNull:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Filter{}, fmt.Errorf("%w: %s[null] must be true or false", ErrInvalidQuery, column)
+		}
+		return Filter{Column: column, Op: op, Value: b}, nil
+	default:
+		return Filter{}, fmt.Errorf("%w: unknown operator %q", ErrInvalidQuery, op)
+	}
+}
+
+func intParam(values url.Values, name string, def int) (int, error) {
+	raw := strings.TrimSpace(values.Get(name))
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%w: %s must be a positive integer", ErrInvalidQuery, name)
+	}
+	return n, nil
+}
+
+// clause renders f as a WHERE condition with its args.
+func (f Filter) clause() (string, []any) {
+	switch f.Op {
+	case OpLike:
+		return f.Column + " ILIKE ?", []any{"%" + escapeLike(fmt.Sprint(f.Value)) + "%"}
+	case OpIn:
+		return f.Column + " IN ?", []any{f.Value}
+	case OpNull:
+		if b, _ := f.Value.(bool); b {
+			return f.Column + " IS NULL", nil
+		}
+		return f.Column + " IS NOT NULL", nil
+	default:
+		return f.Column + " " + sqlOps[f.Op] + " ?", []any{f.Value}
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testSpec = Spec{
+	Filters:      map[string]string{"status": "status", "created": "created_at", "name": "name", "id": "id"},
+	Sorts:        map[string]string{"created": "created_at", "id": "id"},
+	DefaultSort:  []Sort{{Column: "created_at", Desc: true}},
+	DefaultLimit: 20,
+	MaxLimit:     100,
+}
+
+func TestParseQuery(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		query string
+		want  Query
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  Query{Sort: testSpec.DefaultSort, Limit: 20, Page: 1},
+		},
+		{
+			name:  "filters map to columns",
+			query: "status=active&created[gte]=2026-01-01&name[like]=acme&id[in]=1,%202",
+			want: Query{
+				Filters: []Filter{
+					{Column: "created_at", Op: OpGte, Value: "2026-01-01"},
+					{Column: "id", Op: OpIn, Value: []string{"1", "2"}},
+					{Column: "name", Op: OpLike, Value: "acme"},
+					{Column: "status", Op: OpEq, Value: "active"},
+				},
+				Sort: testSpec.DefaultSort, Limit: 20, Page: 1,
+			},
+		},
+		{
+			name:  "unknown plain params are ignored",
+			query: "tenant_id=9&debug=1&status=active",
+			want: Query{
+				Filters: []Filter{{Column: "status", Op: OpEq, Value: "active"}},
+				Sort:    testSpec.DefaultSort, Limit: 20, Page: 1,
+			},
+		},
+		{
+			name:  "sort, paging and cursor",
+			query: "sort=-created,id&limit=5&page=3&cursor=abc",
+			want: Query{
+				Sort:  []Sort{{Column: "created_at", Desc: true}, {Column: "id"}},
+				Limit: 5, Page: 3, Cursor: "abc",
+			},
+		},
+		{
+			name:  "limit is capped",
+			query: "limit=1000",
+			want:  Query{Sort: testSpec.DefaultSort, Limit: 100, Page: 1},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			values, err := url.ParseQuery(c.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseQuery(values, testSpec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.SortFunc(got.Filters, func(a, b Filter) int { return strings.Compare(a.Column, b.Column) })
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got  %+v\nwant %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryRejects(t *testing.T) {
+	for _, query := range []string{
+		"tenant_id[eq]=9",      // operator on a field outside the whitelist
+		"status[regex]=.*",     // unknown operator
+		"name[null]=maybe",     // null takes a bool
+		"sort=tenant_id",       // sort outside the whitelist
+		"sort=-created,status", // status is filterable, not sortable
+		"limit=0",              // paging must be positive
+		"limit=ten",
+		"page=-1",
+		"status[eq]=x&sort=password", // one bad part fails the query
+	} {
+		values, _ := url.ParseQuery(query)
+		if _, err := ParseQuery(values, testSpec); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("ParseQuery(%s) = %v, want ErrInvalidQuery", query, err)
+		}
+	}
+
+	bad := Spec{Filters: map[string]string{"x": `name"; DROP TABLE t; --`}}
+	if _, err := ParseQuery(url.Values{"x": {"1"}}, bad); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("spec with an invalid column: %v, want ErrInvalidQuery", err)
+	}
+}
+
+func TestFilterClause(t *testing.T) {
+	for _, c := range []struct {
+		filter Filter
+		cond   string
+		args   []any
+	}{
+		{Filter{Column: "status", Op: OpNe, Value: "x"}, "status <> ?", []any{"x"}},
+		{Filter{Column: "name", Op: OpLike, Value: `50%_a\b`}, "name ILIKE ?", []any{`%50\%\_a\\b%`}},
+		{Filter{Column: "id", Op: OpIn, Value: []string{"1", "2"}}, "id IN ?", []any{[]string{"1", "2"}}},
+		{Filter{Column: "deleted_at", Op: OpNull, Value: true}, "deleted_at IS NULL", nil},
+		{Filter{Column: "deleted_at", Op: OpNull, Value: false}, "deleted_at IS NOT NULL", nil},
+	} {
+		cond, args := c.filter.clause()
+		if cond != c.cond || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("%+v: got %s %v, want %s %v", c.filter, cond, args, c.cond, c.args)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+// Package repository provides a generic GORM repository for domain entities.
+//
+// Entities are routed by the schema-router plugin like any other model: types embedding
+// scope.MainModel live in the main schema, everything else in the tenant schema from ctx.
+// Embed SoftDelete for soft deletes and Versioned for optimistic locking.
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"skyrix/internal/engine"
+	"skyrix/internal/engine/readmodel"
+	"skyrix/internal/kernel/db/scope"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict is returned by Update when the row's version changed since it was read.
+	ErrConflict = errors.New("record was modified concurrently")
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 200
+)
+
+// SoftDelete makes Delete set deleted_at instead of removing the row;
+// soft-deleted rows are excluded from every query.
+type SoftDelete struct {
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index"`
+}
+
+// Versioned enables optimistic locking: Update only succeeds if version is unchanged
+// and increments it. Entities may declare their own version column of any integer type.
+type Versioned struct {
+	Version int64 `gorm:"column:version;not null;default:1"`
+}
+
+// OffsetPage is a page of offset pagination.
+type OffsetPage[T any] struct {
+	Items []T
+	Total int64
+	Page  int
+	Limit int
+}
+
+// CursorPage is a page of cursor pagination. Next is "" on the last page.
+type CursorPage[T any] struct {
+	Items   []T
+	Next    string
+	HasMore bool
+}
+
+// Repository implements CRUD and listing for entity type T.
+type Repository[T any] struct {
+	DB engine.DB
+
+	once   sync.Once
+	schema *schema.Schema
+	err    error
+}
+
+func New[T any](db engine.DB) *Repository[T] {
+	return &Repository[T]{DB: db}
+}
+
+// meta parses T once (primary key, version and soft delete columns).
+func (r *Repository[T]) meta(db *gorm.DB) (*schema.Schema, error) {
+	r.once.Do(func() {
+		stmt := db.Session(&gorm.Session{NewDB: true}).Statement
+		if r.err = stmt.Parse(new(T)); r.err == nil {
+			r.schema = stmt.Schema
+		}
+		if r.err == nil && r.schema.PrioritizedPrimaryField == nil {
+			r.err = fmt.Errorf("repository: %s has no primary key", r.schema.Name)
+		}
+		if r.err == nil {
+			if f := r.schema.LookUpField("version"); f != nil && !isInteger(f.IndirectFieldType.Kind()) {
+				r.err = fmt.Errorf("repository: %s.%s must be an integer to lock on it", r.schema.Name, f.Name)
+			}
+		}
+	})
+	return r.schema, r.err
+}
+
+func isInteger(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// versionOf reads the version field of rv as int64; meta only accepts integer version fields.
+func versionOf(ctx context.Context, f *schema.Field, rv reflect.Value) int64 {
+	v, _ := f.ValueOf(ctx, rv)
+	switch fv := reflect.Indirect(reflect.ValueOf(v)); {
+	case fv.CanInt():
+		return fv.Int()
+	case fv.CanUint():
+		return int64(fv.Uint())
+	}
+	return 0
+}
+
+// session returns a session on T's table for ctx (transaction in ctx is joined).
+func (r *Repository[T]) session(ctx context.Context) (*gorm.DB, *schema.Schema, error) {
+	db := r.DB.WithContext(ctx)
+	s, err := r.meta(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db.Model(new(T)), s, nil
+}
+
+// Get returns the entity with primary key id.
+func (r *Repository[T]) Get(ctx context.Context, id any) (*T, error) {
+	db, s, err := r.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out T
+	err = db.Where(clause.Eq{Column: clause.Column{Name: s.PrioritizedPrimaryField.DBName}, Value: id}).Take(&out).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Create inserts entity. Versioned entities start at version 1.
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	db, s, err := r.session(ctx)
+	if err != nil {
+		return err
+	}
+	if f := s.LookUpField("version"); f != nil {
+		if versionOf(ctx, f, reflect.ValueOf(entity).Elem()) == 0 {
+			if err := f.Set(ctx, reflect.ValueOf(entity).Elem(), int64(1)); err != nil {
+				return err
+			}
+		}
+	}
+	return db.Create(entity).Error
+}
+
+// Update saves all fields of entity (zero values included) except the primary key, created_at,
+// tenant_id and deleted_at: it neither moves a row to another tenant nor deletes or restores
+// it. Soft-deleted rows are not found. For Versioned entities it only matches the version that
+// was read, returns ErrConflict otherwise, and bumps entity's version.
+func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	db, s, err := r.session(ctx)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(entity).Elem()
+	pk := s.PrioritizedPrimaryField
+	id, zero := pk.ValueOf(ctx, rv)
+	if zero {
+		return fmt.Errorf("repository: update %s without primary key", s.Name)
+	}
+
+	q := db.Where(clause.Eq{Column: clause.Column{Name: pk.DBName}, Value: id})
+	omit := []string{pk.DBName}
+	for _, name := range []string{"created_at", scope.TenantIDColumn, "deleted_at"} {
+		if f := s.LookUpField(name); f != nil {
+			omit = append(omit, f.DBName)
+		}
+	}
+
+	version := s.LookUpField("version")
+	var current int64
+	if version != nil {
+		current = versionOf(ctx, version, rv)
+		if err := version.Set(ctx, rv, current+1); err != nil {
+			return err
+		}
+		q = q.Where(clause.Eq{Column: clause.Column{Name: version.DBName}, Value: current})
+	}
+
+	res := q.Select("*").Omit(omit...).Updates(entity)
+	if res.Error != nil || res.RowsAffected > 0 {
+		if res.Error != nil && version != nil {
+			_ = version.Set(ctx, rv, current)
+		}
+		return res.Error
+	}
+
+	if version != nil {
+		_ = version.Set(ctx, rv, current)
+		if _, err := r.Get(ctx, id); err == nil {
+			return ErrConflict
+		}
+	}
+	return ErrNotFound
+}
+
+// Delete removes the entity with primary key id; entities embedding SoftDelete are soft-deleted.
+func (r *Repository[T]) Delete(ctx context.Context, id any) error {
+	db, s, err := r.session(ctx)
+	if err != nil {
+		return err
+	}
+	res := db.Where(clause.Eq{Column: clause.Column{Name: s.PrioritizedPrimaryField.DBName}, Value: id}).Delete(new(T))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// HardDelete removes the row even for SoftDelete entities.
+func (r *Repository[T]) HardDelete(ctx context.Context, id any) error {
+	db, s, err := r.session(ctx)
+	if err != nil {
+		return err
+	}
+	res := db.Unscoped().Where(clause.Eq{Column: clause.Column{Name: s.PrioritizedPrimaryField.DBName}, Value: id}).Delete(new(T))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Restore clears deleted_at of a soft-deleted entity.
+func (r *Repository[T]) Restore(ctx context.Context, id any) error {
+	db, s, err := r.session(ctx)
+	if err != nil {
+		return err
+	}
+	if s.LookUpField("deleted_at") == nil {
+		return fmt.Errorf("repository: %s does not support soft delete", s.Name)
+	}
+	res := db.Unscoped().
+		Where(clause.Eq{Column: clause.Column{Name: s.PrioritizedPrimaryField.DBName}, Value: id}).
+		Where("deleted_at IS NOT NULL").
+		Update("deleted_at", nil)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// applyFilters adds q's filters (already whitelisted by ParseQuery) to db.
+func applyFilters(db *gorm.DB, q Query) *gorm.DB {
+	for _, f := range q.Filters {
+		cond, args := f.clause()
+		db = db.Where(cond, args...)
+	}
+	return db
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultLimit
+	}
+	if limit > maxLimit {
+		return maxLimit
+	}
+	return limit
+}
+
+// List returns one page of offset pagination with the total row count.
+func (r *Repository[T]) List(ctx context.Context, q Query) (OffsetPage[T], error) {
+	page := OffsetPage[T]{Page: q.Page, Limit: clampLimit(q.Limit)}
+	if page.Page < 1 {
+		page.Page = 1
+	}
+	db, s, err := r.session(ctx)
+	if err != nil {
+		return page, err
+	}
+	db = applyFilters(db, q)
+
+	if err := db.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return page, err
+	}
+	for _, k := range r.keys(s, q.Sort) {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: k.Column}, Desc: k.Desc})
+	}
+	err = db.Offset((page.Page - 1) * page.Limit).Limit(page.Limit).Find(&page.Items).Error
+	return page, err
+}
+
+// ListCursor returns one page of cursor (keyset) pagination ordered by q.Sort plus the primary key.
+func (r *Repository[T]) ListCursor(ctx context.Context, q Query) (CursorPage[T], error) {
+	var page CursorPage[T]
+	limit := clampLimit(q.Limit)
+	db, s, err := r.session(ctx)
+	if err != nil {
+		return page, err
+	}
+	db = applyFilters(db, q)
+	keys := r.keys(s, q.Sort)
+
+	if q.Cursor != "" {
+		after, err := readmodel.DecodeCursor(q.Cursor, len(keys))
+		if err != nil {
+			return page, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+		}
+		cond, args := readmodel.AfterCondition(keys, after)
+		db = db.Where(cond, args...)
+	}
+	for _, k := range keys {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: k.Column}, Desc: k.Desc})
+	}
+	if err := db.Limit(limit + 1).Find(&page.Items).Error; err != nil {
+		return page, err
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		page.HasMore = true
+		last := reflect.ValueOf(&page.Items[limit-1]).Elem()
+		values := make([]any, len(keys))
+		for i, k := range keys {
+			f := s.LookUpField(k.Column)
+			if f == nil {
+				return page, fmt.Errorf("repository: sort column %q is not a field of %s", k.Column, s.Name)
+			}
+			values[i], _ = f.ValueOf(ctx, last)
+		}
+		if page.Next, err = readmodel.EncodeCursor(values); err != nil {
+			return page, err
+		}
+	}
+	return page, nil
+}
+
+// keys appends the primary key to sorts so the order is total (required by cursors).
+func (r *Repository[T]) keys(s *schema.Schema, sorts []Sort) []readmodel.Key {
+	pk := s.PrioritizedPrimaryField.DBName
+	keys := make([]readmodel.Key, 0, len(sorts)+1)
+	hasPK := false
+	for _, srt := range sorts {
+		keys = append(keys, readmodel.Key{Column: srt.Column, Desc: srt.Desc})
+		hasPK = hasPK || srt.Column == pk
+	}
+	if !hasPK {
+		keys = append(keys, readmodel.Key{Column: pk})
+	}
+	return keys
+}
@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"skyrix/internal/engine"
+	"skyrix/internal/engine/sqltest"
+)
+
+type widget struct {
+	ID        int64
+	Name      string
+	Status    string
+	TenantID  int64 `gorm:"column:tenant_id"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	SoftDelete
+	Version int32 `gorm:"column:version;not null;default:1"`
+}
+
+var widgetColumns = []string{"id", "name", "status", "tenant_id", "created_at", "updated_at", "deleted_at", "version"}
+
+func widgetRow(id int64, name string, createdAt time.Time, version int64) []driver.Value {
+	return []driver.Value{id, name, "active", int64(7), createdAt, createdAt, nil, version}
+}
+
+func newTestRepository[T any](t *testing.T) (*Repository[T], *sqltest.Recorder) {
+	t.Helper()
+	gdb, rec := sqltest.Open(t)
+	return New[T](engine.NewDatabaseService(gdb, "core")), rec
+}
+
+// statement returns the only recorded statement starting with prefix.
+func statement(t *testing.T, rec *sqltest.Recorder, prefix string) (string, []any) {
+	t.Helper()
+	var (
+		found string
+		args  []any
+		n     int
+	)
+	all := rec.Args()
+	for i, s := range rec.Statements() {
+		if strings.HasPrefix(s, prefix) {
+			found, args, n = s, all[i], n+1
+		}
+	}
+	if n != 1 {
+		t.Fatalf("%d statements start with %s: %q", n, prefix, rec.Statements())
+	}
+	return found, args
+}
+
+// respond answers SELECTs with rows and writes with affected.
+func respond(rec *sqltest.Recorder, affected int64, rows ...[]driver.Value) {
+	rec.Respond = func(stmt string) sqltest.Response {
+		if strings.HasPrefix(stmt, "SELECT") {
+			return sqltest.Response{Columns: widgetColumns, Rows: rows}
+		}
+		return sqltest.Response{RowsAffected: affected}
+	}
+}
+
+func TestUpdateKeepsProtectedColumns(t *testing.T) {
+	r, rec := newTestRepository[widget](t)
+	respond(rec, 1)
+
+	w := &widget{ID: 1, Name: "renamed", TenantID: 99, Version: 3}
+	if err := r.Update(context.Background(), w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Version != 4 {
+		t.Errorf("version = %d, want 4", w.Version)
+	}
+
+	stmt, args := statement(t, rec, "UPDATE")
+	set, where, _ := strings.Cut(stmt, " WHERE ")
+	for _, col := range []string{`"name"`, `"status"`, `"updated_at"`, `"version"`} {
+		if !strings.Contains(set, col) {
+			t.Errorf("%s does not set %s", stmt, col)
+		}
+	}
+	for _, col := range []string{`"id"`, `"tenant_id"`, `"created_at"`, `"deleted_at"`} {
+		if strings.Contains(set, col) {
+			t.Errorf("%s sets %s", stmt, col)
+		}
+	}
+	if !strings.Contains(where, `"version" = `) || !strings.Contains(where, `"deleted_at" IS NULL`) {
+		t.Errorf("%s does not match the read version of a live row", stmt)
+	}
+	if args[len(args)-1] != int64(3) {
+		t.Errorf("args %v: want the read version 3 last", args)
+	}
+}
+
+func TestUpdateVersionConflict(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, c := range []struct {
+		name string
+		rows [][]driver.Value
+		want error
+	}{
+		{"row changed", [][]driver.Value{widgetRow(1, "other", created, 4)}, ErrConflict},
+		{"row gone", nil, ErrNotFound},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r, rec := newTestRepository[widget](t)
+			respond(rec, 0, c.rows...)
+
+			w := &widget{ID: 1, Name: "mine", Version: 3}
+			if err := r.Update(context.Background(), w); !errors.Is(err, c.want) {
+				t.Errorf("Update = %v, want %v", err, c.want)
+			}
+			if w.Version != 3 {
+				t.Errorf("version = %d after a failed update, want 3", w.Version)
+			}
+		})
+	}
+}
+
+func TestNonIntegerVersionIsRejected(t *testing.T) {
+	type badVersion struct {
+		ID      int64
+		Version string
+	}
+	r, rec := newTestRepository[badVersion](t)
+	if err := r.Update(context.Background(), &badVersion{ID: 1, Version: "a"}); err == nil || !strings.Contains(err.Error(), "integer") {
+		t.Errorf("Update = %v, want an integer version error", err)
+	}
+	if stmts := rec.Statements(); len(stmts) != 0 {
+		t.Errorf("ran %q", stmts)
+	}
+}
+
+func TestCreateStartsAtVersionOne(t *testing.T) {
+	r, _ := newTestRepository[widget](t)
+	w := &widget{Name: "new"}
+	if err := r.Create(context.Background(), w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Version != 1 {
+		t.Errorf("version = %d, want 1", w.Version)
+	}
+}
+
+func TestSoftDeleteScope(t *testing.T) {
+	ctx := context.Background()
+	live := `"widgets"."deleted_at" IS NULL`
+
+	r, rec := newTestRepository[widget](t)
+	respond(rec, 1)
+	if _, err := r.Get(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get = %v, want ErrNotFound", err)
+	}
+	if stmt, _ := statement(t, rec, "SELECT"); !strings.Contains(stmt, live) {
+		t.Errorf("Get sees deleted rows: %s", stmt)
+	}
+
+	rec.Reset()
+	if err := r.Delete(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if stmt, _ := statement(t, rec, "UPDATE"); !strings.Contains(stmt, `SET "deleted_at"=`) || !strings.Contains(stmt, live) {
+		t.Errorf("Delete did not soft-delete a live row: %s", stmt)
+	}
+
+	rec.Reset()
+	if err := r.HardDelete(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if stmt, _ := statement(t, rec, "DELETE"); strings.Contains(stmt, "deleted_at") {
+		t.Errorf("HardDelete is scoped to live rows: %s", stmt)
+	}
+
+	rec.Reset()
+	if err := r.Restore(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if stmt, _ := statement(t, rec, "UPDATE"); !strings.Contains(stmt, "deleted_at IS NOT NULL") {
+		t.Errorf("Restore does not target deleted rows: %s", stmt)
+	}
+
+	rec.Reset()
+	respond(rec, 0)
+	if err := r.Delete(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete of a missing row = %v, want ErrNotFound", err)
+	}
+}
+
+func TestListOffset(t *testing.T) {
+	r, rec := newTestRepository[widget](t)
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rec.Respond = func(stmt string) sqltest.Response {
+		if strings.HasPrefix(stmt, "SELECT count(*)") {
+			return sqltest.Response{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(5)}}}
+		}
+		return sqltest.Response{Columns: widgetColumns, Rows: [][]driver.Value{
+			widgetRow(3, "c", created, 1), widgetRow(4, "d", created, 1),
+		}}
+	}
+
+	page, err := r.List(context.Background(), Query{
+		Filters: []Filter{{Column: "status", Op: OpEq, Value: "active"}},
+		Sort:    []Sort{{Column: "created_at", Desc: true}},
+		Limit:   2,
+		Page:    2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 5 || page.Page != 2 || page.Limit != 2 || len(page.Items) != 2 || page.Items[0].ID != 3 {
+		t.Errorf("page = %+v", page)
+	}
+
+	stmt, args := statement(t, rec, "SELECT *")
+	for _, want := range []string{`status = $1`, `"widgets"."deleted_at" IS NULL`, `ORDER BY "created_at" DESC,"id"`, "LIMIT $2 OFFSET $3"} {
+		if !strings.Contains(stmt, want) {
+			t.Errorf("%s does not contain %s", stmt, want)
+		}
+	}
+	if len(args) != 3 || args[0] != "active" || args[1] != 2 || args[2] != 2 {
+		t.Errorf("args = %v", args)
+	}
+	if count, _ := statement(t, rec, "SELECT count(*)"); strings.Contains(count, "ORDER BY") || strings.Contains(count, "LIMIT") {
+		t.Errorf("count is paged: %s", count)
+	}
+}
+
+func TestListCursor(t *testing.T) {
+	r, rec := newTestRepository[widget](t)
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	respond(rec, 0, widgetRow(1, "a", created, 1), widgetRow(2, "b", created, 1), widgetRow(3, "c", created, 1))
+	q := Query{Sort: []Sort{{Column: "name", Desc: true}}, Limit: 2}
+
+	page, err := r.ListCursor(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !page.HasMore || len(page.Items) != 2 || page.Next == "" {
+		t.Fatalf("page = %+v, want 2 items and a next cursor", page)
+	}
+	stmt, _ := statement(t, rec, "SELECT")
+	if !strings.Contains(stmt, `ORDER BY "name" DESC,"id"`) || !strings.Contains(stmt, "LIMIT $1") {
+		t.Errorf("first page: %s", stmt)
+	}
+
+	rec.Reset()
+	respond(rec, 0, widgetRow(3, "c", created, 1))
+	q.Cursor = page.Next
+	page, err = r.ListCursor(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.HasMore || page.Next != "" || len(page.Items) != 1 {
+		t.Errorf("last page = %+v", page)
+	}
+	stmt, args := statement(t, rec, "SELECT")
+	if !strings.Contains(stmt, "((name < $1) OR (name = $2 AND id > $3))") {
+		t.Errorf("second page: %s", stmt)
+	}
+	if len(args) < 3 || args[0] != "b" || args[1] != "b" || args[2] != int64(2) {
+		t.Errorf("cursor args = %v, want the last item of the first page", args)
+	}
+
+	q.Cursor = "not a cursor"
+	if _, err := r.ListCursor(context.Background(), q); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("bad cursor: %v, want ErrInvalidQuery", err)
+	}
+}
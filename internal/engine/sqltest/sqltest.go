@@ -0,0 +1,205 @@
+// Package sqltest provides a recording database/sql driver for tests that exercise transaction
+// control flow (BEGIN, savepoints, COMMIT/ROLLBACK) without a Postgres server.
+//
+// Every statement, including transaction control, is appended to the Recorder. Queries return
+// no rows and writes affect none unless Recorder.Respond says otherwise; nothing is stored, so
+// it is not a substitute for the DSN-gated tests that check what the database actually does.
+package sqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+const driverName = "skyrix-sqltest"
+
+var (
+	registerOnce sync.Once
+	recorders    sync.Map // dsn -> *Recorder
+	seq          atomic.Int64
+)
+
+// Recorder holds the statements run through one Open'd database.
+type Recorder struct {
+	// Fail, when set, is called for every statement; a non-nil error fails it.
+	Fail func(stmt string) error
+	// Respond, when set, supplies the result of every query and write that did not fail.
+	Respond func(stmt string) Response
+
+	mu    sync.Mutex
+	stmts []string
+	args  [][]any
+}
+
+// Statements returns the statements run so far, in order.
+func (r *Recorder) Statements() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.stmts...)
+}
+
+// Args returns the arguments of each statement run so far, in the order of Statements.
+func (r *Recorder) Args() [][]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]any(nil), r.args...)
+}
+
+// Reset forgets the statements run so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.stmts, r.args = nil, nil
+	r.mu.Unlock()
+}
+
+// Response is the canned result of one statement: the rows a query returns, or the number of
+// rows a write affected.
+type Response struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	RowsAffected int64
+}
+
+func (r *Recorder) respond(stmt string) Response {
+	r.mu.Lock()
+	respond := r.Respond
+	r.mu.Unlock()
+	if respond == nil {
+		return Response{}
+	}
+	return respond(stmt)
+}
+
+func (r *Recorder) record(stmt string, args []driver.NamedValue) error {
+	var values []any
+	for _, a := range args {
+		values = append(values, a.Value)
+	}
+	r.mu.Lock()
+	r.stmts = append(r.stmts, stmt)
+	r.args = append(r.args, values)
+	fail := r.Fail
+	r.mu.Unlock()
+	if fail != nil {
+		return fail(stmt)
+	}
+	return nil
+}
+
+// Open returns a GORM Postgres session backed by a fresh Recorder. The pool is closed when
+// the test ends.
+func Open(t testing.TB) (*gorm.DB, *Recorder) {
+	t.Helper()
+	registerOnce.Do(func() { sql.Register(driverName, fakeDriver{}) })
+
+	dsn := "sqltest-" + strconv.FormatInt(seq.Add(1), 10)
+	rec := &Recorder{}
+	recorders.Store(dsn, rec)
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DriverName: driverName, DSN: dsn}), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 gormLogger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+		recorders.Delete(dsn)
+	})
+	return db, rec
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	rec, ok := recorders.Load(dsn)
+	if !ok {
+		return nil, errors.New("sqltest: unknown database " + dsn)
+	}
+	return &conn{rec: rec.(*Recorder)}, nil
+}
+
+type conn struct {
+	rec *Recorder
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("sqltest: prepared statements are not supported")
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	stmt := "BEGIN"
+	if opts.ReadOnly {
+		stmt += " READ ONLY"
+	}
+	if err := c.rec.record(stmt, nil); err != nil {
+		return nil, err
+	}
+	return tx{rec: c.rec}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	query = strings.TrimSpace(query)
+	if err := c.rec.record(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(c.rec.respond(query).RowsAffected), nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	query = strings.TrimSpace(query)
+	if err := c.rec.record(query, args); err != nil {
+		return nil, err
+	}
+	res := c.rec.respond(query)
+	return &rows{columns: res.Columns, rows: res.Rows}, nil
+}
+
+// CheckNamedValue accepts any argument; the values are recorded, never interpreted.
+func (c *conn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+type tx struct {
+	rec *Recorder
+}
+
+func (t tx) Commit() error   { return t.rec.record("COMMIT", nil) }
+func (t tx) Rollback() error { return t.rec.record("ROLLBACK", nil) }
+
+type rows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
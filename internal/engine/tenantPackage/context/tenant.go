@@ -1,12 +1,17 @@
 package context
 
-import "context"
+import (
+	"context"
+	"strconv"
+)
 
 type ctxKey string
 
 const (
 	ctxSchemaKey ctxKey = "tenant_schema"
 	ctxByKey     ctxKey = "tenant_resolved_by"
+	ctxIDKey     ctxKey = "tenant_id"
+	ctxDBKey     ctxKey = "tenant_database"
 )
 
 func WithSchema(ctx context.Context, schema string) context.Context {
@@ -34,3 +39,39 @@ func ResolvedByFrom(ctx context.Context) string {
 	}
 	return ""
 }
+
+// WithTenantID marks ctx as a shared-schema tenant: its rows are filtered by tenant_id = id.
+func WithTenantID(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, ctxIDKey, id)
+}
+
+// TenantIDFrom returns the shared-schema tenant id; ok is false for schema-per-tenant and main.
+func TenantIDFrom(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(ctxIDKey).(int64)
+	return id, ok && id > 0
+}
+
+// WithDatabase routes ctx to a tenant's dedicated database (ref is a DSN or secret reference,
+// see entity.Tenant.Database). "" routes back to the main database.
+func WithDatabase(ctx context.Context, ref string) context.Context {
+	return context.WithValue(ctx, ctxDBKey, ref)
+}
+
+// DatabaseFrom returns the dedicated database reference of ctx, or "" for the main database.
+func DatabaseFrom(ctx context.Context) string {
+	s, _ := ctx.Value(ctxDBKey).(string)
+	return s
+}
+
+// Key identifies the tenant of ctx for keys and locks: "main", the schema, or "schema#id"
+// for shared-schema tenants (who share one schema).
+func Key(ctx context.Context) string {
+	schema := SchemaFrom(ctx)
+	if schema == "" {
+		return "main"
+	}
+	if id, ok := TenantIDFrom(ctx); ok {
+		return schema + "#" + strconv.FormatInt(id, 10)
+	}
+	return schema
+}
@@ -1,21 +1,57 @@
 package entity
 
 import (
+	"skyrix/internal/engine/repository"
 	"skyrix/internal/kernel/db/scope"
+	"strings"
 	"time"
 )
 
+// Isolation modes of a tenant.
+const (
+	// IsolationSchema gives the tenant its own schema (Schema).
+	IsolationSchema = "schema"
+	// IsolationShared puts the tenant's rows in a schema shared with other tenants (Schema),
+	// separated by tenant_id (row filter plugin + Postgres RLS on app.tenant_id).
+	IsolationShared = "shared"
+	// IsolationDatabase gives the tenant a dedicated database (Database); Schema is the
+	// tenant schema inside it.
+	IsolationDatabase = "database"
+)
+
 // Tenant lives in MAIN schema (core).
 type Tenant struct {
 	scope.MainModel
+	repository.SoftDelete
 
 	ID        int64      `gorm:"column:id;primaryKey"`
 	Namespace string     `gorm:"column:tenant;type:text;not null;index:ux_tenant_alive,unique,where:deleted_at IS NULL"`
-	Schema    *string    `gorm:"column:schema;type:text;default:null;index:uniq_subscriber_schema_nz,unique,where:deleted_at IS NULL"`
+	Schema    *string    `gorm:"column:schema;type:text;default:null;index:uniq_subscriber_schema_nz,unique,where:deleted_at IS NULL AND isolation <> 'shared'"`
 	Domain    *string    `gorm:"column:domain;type:text;default:null;index:uniq_subscriber_domain_nz,unique,where:deleted_at IS NULL"`
+	Isolation string     `gorm:"column:isolation;type:text;not null;default:'schema'"`
+	Database  *string    `gorm:"column:database;type:text;default:null"` // IsolationDatabase: "env:NAME" or "file:/path", never a DSN
 	IsActive  bool       `gorm:"column:is_active"`
 	ActiveTo  *time.Time `gorm:"column:active_to;index"`
 	UpdatedAt time.Time  `gorm:"column:updated_at"`
 }
 
 func (Tenant) TableName() string { return "tenants" }
+
+// IsShared reports whether the tenant uses the shared-schema (row-level) mode.
+func (t *Tenant) IsShared() bool { return t != nil && t.Isolation == IsolationShared }
+
+// IsSecretRef reports whether ref points at a DSN ("env:NAME" or "file:/path") instead of
+// being one. Tenants are cached in Redis, so Database must never hold credentials itself.
+func IsSecretRef(ref string) bool {
+	ref = strings.TrimSpace(ref)
+	return strings.HasPrefix(ref, "env:") || strings.HasPrefix(ref, "file:")
+}
+
+// DatabaseRef returns the dedicated database reference, or "" when the tenant has none or
+// Database is not a secret reference.
+func (t *Tenant) DatabaseRef() string {
+	if t == nil || t.Isolation != IsolationDatabase || t.Database == nil || !IsSecretRef(*t.Database) {
+		return ""
+	}
+	return strings.TrimSpace(*t.Database)
+}
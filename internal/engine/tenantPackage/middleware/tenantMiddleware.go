@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"skyrix/internal/engine"
+	"skyrix/internal/engine/metrics"
 	"skyrix/internal/logger"
 )
 
@@ -23,12 +24,14 @@ func NewTenantMiddleware(log logger.Interface, db *engine.Database, resolver *sc
 
 func (m *TenantMiddleware) Handle(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		schema, by, err := m.Resolver.ResolveSchema(r)
+		res, err := m.Resolver.Resolve(r)
+		schema, by := res.Schema, res.By
 		if err != nil {
 			// fallback to main schema on "soft" errors
 			if errors.Is(err, schemaResolver.ErrTenantHeaderMissing) || errors.Is(err, schemaResolver.ErrHostEmpty) || errors.Is(err, schemaResolver.ErrTenantNotFoundHost) {
 				schema = m.DB.MainSchema
 				by = "default"
+				res = schemaResolver.Resolution{}
 			} else {
 				schemaResolver.HTTPError(w, err)
 				return
@@ -53,6 +56,19 @@ func (m *TenantMiddleware) Handle(next http.Handler) http.Handler {
 		ctx := r.Context()
 		ctx = context.WithSchema(ctx, schema)
 		ctx = context.WithResolvedBy(ctx, by)
+		if res.TenantID > 0 {
+			ctx = context.WithTenantID(ctx, res.TenantID)
+		}
+		if res.Database != "" {
+			ctx = context.WithDatabase(ctx, res.Database)
+		}
+
+		metrics.TagTenant(ctx)
+
+		// Tenant queries of this request share one pinned connection; it is reset and
+		// returned to the pool when the request ends.
+		ctx, release := engine.WithConnScope(ctx)
+		defer release()
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -2,6 +2,7 @@ package tenantPackage
 
 import (
 	"skyrix/internal/config"
+	"skyrix/internal/engine/outbox"
 	"skyrix/internal/engine/tenantPackage/repository"
 	"skyrix/internal/engine/tenantPackage/schemaResolver"
 	"skyrix/internal/engine/tenantPackage/service"
@@ -17,6 +18,8 @@ var ProviderSet = wire.NewSet(
 // CoreSet repo/service/resolver/options. No HTTP/router here.
 var CoreSet = wire.NewSet(
 	repository.NewTenantRepository,
+	// the outbox relay visits every dedicated tenant database listed in the tenants table
+	wire.Bind(new(outbox.TenantDatabases), new(*repository.TenantRepository)),
 
 	ProvideTenantCacheOpts,
 	ProvideTenantHeader,
@@ -36,8 +39,7 @@ var MiddlewareSet = wire.NewSet(
 
 func ProvideTenantCacheOpts(cfg *config.Config) service.CacheOpts {
 	return service.CacheOpts{
-		TTL:       cfg.TenantCache.TTL,
-		KeyPrefix: cfg.TenantCache.KeyPrefix,
+		TTL: cfg.TenantCache.TTL,
 	}
 }
 
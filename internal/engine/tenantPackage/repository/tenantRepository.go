@@ -2,17 +2,20 @@ package repository
 
 import (
 	"context"
+	"strings"
 
 	"skyrix/internal/engine"
+	base "skyrix/internal/engine/repository"
 	"skyrix/internal/engine/tenantPackage/entity"
 )
 
 type TenantRepository struct {
+	*base.Repository[entity.Tenant]
 	DB *engine.Database
 }
 
 func NewTenantRepository(db *engine.Database) *TenantRepository {
-	return &TenantRepository{DB: db}
+	return &TenantRepository{Repository: base.New[entity.Tenant](db), DB: db}
 }
 
 func (r *TenantRepository) GetByNamespace(ctx context.Context, ns string) (*entity.Tenant, error) {
@@ -48,3 +51,36 @@ func (r *TenantRepository) ListDomains(ctx context.Context) ([]string, error) {
 		Scan(&out).Error
 	return out, err
 }
+
+// ListActive returns all active tenants that have a schema assigned, ordered by id.
+// Used by job runners that fan out across tenant schemas.
+func (r *TenantRepository) ListActive(ctx context.Context) ([]entity.Tenant, error) {
+	var out []entity.Tenant
+	err := r.DB.WithContext(ctx).
+		Where("is_active = true AND schema IS NOT NULL AND schema <> ''").
+		Order("id").
+		Find(&out).Error
+	return out, err
+}
+
+// ListDatabases returns the distinct references of active tenants with a dedicated database
+// (for workers that visit every database, like the outbox relay). Inline DSNs are skipped.
+func (r *TenantRepository) ListDatabases(ctx context.Context) ([]string, error) {
+	var refs []string
+	err := r.DB.WithContext(ctx).
+		Model(&entity.Tenant{}).
+		Distinct("database").
+		Where("is_active = true AND isolation = ? AND database IS NOT NULL", entity.IsolationDatabase).
+		Order("database").
+		Scan(&refs).Error
+	if err != nil {
+		return nil, err
+	}
+	out := refs[:0]
+	for _, ref := range refs {
+		if entity.IsSecretRef(ref) {
+			out = append(out, strings.TrimSpace(ref))
+		}
+	}
+	return out, nil
+}
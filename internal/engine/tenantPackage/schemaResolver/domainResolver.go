@@ -3,7 +3,6 @@ package schemaResolver
 import (
 	"net/http"
 	"skyrix/internal/engine/tenantPackage/service"
-	"strings"
 )
 
 type DomainResolver struct {
@@ -14,23 +13,16 @@ func NewDomainResolver(svc *service.TenantService) *DomainResolver {
 	return &DomainResolver{svc: svc}
 }
 
-func (r *DomainResolver) ResolveSchema(req *http.Request) (string, string, error) {
+func (r *DomainResolver) Resolve(req *http.Request) (Resolution, error) {
 	host := hostFromRequest(req)
 	if host == "" {
-		return "", "", ErrHostEmpty
+		return Resolution{}, ErrHostEmpty
 	}
 
 	t, err := r.svc.GetByDomain(req.Context(), host)
 	if err != nil {
-		return "", "", ErrTenantNotFoundHost
+		return Resolution{}, ErrTenantNotFoundHost
 	}
 
-	if t.Schema == nil {
-		return "", "", ErrSchemaInvalid
-	}
-	schema := strings.ToLower(strings.TrimSpace(*t.Schema))
-	if !ReIdent.MatchString(schema) {
-		return "", "", ErrSchemaInvalid
-	}
-	return schema, "domain", nil
+	return ResolutionOf(t, "domain")
 }
@@ -10,5 +10,6 @@ var (
 	ErrHostEmpty          = errors.New("empty host")
 	ErrTenantNotFoundHost = errors.New("tenant not found by domain")
 
-	ErrSchemaInvalid = errors.New("invalid schema")
+	ErrSchemaInvalid   = errors.New("invalid schema")
+	ErrDatabaseInvalid = errors.New("tenant database is not configured")
 )
@@ -19,26 +19,19 @@ func NewHeaderResolver(svc *service.TenantService, header string) *HeaderResolve
 	return &HeaderResolver{header: h, svc: svc}
 }
 
-func (r *HeaderResolver) ResolveSchema(req *http.Request) (string, string, error) {
+func (r *HeaderResolver) Resolve(req *http.Request) (Resolution, error) {
 	tenant := strings.TrimSpace(req.Header.Get(r.header))
 	if tenant == "" {
-		return "", "", ErrTenantHeaderMissing
+		return Resolution{}, ErrTenantHeaderMissing
 	}
 	if !ReIdent.MatchString(tenant) {
-		return "", "", ErrTenantInvalid
+		return Resolution{}, ErrTenantInvalid
 	}
 
 	t, err := r.svc.GetByNamespace(req.Context(), tenant)
 	if err != nil {
-		return "", "", ErrTenantNotFound
+		return Resolution{}, ErrTenantNotFound
 	}
 
-	if t.Schema == nil {
-		return "", "", ErrSchemaInvalid
-	}
-	schema := strings.ToLower(strings.TrimSpace(*t.Schema))
-	if !ReIdent.MatchString(schema) {
-		return "", "", ErrSchemaInvalid
-	}
-	return schema, "header", nil
+	return ResolutionOf(t, "header")
 }
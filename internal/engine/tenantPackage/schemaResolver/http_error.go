@@ -34,6 +34,8 @@ func HTTPError(w http.ResponseWriter, err error) {
 		writeJSON(w, http.StatusBadRequest, "HOST_EMPTY", "Empty host")
 	case errors.Is(err, ErrSchemaInvalid):
 		writeJSON(w, http.StatusInternalServerError, "SCHEMA_INVALID", "Invalid database schema")
+	case errors.Is(err, ErrDatabaseInvalid):
+		writeJSON(w, http.StatusInternalServerError, "DATABASE_INVALID", "Tenant database is not configured")
 	default:
 		writeJSON(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal error")
 	}
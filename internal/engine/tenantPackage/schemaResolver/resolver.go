@@ -3,19 +3,29 @@ package schemaResolver
 import (
 	"errors"
 	"net/http"
+	"skyrix/internal/engine/metrics"
 	"skyrix/internal/engine/tenantPackage/service"
 )
 
+// Resolution is a resolved tenant.
+type Resolution struct {
+	Schema   string
+	TenantID int64  // shared-schema tenants only; 0 for schema-per-tenant
+	Database string // dedicated database reference; "" for the main database
+	By       string
+}
+
 type Resolver interface {
-	ResolveSchema(req *http.Request) (schema string, resolvedBy string, err error)
+	Resolve(req *http.Request) (Resolution, error)
 }
 
 type SchemaResolver struct {
-	order []string
-	reg   map[string]Resolver
+	order   []string
+	reg     map[string]Resolver
+	metrics *metrics.Metrics
 }
 
-func NewSchemaResolver(svc *service.TenantService, header string, order []string) *SchemaResolver {
+func NewSchemaResolver(svc *service.TenantService, header string, order []string, m *metrics.Metrics) *SchemaResolver {
 	reg := map[string]Resolver{
 		"header": NewHeaderResolver(svc, header),
 		"domain": NewDomainResolver(svc),
@@ -23,30 +33,63 @@ func NewSchemaResolver(svc *service.TenantService, header string, order []string
 	if len(order) == 0 {
 		order = []string{"header", "domain"}
 	}
-	return &SchemaResolver{order: order, reg: reg}
+	return &SchemaResolver{order: order, reg: reg, metrics: m}
 }
 
-func (s *SchemaResolver) ResolveSchema(req *http.Request) (string, string, error) {
+// Resolve tries the resolvers in order; soft errors fall through to the next one.
+// Outcomes are counted in tenant_resolutions_total.
+func (s *SchemaResolver) Resolve(req *http.Request) (Resolution, error) {
+	res, err := s.resolve(req)
+	if s.metrics != nil {
+		resolver := res.By
+		if resolver == "" {
+			resolver = "none"
+		}
+		s.metrics.TenantResolutions.With(resolver, resolutionOutcome(err)).Inc()
+	}
+	return res, err
+}
+
+func (s *SchemaResolver) resolve(req *http.Request) (Resolution, error) {
 	var last error
 	for _, name := range s.order {
 		r := s.reg[name]
 		if r == nil {
 			continue
 		}
-		schema, by, err := r.ResolveSchema(req)
+		res, err := r.Resolve(req)
 		if err == nil {
-			return schema, by, nil
+			return res, nil
 		}
 		if errorsIsSoft(err) {
 			last = err
 			continue
 		}
-		return "", "", err
+		return Resolution{}, err
 	}
 	if last == nil {
 		last = ErrTenantNotFound
 	}
-	return "", "", last
+	return Resolution{}, last
+}
+
+// ResolveSchema is Resolve reduced to the schema and the resolver name.
+func (s *SchemaResolver) ResolveSchema(req *http.Request) (string, string, error) {
+	res, err := s.Resolve(req)
+	return res.Schema, res.By, err
+}
+
+func resolutionOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "resolved"
+	case errors.Is(err, ErrTenantHeaderMissing) || errors.Is(err, ErrHostEmpty):
+		return "missing"
+	case errors.Is(err, ErrTenantNotFound) || errors.Is(err, ErrTenantNotFoundHost):
+		return "not_found"
+	default:
+		return "error"
+	}
 }
 
 func errorsIsSoft(err error) bool {
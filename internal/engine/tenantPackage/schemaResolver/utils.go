@@ -4,6 +4,7 @@ import (
 	"net"
 	"net/http"
 	"regexp"
+	"skyrix/internal/engine/tenantPackage/entity"
 	"strings"
 )
 
@@ -23,3 +24,26 @@ func hostFromRequest(req *http.Request) string {
 	}
 	return strings.ToLower(h)
 }
+
+// ResolutionOf validates t's schema and builds its Resolution. A database-isolated tenant
+// without a usable database reference fails with ErrDatabaseInvalid rather than falling
+// back to the main database.
+func ResolutionOf(t *entity.Tenant, by string) (Resolution, error) {
+	if t.Schema == nil {
+		return Resolution{}, ErrSchemaInvalid
+	}
+	schema := strings.ToLower(strings.TrimSpace(*t.Schema))
+	if !ReIdent.MatchString(schema) {
+		return Resolution{}, ErrSchemaInvalid
+	}
+	res := Resolution{Schema: schema, By: by}
+	if t.IsShared() {
+		res.TenantID = t.ID
+	}
+	if t.Isolation == entity.IsolationDatabase {
+		if res.Database = t.DatabaseRef(); res.Database == "" {
+			return Resolution{}, ErrDatabaseInvalid
+		}
+	}
+	return res, nil
+}
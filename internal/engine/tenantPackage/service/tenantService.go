@@ -2,10 +2,9 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"skyrix/internal/engine"
+	"skyrix/internal/engine/cache"
 	"skyrix/internal/engine/tenantPackage/entity"
 	"skyrix/internal/engine/tenantPackage/repository"
 	"skyrix/internal/logger"
@@ -17,23 +16,24 @@ import (
 type TenantService struct {
 	Log         logger.Interface
 	Repo        *repository.TenantRepository
-	Cache       engine.Cache
+	Cache       *cache.TenantCache
 	ttl         time.Duration
-	KeyPrefix   string
+	l2          *cache.TypedCache[entity.Tenant]
 	mu          sync.RWMutex
 	byNamespace map[string]*entity.Tenant
 	byDomain    map[string]*entity.Tenant
 }
 
 type CacheOpts struct {
-	TTL       time.Duration
-	KeyPrefix string // needed to form keys
+	TTL time.Duration
 }
 
+// NewTenantService caches tenants in the global scope of c: tenant rows live in the main
+// schema and are looked up before any tenant is known.
 func NewTenantService(
 	log logger.Interface,
 	repo *repository.TenantRepository,
-	cache engine.Cache,
+	c *cache.TenantCache,
 	opts CacheOpts,
 ) *TenantService {
 	ttl := opts.TTL
@@ -41,32 +41,26 @@ func NewTenantService(
 		ttl = 3 * time.Minute
 	}
 
-	prefix := strings.TrimSuffix(strings.TrimSpace(opts.KeyPrefix), ":")
-	if prefix == "" {
-		prefix = "skyrix-delivery"
-	}
-
-	return &TenantService{
+	s := &TenantService{
 		Log:         log,
 		Repo:        repo,
-		Cache:       cache,
+		Cache:       c,
 		ttl:         ttl,
-		KeyPrefix:   prefix,
 		byNamespace: make(map[string]*entity.Tenant),
 		byDomain:    make(map[string]*entity.Tenant),
 	}
+	if c != nil {
+		s.l2 = cache.NewTyped[entity.Tenant](c.Global(), cache.Options{Namespace: "tenant", TTL: ttl})
+	}
+	return s
 }
 
 var ErrNotFound = errors.New("tenant not found")
 
 func norm(s string) string { return strings.ToLower(strings.TrimSpace(s)) }
 
-func (s *TenantService) redisKeyNamespace(namespace string) string {
-	return s.KeyPrefix + ":tenant:namespace:" + norm(namespace)
-}
-func (s *TenantService) redisKeyDomain(d string) string {
-	return s.KeyPrefix + ":tenant:domain:" + norm(d)
-}
+// tenantTag tags every cache entry of a tenant, whichever key it was loaded by.
+func tenantTag(namespace string) string { return "tenant:" + norm(namespace) }
 
 func (s *TenantService) isActive(t *entity.Tenant) bool {
 	return t != nil && t.IsActive
@@ -93,21 +87,6 @@ func (s *TenantService) nsVal(t *entity.Tenant) string {
 	return norm(t.Namespace)
 }
 
-func encodeTenant(t *entity.Tenant) ([]byte, error) {
-	return json.Marshal(t)
-}
-
-func decodeTenant(b []byte) (*entity.Tenant, error) {
-	if len(b) == 0 {
-		return nil, nil
-	}
-	var t entity.Tenant
-	if err := json.Unmarshal(b, &t); err != nil {
-		return nil, err
-	}
-	return &t, nil
-}
-
 // updateL1Cache populates the in-memory cache with the given tenant.
 func (s *TenantService) updateL1Cache(t *entity.Tenant) {
 	if t == nil {
@@ -124,28 +103,44 @@ func (s *TenantService) updateL1Cache(t *entity.Tenant) {
 	}
 }
 
-// updateL2Cache populates the Redis cache with the given tenant.
-func (s *TenantService) updateL2Cache(ctx context.Context, t *entity.Tenant) {
-	if s.Cache == nil || t == nil {
-		return
+// sanitize clears a Database value that is not a secret reference. Credentials are never
+// cached or handed out; the tenant then fails to resolve its database.
+func (s *TenantService) sanitize(t *entity.Tenant) {
+	if t.Database != nil && !entity.IsSecretRef(*t.Database) {
+		s.Log.Error("tenant database is not a secret reference", "tenant", t.Namespace)
+		t.Database = nil
 	}
+}
 
-	data, err := encodeTenant(t)
-	if err != nil {
-		s.Log.Error("failed to encode tenant for cache", "namespace", s.nsVal(t), "error", err)
-		return
+// load reads a usable tenant through the Redis cache (single-flight per key), falling back to fetch.
+func (s *TenantService) load(ctx context.Context, key string, fetch func(ctx context.Context) (*entity.Tenant, error)) (*entity.Tenant, error) {
+	loader := func(ctx context.Context) (entity.Tenant, error) {
+		t, err := fetch(ctx)
+		if err != nil || !s.isActive(t) || s.schemaVal(t) == "" {
+			return entity.Tenant{}, ErrNotFound
+		}
+		s.sanitize(t)
+		return *t, nil
 	}
 
-	if ns := s.nsVal(t); ns != "" {
-		if err := s.Cache.Set(ctx, s.redisKeyNamespace(ns), data, s.ttl); err != nil {
-			s.Log.Warn("failed to set tenant in cache by namespace", "namespace", ns, "error", err)
-		}
+	var t entity.Tenant
+	var err error
+	if s.l2 == nil {
+		t, err = loader(ctx)
+	} else {
+		// Tagged with the namespace, so InvalidateTenant drops the namespace and domain entries.
+		t, err = s.l2.GetOrLoad(ctx, key, loader, cache.WithTagsFrom(func(t entity.Tenant) []string {
+			return []string{tenantTag(t.Namespace)}
+		}))
 	}
-	if d := s.domainVal(t); d != "" {
-		if err := s.Cache.Set(ctx, s.redisKeyDomain(d), data, s.ttl); err != nil {
-			s.Log.Warn("failed to set tenant in cache by domain", "domain", d, "error", err)
-		}
+	if err != nil {
+		return nil, err
+	}
+	if !s.isActive(&t) || s.schemaVal(&t) == "" {
+		return nil, ErrNotFound
 	}
+	s.updateL1Cache(&t)
+	return &t, nil
 }
 
 func (s *TenantService) GetByNamespace(ctx context.Context, namespace string) (*entity.Tenant, error) {
@@ -162,33 +157,10 @@ func (s *TenantService) GetByNamespace(ctx context.Context, namespace string) (*
 	}
 	s.mu.RUnlock()
 
-	// Redis
-	if s.Cache != nil {
-		key := s.redisKeyNamespace(namespace)
-		if b, ok, err := s.Cache.Get(ctx, key); err == nil && ok {
-			if t, err := decodeTenant(b); err == nil && s.isActive(t) && s.schemaVal(t) != "" {
-				s.updateL1Cache(t)
-				return t, nil
-			} else if err != nil {
-				s.Log.Warn("failed to decode tenant from cache", "key", key, "error", err)
-			}
-		}
-	}
-
-	// DB
-	t, err := s.Repo.GetByNamespace(ctx, namespace)
-	if err != nil {
-		return nil, ErrNotFound
-	}
-	if !s.isActive(t) || s.schemaVal(t) == "" {
-		return nil, ErrNotFound
-	}
-
-	// store caches
-	s.updateL1Cache(t)
-	s.updateL2Cache(ctx, t)
-
-	return t, nil
+	// Redis, then DB
+	return s.load(ctx, "namespace:"+namespace, func(ctx context.Context) (*entity.Tenant, error) {
+		return s.Repo.GetByNamespace(ctx, namespace)
+	})
 }
 
 func (s *TenantService) GetByDomain(ctx context.Context, domain string) (*entity.Tenant, error) {
@@ -205,33 +177,43 @@ func (s *TenantService) GetByDomain(ctx context.Context, domain string) (*entity
 	}
 	s.mu.RUnlock()
 
-	// Redis
-	if s.Cache != nil {
-		key := s.redisKeyDomain(domain)
-		if b, ok, err := s.Cache.Get(ctx, key); err == nil && ok {
-			if t, err := decodeTenant(b); err == nil && s.isActive(t) && s.schemaVal(t) != "" {
-				s.updateL1Cache(t)
-				return t, nil
-			} else if err != nil {
-				s.Log.Warn("failed to decode tenant from cache", "key", key, "error", err)
-			}
-		}
+	// Redis, then DB
+	return s.load(ctx, "domain:"+domain, func(ctx context.Context) (*entity.Tenant, error) {
+		return s.Repo.GetByDomain(ctx, domain)
+	})
+}
+
+// InvalidateTenant drops a tenant from the in-memory cache and, through its tag, every Redis
+// entry of it (by namespace and by domain). Other instances keep their in-memory copy until restart.
+func (s *TenantService) InvalidateTenant(ctx context.Context, namespace string) error {
+	namespace = norm(namespace)
+	s.mu.Lock()
+	if t, ok := s.byNamespace[namespace]; ok {
+		delete(s.byDomain, s.domainVal(t))
 	}
+	delete(s.byNamespace, namespace)
+	s.mu.Unlock()
 
-	// DB
-	t, err := s.Repo.GetByDomain(ctx, domain)
+	if s.l2 == nil {
+		return nil
+	}
+	return s.l2.InvalidateTags(ctx, tenantTag(namespace))
+}
+
+// ListActive returns all active tenants straight from the database, sanitized like cached
+// lookups. The tenants table lives in the main schema.
+func (s *TenantService) ListActive(ctx context.Context) ([]entity.Tenant, error) {
+	if s.Repo == nil {
+		return nil, fmt.Errorf("repo is nil")
+	}
+	tenants, err := s.Repo.ListActive(ctx)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, err
 	}
-	if !s.isActive(t) || s.schemaVal(t) == "" {
-		return nil, ErrNotFound
+	for i := range tenants {
+		s.sanitize(&tenants[i])
 	}
-
-	// store caches
-	s.updateL1Cache(t)
-	s.updateL2Cache(ctx, t)
-
-	return t, nil
+	return tenants, nil
 }
 
 func (s *TenantService) ListDomains(ctx context.Context) ([]string, error) {
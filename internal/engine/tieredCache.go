@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"skyrix/internal/logger"
+)
+
+// TieredOpts configures a TieredCache.
+type TieredOpts struct {
+	// L1TTL caps how long a value is served from memory (default 30s). It bounds staleness
+	// when an invalidation message is missed.
+	L1TTL time.Duration
+	// Channel is the Redis pub/sub channel for invalidations (default "<key prefix>:cache:invalidate").
+	Channel string
+}
+
+// TieredCache layers an in-process MemoryCache (L1) in front of Redis (L2).
+//
+// Reads hit L1 first and fill it from L2. Writes go to L2, then L1, and are broadcast on a
+// Redis channel so other instances drop their L1 copy. SetNX is decided by L2 alone, so locks
+// and dedup keys stay correct across instances.
+type TieredCache struct {
+	l1      *MemoryCache
+	l2      *Redis
+	log     logger.Interface
+	l1TTL   time.Duration
+	channel string
+	id      []byte // instance id; own invalidations are ignored
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+var (
+	_ Cache          = (*TieredCache)(nil)
+	_ KeyScanner     = (*TieredCache)(nil)
+	_ CompareDeleter = (*TieredCache)(nil)
+	_ Expirer        = (*TieredCache)(nil)
+)
+
+func NewTieredCache(l1 *MemoryCache, l2 *Redis, log logger.Interface, opts TieredOpts) *TieredCache {
+	if opts.L1TTL <= 0 {
+		opts.L1TTL = 30 * time.Second
+	}
+	if opts.Channel == "" {
+		opts.Channel = l2.KeyPrefix() + ":cache:invalidate"
+	}
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &TieredCache{
+		l1:      l1,
+		l2:      l2,
+		log:     log,
+		l1TTL:   opts.L1TTL,
+		channel: opts.Channel,
+		id:      []byte(hex.EncodeToString(id[:])),
+		cancel:  cancel,
+	}
+	c.wg.Add(1)
+	go c.listen(ctx)
+	return c
+}
+
+func (c *TieredCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if b, ok, _ := c.l1.Get(ctx, key); ok {
+		return b, true, nil
+	}
+	b, ok, err := c.l2.Get(ctx, key)
+	if err != nil || !ok {
+		return b, ok, err
+	}
+	_ = c.l1.Set(ctx, key, b, c.l1TTL)
+	return b, true, nil
+}
+
+func (c *TieredCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	if err := c.l2.Set(ctx, key, val, ttl); err != nil {
+		_ = c.l1.Del(ctx, key)
+		return err
+	}
+	_ = c.l1.Set(ctx, key, val, c.l1TTLFor(ttl))
+	c.broadcast(ctx, key)
+	return nil
+}
+
+func (c *TieredCache) SetNX(ctx context.Context, key string, val []byte, ttl time.Duration) (bool, error) {
+	ok, err := c.l2.SetNX(ctx, key, val, ttl)
+	if err != nil || !ok {
+		_ = c.l1.Del(ctx, key)
+		return ok, err
+	}
+	_ = c.l1.Set(ctx, key, val, c.l1TTLFor(ttl))
+	c.broadcast(ctx, key)
+	return true, nil
+}
+
+func (c *TieredCache) Del(ctx context.Context, key string) error {
+	_ = c.l1.Del(ctx, key)
+	err := c.l2.Del(ctx, key)
+	c.broadcast(ctx, key)
+	return err
+}
+
+// DelIfValue compares and deletes in L2; a deleted key is dropped from every L1.
+func (c *TieredCache) DelIfValue(ctx context.Context, key string, val []byte) (bool, error) {
+	ok, err := c.l2.DelIfValue(ctx, key, val)
+	if err != nil || !ok {
+		return ok, err
+	}
+	_ = c.l1.Del(ctx, key)
+	c.broadcast(ctx, key)
+	return true, nil
+}
+
+// Expire changes the TTL in L2; L1 copies are capped by L1TTL anyway.
+func (c *TieredCache) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return c.l2.Expire(ctx, key, ttl)
+}
+
+func (c *TieredCache) Exists(ctx context.Context, key string) (bool, error) {
+	if ok, _ := c.l1.Exists(ctx, key); ok {
+		return true, nil
+	}
+	return c.l2.Exists(ctx, key)
+}
+
+// ScanKeys scans L2, which holds every key.
+func (c *TieredCache) ScanKeys(ctx context.Context, pattern string, count int, cursor uint64) ([]string, uint64, error) {
+	return c.l2.ScanKeys(ctx, pattern, count, cursor)
+}
+
+func (c *TieredCache) DelMany(ctx context.Context, keys []string) error {
+	_ = c.l1.DelMany(ctx, keys)
+	err := c.l2.DelMany(ctx, keys)
+	for _, key := range keys {
+		c.broadcast(ctx, key)
+	}
+	return err
+}
+
+// Stats returns the L1 statistics.
+func (c *TieredCache) Stats() CacheStats { return c.l1.Stats() }
+
+// Close stops listening for invalidations and the L1 sweep. Redis is closed by its owner.
+func (c *TieredCache) Close() error {
+	c.once.Do(c.cancel)
+	c.wg.Wait()
+	return c.l1.Close()
+}
+
+// l1TTLFor keeps L1 copies no longer than L1TTL nor the entry's own TTL.
+func (c *TieredCache) l1TTLFor(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < c.l1TTL {
+		return ttl
+	}
+	return c.l1TTL
+}
+
+// broadcast tells other instances to drop key from L1. Message: "<instance id> <key>".
+func (c *TieredCache) broadcast(ctx context.Context, key string) {
+	msg := make([]byte, 0, len(c.id)+1+len(key))
+	msg = append(append(append(msg, c.id...), ' '), key...)
+	if err := c.l2.Publish(ctx, c.channel, msg); err != nil && c.log != nil {
+		c.log.Warn("cache invalidation broadcast failed", "key", key, "error", err)
+	}
+}
+
+// listen drops keys invalidated by other instances. go-redis reconnects the subscription;
+// messages sent while disconnected are lost, which L1TTL bounds.
+func (c *TieredCache) listen(ctx context.Context) {
+	defer c.wg.Done()
+	sub := c.l2.Subscribe(ctx, c.channel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-ch:
+			if !ok {
+				return
+			}
+			id, key, found := bytes.Cut([]byte(m.Payload), []byte{' '})
+			if !found || bytes.Equal(id, c.id) {
+				continue
+			}
+			_ = c.l1.Del(ctx, string(key))
+		}
+	}
+}
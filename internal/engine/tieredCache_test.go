@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const testChannel = "test:cache:invalidate"
+
+// testTiered returns two TieredCaches (two instances) sharing one miniredis.
+func testTiered(t *testing.T) (*miniredis.Miniredis, *TieredCache, *TieredCache) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	newInstance := func() *TieredCache {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { _ = client.Close() })
+		l1 := NewMemoryCache(MemoryOpts{})
+		l2 := NewRedisService(client, testLogger(), RedisOpts{KeyPrefix: "test"})
+		c := NewTieredCache(l1, l2, testLogger(), TieredOpts{L1TTL: time.Minute, Channel: testChannel})
+		t.Cleanup(func() { _ = c.Close() })
+		return c
+	}
+	a, b := newInstance(), newInstance()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for mr.PubSubNumSub(testChannel)[testChannel] < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("instances did not subscribe to the invalidation channel")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return mr, a, b
+}
+
+func TestTieredCacheReadsThroughL1(t *testing.T) {
+	ctx := context.Background()
+	mr, a, _ := testTiered(t)
+
+	if err := a.Set(ctx, "k", []byte("v1"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := mr.Get("k"); got != "v1" {
+		t.Fatalf("L2 holds %q, want v1", got)
+	}
+	// Changed behind the cache's back: the L1 copy is still served.
+	mr.Set("k", "v2")
+	if got, ok, _ := a.Get(ctx, "k"); !ok || string(got) != "v1" {
+		t.Fatalf("Get = %q, %v; want the L1 copy v1", got, ok)
+	}
+}
+
+func TestTieredCacheInvalidatesOtherInstances(t *testing.T) {
+	ctx := context.Background()
+	_, a, b := testTiered(t)
+
+	_ = a.Set(ctx, "k", []byte("v1"), time.Hour)
+	if got, _, _ := b.Get(ctx, "k"); string(got) != "v1" { // fills b's L1
+		t.Fatalf("b read %q, want v1", got)
+	}
+
+	_ = a.Set(ctx, "k", []byte("v2"), time.Hour)
+	eventually(t, func() bool {
+		got, _, _ := b.Get(ctx, "k")
+		return string(got) == "v2"
+	}, "b kept its stale L1 copy after a write on a")
+
+	_ = a.Del(ctx, "k")
+	eventually(t, func() bool {
+		ok, _ := b.Exists(ctx, "k")
+		return !ok
+	}, "b kept its L1 copy after a delete on a")
+}
+
+func TestTieredCacheSetNXIsDecidedByL2(t *testing.T) {
+	ctx := context.Background()
+	_, a, b := testTiered(t)
+
+	if ok, _ := a.SetNX(ctx, "lock", []byte("a"), time.Minute); !ok {
+		t.Fatal("first SetNX failed")
+	}
+	if ok, _ := b.SetNX(ctx, "lock", []byte("b"), time.Minute); ok {
+		t.Fatal("second instance took a key held in L2")
+	}
+	if ok, _ := b.DelIfValue(ctx, "lock", []byte("b")); ok {
+		t.Fatal("DelIfValue deleted another instance's value")
+	}
+	if ok, _ := a.DelIfValue(ctx, "lock", []byte("a")); !ok {
+		t.Fatal("DelIfValue kept the owner's value")
+	}
+	if ok, _ := b.SetNX(ctx, "lock", []byte("b"), time.Minute); !ok {
+		t.Fatal("SetNX failed after release")
+	}
+}
+
+func eventually(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
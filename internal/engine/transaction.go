@@ -2,11 +2,90 @@ package engine
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
 	"skyrix/internal/logger"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
+// Propagation decides how Execute/Run behave when ctx already carries a transaction.
+type Propagation int
+
+const (
+	// Required joins the transaction in ctx through a savepoint, or starts a new one.
+	// An error inside the savepoint only rolls back the nested work.
+	Required Propagation = iota
+	// RequiresNew always starts an independent transaction on its own connection.
+	RequiresNew
+	// Never runs fn without a transaction and fails with ErrTxActive if one is in ctx.
+	Never
+)
+
+// ErrTxActive is returned by Propagation Never when ctx carries a transaction.
+var ErrTxActive = errors.New("transaction already active in context")
+
+const (
+	retryBaseDelay = 20 * time.Millisecond
+	retryMaxDelay  = time.Second
+)
+
+// TxOptions configures one Execute/Run call. Isolation, ReadOnly, StatementTimeout and
+// MaxAttempts only apply when a new transaction begins; a savepoint inherits its parent's.
+type TxOptions struct {
+	Propagation      Propagation
+	Isolation        sql.IsolationLevel
+	ReadOnly         bool
+	StatementTimeout time.Duration
+	// MaxAttempts bounds runs of fn when the transaction fails with a serialization
+	// failure (40001) or deadlock (40P01). 0 and 1 run fn once: retrying is opt-in.
+	MaxAttempts int
+}
+
+type TxOption func(*TxOptions)
+
+func WithPropagation(p Propagation) TxOption {
+	return func(o *TxOptions) { o.Propagation = p }
+}
+
+// WithIsolation sets the isolation level, e.g. sql.LevelSerializable.
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *TxOptions) { o.Isolation = level }
+}
+
+// ReadOnly starts the transaction READ ONLY.
+func ReadOnly() TxOption {
+	return func(o *TxOptions) { o.ReadOnly = true }
+}
+
+// WithStatementTimeout applies statement_timeout with SET LOCAL for the transaction.
+func WithStatementTimeout(d time.Duration) TxOption {
+	return func(o *TxOptions) { o.StatementTimeout = d }
+}
+
+// WithRetry lets fn run up to maxAttempts times when the transaction hits a retryable error.
+// Only opt in when fn is safe to re-run: everything it did in the database was rolled back,
+// but side effects outside it (HTTP calls, messages, counters in memory) happen again.
+func WithRetry(maxAttempts int) TxOption {
+	return func(o *TxOptions) { o.MaxAttempts = maxAttempts }
+}
+
+// IsRetryable reports whether err is a PostgreSQL serialization failure or deadlock.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	return false
+}
+
 type TxManager struct {
 	DB     *Database
 	Logger logger.Interface
@@ -21,12 +100,96 @@ func NewTxManager(db *Database, logger logger.Interface) TransactionManager {
 }
 
 // Execute wraps fn in a database transaction with rollback on error or panic.
-func (tm *TxManager) Execute(ctx context.Context, fn func(tx *gorm.DB) error) error {
-	tx := tm.DB.WithContext(ctx).Begin()
+// Callbacks registered via AfterCommit run once the outermost commit succeeded.
+func (tm *TxManager) Execute(ctx context.Context, fn func(tx *gorm.DB) error, opts ...TxOption) error {
+	return tm.Run(ctx, func(ctx context.Context) error {
+		return fn(tm.DB.WithContext(ctx))
+	}, opts...)
+}
+
+// Run wraps fn in a transaction according to the propagation option (Required by default).
+func (tm *TxManager) Run(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	var o TxOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+
+	cur := txFrom(ctx)
+	switch o.Propagation {
+	case Never:
+		if cur != nil {
+			return ErrTxActive
+		}
+		return fn(ctx)
+	case Required:
+		if cur != nil {
+			return tm.savepoint(ctx, cur, fn)
+		}
+	}
+	return tm.retry(ctx, o, fn)
+}
+
+// retry runs begin until it succeeds, fails with a non-retryable error or runs out of attempts.
+// Backoff is exponential with jitter, starting at retryBaseDelay and capped at retryMaxDelay.
+func (tm *TxManager) retry(ctx context.Context, o TxOptions, fn func(ctx context.Context) error) error {
+	attempts := max(o.MaxAttempts, 1)
+
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := tm.begin(ctx, o, fn)
+		if err == nil || !IsRetryable(err) || attempt >= attempts {
+			if err != nil && attempt > 1 && IsRetryable(err) {
+				tm.Logger.Error("Transaction retries exhausted", "attempts", attempt, "error", err)
+			}
+			return err
+		}
+
+		wait := delay/2 + rand.N(delay/2+1)
+		tm.Logger.Warn("Transaction retry", "attempt", attempt, "max_attempts", attempts, "backoff", wait, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		if delay *= 2; delay > retryMaxDelay {
+			delay = retryMaxDelay
+		}
+	}
+}
+
+func (tm *TxManager) begin(ctx context.Context, o TxOptions, fn func(ctx context.Context) error) error {
+	st := &txState{hooks: &txHooks{}, seq: new(atomic.Int64)}
+	txCtx := context.WithValue(ctx, txKey{}, st)
+	// A transaction started inside another one (RequiresNew) must not reuse the connection the
+	// scope pinned: the outer transaction is running on it. Without a scope, the new one leases
+	// the tenant database's pool until the transaction is over.
+	if txFrom(ctx) != nil || (tenantContext.DatabaseFrom(ctx) != "" && connScopeFrom(ctx) == nil) {
+		var release func()
+		txCtx, release = WithConnScope(txCtx)
+		defer release()
+	}
+
+	var sqlOpts []*sql.TxOptions
+	if o.Isolation != sql.LevelDefault || o.ReadOnly {
+		sqlOpts = append(sqlOpts, &sql.TxOptions{Isolation: o.Isolation, ReadOnly: o.ReadOnly})
+	}
+	tx := tm.DB.BeginTx(txCtx, sqlOpts...)
 	if tx.Error != nil {
 		tm.Logger.Error("Transaction failed to begin", "error", tx.Error)
 		return tx.Error
 	}
+	st.tx = tx
+
+	if o.StatementTimeout > 0 {
+		stmt := fmt.Sprintf("SET LOCAL statement_timeout = %d", o.StatementTimeout.Milliseconds())
+		if err := tx.Exec(stmt).Error; err != nil {
+			tm.Logger.Error("Transaction failed to set statement timeout", "error", err)
+			tx.Rollback()
+			return err
+		}
+	}
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -36,7 +199,7 @@ func (tm *TxManager) Execute(ctx context.Context, fn func(tx *gorm.DB) error) er
 		}
 	}()
 
-	if err := fn(tx); err != nil {
+	if err := fn(txCtx); err != nil {
 		tm.Logger.Error("Transaction rolled back due to function error", "error", err)
 		tx.Rollback()
 		return err
@@ -45,6 +208,137 @@ func (tm *TxManager) Execute(ctx context.Context, fn func(tx *gorm.DB) error) er
 	commitErr := tx.Commit().Error
 	if commitErr != nil {
 		tm.Logger.Error("Transaction commit failed", "error", commitErr)
+		return commitErr
+	}
+	if !o.ReadOnly {
+		MarkWritten(ctx)
+	}
+	st.hooks.run(ctx, tm.Logger)
+	return nil
+}
+
+// savepoint runs fn inside a savepoint of the transaction in cur. After-commit hooks
+// registered by fn are handed to the enclosing level only if the savepoint is released.
+// Savepoint statements are executed directly: the Postgres dialector's SavePoint and
+// RollbackTo discard the statement's error.
+func (tm *TxManager) savepoint(ctx context.Context, cur *txState, fn func(ctx context.Context) error) error {
+	name := fmt.Sprintf("sp_%d", cur.seq.Add(1))
+	if err := cur.tx.Exec("SAVEPOINT " + name).Error; err != nil {
+		tm.Logger.Error("Savepoint failed", "savepoint", name, "error", err)
+		return err
+	}
+
+	child := &txState{tx: cur.tx, hooks: &txHooks{}, seq: cur.seq}
+	spCtx := context.WithValue(ctx, txKey{}, child)
+
+	defer func() {
+		if r := recover(); r != nil {
+			tm.Logger.Error("Savepoint rolled back due to panic", "savepoint", name, "panic_value", r)
+			if err := cur.tx.Exec("ROLLBACK TO SAVEPOINT " + name).Error; err != nil {
+				tm.Logger.Error("Savepoint rollback failed", "savepoint", name, "error", err)
+			}
+			panic(r)
+		}
+	}()
+
+	if err := fn(spCtx); err != nil {
+		tm.Logger.Error("Savepoint rolled back due to function error", "savepoint", name, "error", err)
+		if rbErr := cur.tx.Exec("ROLLBACK TO SAVEPOINT " + name).Error; rbErr != nil {
+			// The enclosing transaction is unusable now; the caller has to roll it back.
+			tm.Logger.Error("Savepoint rollback failed", "savepoint", name, "error", rbErr)
+			return errors.Join(err, fmt.Errorf("rollback to savepoint %s: %w", name, rbErr))
+		}
+		return err
+	}
+	if err := cur.tx.Exec("RELEASE SAVEPOINT " + name).Error; err != nil {
+		tm.Logger.Error("Savepoint release failed", "savepoint", name, "error", err)
+		return err
+	}
+	cur.hooks.adopt(child.hooks)
+	return nil
+}
+
+type txKey struct{}
+
+// txState is one level (transaction or savepoint) of a TxManager transaction.
+type txState struct {
+	tx    *gorm.DB
+	hooks *txHooks
+	seq   *atomic.Int64 // savepoint names, shared by all levels
+}
+
+func txFrom(ctx context.Context) *txState {
+	if ctx == nil {
+		return nil
+	}
+	st, _ := ctx.Value(txKey{}).(*txState)
+	return st
+}
+
+// TxFrom returns the TxManager transaction carried by ctx, if any.
+func TxFrom(ctx context.Context) (*gorm.DB, bool) {
+	if st := txFrom(ctx); st != nil && st.tx != nil {
+		return st.tx.WithContext(ctx), true
+	}
+	return nil, false
+}
+
+// txHooks collects after-commit callbacks of one transaction level.
+type txHooks struct {
+	mu  sync.Mutex
+	fns []func(ctx context.Context)
+}
+
+func (h *txHooks) add(fn func(ctx context.Context)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fns = append(h.fns, fn)
+}
+
+// adopt moves child's callbacks to h (a released savepoint hands them to its parent).
+func (h *txHooks) adopt(child *txHooks) {
+	child.mu.Lock()
+	fns := child.fns
+	child.fns = nil
+	child.mu.Unlock()
+	for _, fn := range fns {
+		h.add(fn)
+	}
+}
+
+// run executes callbacks in registration order; a panicking callback is logged and skipped.
+func (h *txHooks) run(ctx context.Context, log logger.Interface) {
+	h.mu.Lock()
+	fns := h.fns
+	h.fns = nil
+	h.mu.Unlock()
+
+	for _, fn := range fns {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Error("After-commit hook panicked", "panic_value", r)
+				}
+			}()
+			fn(ctx)
+		}()
+	}
+}
+
+// AfterCommit registers fn to run after the TxManager transaction behind tx (or in ctx) commits.
+// Nothing runs if the transaction, or the savepoint fn was registered in, rolls back.
+// When neither carries a TxManager transaction there is no commit to wait for, so fn runs
+// immediately with ctx.
+func AfterCommit(ctx context.Context, tx *gorm.DB, fn func(ctx context.Context)) {
+	if tx != nil && tx.Statement != nil {
+		if st := txFrom(tx.Statement.Context); st != nil {
+			st.hooks.add(fn)
+			return
+		}
+	}
+	if st := txFrom(ctx); st != nil {
+		st.hooks.add(fn)
+		return
 	}
-	return commitErr
+	fn(ctx)
 }
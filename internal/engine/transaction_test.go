@@ -0,0 +1,356 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"skyrix/internal/engine/sqltest"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// recordingTxManager returns a TxManager whose statements are recorded instead of run.
+func recordingTxManager(t *testing.T) (TransactionManager, *sqltest.Recorder) {
+	t.Helper()
+	gdb, rec := sqltest.Open(t)
+	return NewTxManager(NewDatabaseService(gdb, "core"), testLogger()), rec
+}
+
+// txControl drops the search_path statement BeginTx issues, leaving transaction control.
+func txControl(stmts []string) []string {
+	return slices.DeleteFunc(stmts, func(s string) bool { return strings.HasPrefix(s, "SET LOCAL") })
+}
+
+func TestSavepointsAreNamedInOrder(t *testing.T) {
+	tm, rec := recordingTxManager(t)
+	nop := func(context.Context) error { return nil }
+
+	err := tm.Run(context.Background(), func(ctx context.Context) error {
+		if err := tm.Run(ctx, nop); err != nil {
+			return err
+		}
+		return tm.Run(ctx, func(ctx context.Context) error {
+			return tm.Run(ctx, nop)
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"BEGIN",
+		"SAVEPOINT sp_1", "RELEASE SAVEPOINT sp_1",
+		"SAVEPOINT sp_2", "SAVEPOINT sp_3", "RELEASE SAVEPOINT sp_3", "RELEASE SAVEPOINT sp_2",
+		"COMMIT",
+	}
+	if got := txControl(rec.Statements()); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestSavepointRollsBackOnError(t *testing.T) {
+	tm, rec := recordingTxManager(t)
+	nested := errors.New("nested")
+
+	err := tm.Run(context.Background(), func(ctx context.Context) error {
+		if err := tm.Run(ctx, func(context.Context) error { return nested }); !errors.Is(err, nested) {
+			t.Errorf("nested Run: %v", err)
+		}
+		return nil // the outer transaction goes on
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "COMMIT"}
+	if got := txControl(rec.Statements()); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestSavepointRollsBackOnPanic(t *testing.T) {
+	tm, rec := recordingTxManager(t)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "kaboom" {
+				t.Errorf("recovered %v, want the nested panic", r)
+			}
+		}()
+		_ = tm.Run(context.Background(), func(ctx context.Context) error {
+			return tm.Run(ctx, func(context.Context) error { panic("kaboom") })
+		})
+	}()
+
+	want := []string{"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "ROLLBACK"}
+	if got := txControl(rec.Statements()); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestSavepointRollbackFailureIsReturned(t *testing.T) {
+	tm, rec := recordingTxManager(t)
+	rbErr := errors.New("connection lost")
+	rec.Fail = func(stmt string) error {
+		if strings.HasPrefix(stmt, "ROLLBACK TO SAVEPOINT") {
+			return rbErr
+		}
+		return nil
+	}
+	nested := errors.New("nested")
+
+	var got error
+	_ = tm.Run(context.Background(), func(ctx context.Context) error {
+		got = tm.Run(ctx, func(context.Context) error { return nested })
+		return got
+	})
+	if !errors.Is(got, nested) || !errors.Is(got, rbErr) {
+		t.Errorf("nested Run: %v, want both the nested and the rollback error", got)
+	}
+}
+
+func TestNeverFailsInsideTransaction(t *testing.T) {
+	tm, _ := recordingTxManager(t)
+	ran := false
+	err := tm.Run(context.Background(), func(ctx context.Context) error {
+		return tm.Run(ctx, func(context.Context) error {
+			ran = true
+			return nil
+		}, WithPropagation(Never))
+	})
+	if !errors.Is(err, ErrTxActive) || ran {
+		t.Errorf("got %v (ran %v), want ErrTxActive", err, ran)
+	}
+}
+
+func TestTxHooksAdoptAndRun(t *testing.T) {
+	var order []string
+	add := func(h *txHooks, name string) {
+		h.add(func(context.Context) { order = append(order, name) })
+	}
+
+	parent, child := &txHooks{}, &txHooks{}
+	add(parent, "parent-1")
+	add(child, "child")
+	child.add(func(context.Context) { panic("bad hook") })
+	add(parent, "parent-2")
+	parent.adopt(child)
+
+	parent.run(context.Background(), testLogger())
+	if want := []string{"parent-1", "parent-2", "child"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("hooks ran %v, want %v (panics skipped)", order, want)
+	}
+
+	order = nil
+	parent.run(context.Background(), testLogger())
+	child.run(context.Background(), testLogger())
+	if len(order) != 0 {
+		t.Errorf("hooks ran twice: %v", order)
+	}
+}
+
+func TestAfterCommitFollowsSavepointOutcome(t *testing.T) {
+	tm, _ := recordingTxManager(t)
+	var ran []string
+	hook := func(name string) func(context.Context) {
+		return func(context.Context) { ran = append(ran, name) }
+	}
+
+	err := tm.Run(context.Background(), func(ctx context.Context) error {
+		AfterCommit(ctx, nil, hook("outer"))
+		_ = tm.Run(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, nil, hook("rolled-back"))
+			return errors.New("nested")
+		})
+		return tm.Run(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, nil, hook("released"))
+			if len(ran) != 0 {
+				t.Error("hook ran before commit")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"outer", "released"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("hooks ran %v, want %v", ran, want)
+	}
+
+	ran = nil
+	AfterCommit(context.Background(), nil, hook("no-tx"))
+	if want := []string{"no-tx"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("without a transaction: %v, want %v", ran, want)
+	}
+}
+
+// Nested savepoints against a real Postgres: only the work of rolled back levels disappears.
+func TestNestedSavepointsKeepReleasedWork(t *testing.T) {
+	d := testDatabase(t, 2)
+	tm := NewTxManager(d, testLogger())
+	tenant := testTenants[0]
+
+	ctx, release := WithConnScope(tenantContext.WithSchema(context.Background(), tenant))
+	defer release()
+	insert := func(ctx context.Context, name string) error {
+		return d.WithContext(ctx).Exec("INSERT INTO items (name) VALUES (?)", name).Error
+	}
+	failed := errors.New("nested fails")
+
+	err := tm.Run(ctx, func(ctx context.Context) error {
+		if err := insert(ctx, "outer"); err != nil {
+			return err
+		}
+		return tm.Run(ctx, func(ctx context.Context) error {
+			if err := insert(ctx, "kept"); err != nil {
+				return err
+			}
+			err := tm.Run(ctx, func(ctx context.Context) error {
+				if err := insert(ctx, "dropped"); err != nil {
+					return err
+				}
+				return failed
+			})
+			if !errors.Is(err, failed) {
+				return err
+			}
+			return tm.Run(ctx, func(ctx context.Context) error { return insert(ctx, "deep") })
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := tenantNames(ctx, d.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(names, ",")
+	for _, want := range []string{"outer", "kept", "deep"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("items %s: missing %s", got, want)
+		}
+	}
+	if strings.Contains(got, "dropped") {
+		t.Errorf("items %s: rolled back savepoint kept its row", got)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, true},
+		{"wrapped", fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40001"}), true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"plain error", errors.New("40001"), false},
+		{"nil", nil, false},
+	} {
+		if got := IsRetryable(c.err); got != c.want {
+			t.Errorf("%s: IsRetryable = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// failCommits makes the first n COMMITs fail with err.
+func failCommits(rec *sqltest.Recorder, n int, err error) {
+	var failed atomic.Int32
+	rec.Fail = func(stmt string) error {
+		if stmt == "COMMIT" && int(failed.Add(1)) <= n {
+			return err
+		}
+		return nil
+	}
+}
+
+func TestRetryReRunsRetryableFailures(t *testing.T) {
+	serialization := &pgconn.PgError{Code: "40001"}
+
+	for _, c := range []struct {
+		name      string
+		failures  int
+		err       error
+		opts      []TxOption
+		wantRuns  int
+		wantError bool
+	}{
+		{"not opted in", 1, serialization, nil, 1, true},
+		{"succeeds on retry", 2, serialization, []TxOption{WithRetry(3)}, 3, false},
+		{"attempts exhausted", 5, serialization, []TxOption{WithRetry(3)}, 3, true},
+		{"deadlock", 1, &pgconn.PgError{Code: "40P01"}, []TxOption{WithRetry(2)}, 2, false},
+		{"not retryable", 5, &pgconn.PgError{Code: "23505"}, []TxOption{WithRetry(3)}, 1, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			tm, rec := recordingTxManager(t)
+			failCommits(rec, c.failures, c.err)
+
+			runs := 0
+			err := tm.Run(context.Background(), func(context.Context) error {
+				runs++
+				return nil
+			}, c.opts...)
+			if runs != c.wantRuns {
+				t.Errorf("fn ran %d times, want %d", runs, c.wantRuns)
+			}
+			if (err != nil) != c.wantError || err != nil && !errors.Is(err, c.err) {
+				t.Errorf("err = %v, want error %v: %v", err, c.wantError, c.err)
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenContextIsDone(t *testing.T) {
+	tm, rec := recordingTxManager(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	serialization := &pgconn.PgError{Code: "40001"}
+	rec.Fail = func(stmt string) error {
+		if stmt == "COMMIT" {
+			cancel() // the backoff wait sees a done context
+			return serialization
+		}
+		return nil
+	}
+
+	runs := 0
+	err := tm.Run(ctx, func(context.Context) error {
+		runs++
+		return nil
+	}, WithRetry(10))
+	if runs != 1 || !errors.Is(err, serialization) {
+		t.Errorf("fn ran %d times, err %v; want one run and the serialization failure", runs, err)
+	}
+}
+
+func TestTxOptionsApplyToNewTransactions(t *testing.T) {
+	tm, rec := recordingTxManager(t)
+
+	err := tm.Run(context.Background(), func(ctx context.Context) error {
+		// A savepoint inherits its parent's settings.
+		return tm.Run(ctx, func(context.Context) error { return nil }, WithStatementTimeout(time.Hour))
+	}, ReadOnly(), WithStatementTimeout(1500*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"BEGIN READ ONLY",
+		"SET LOCAL statement_timeout = 1500",
+		"SAVEPOINT sp_1", "RELEASE SAVEPOINT sp_1",
+		"COMMIT",
+	}
+	got := slices.DeleteFunc(rec.Statements(), func(s string) bool { return strings.HasPrefix(s, "SET LOCAL search_path") })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("statements:\n got %q\nwant %q", got, want)
+	}
+}
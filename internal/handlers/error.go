@@ -3,7 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"skyrix/internal/kernel/db/scope"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
 
 	chimw "github.com/go-chi/chi/v5/middleware"
 )
@@ -52,7 +52,7 @@ func writeStructuredError(
 			reqID = chimw.GetReqID(r.Context())
 			url = r.URL.String()
 			remote = r.RemoteAddr
-			tenant = scope.TenantFrom(r.Context())
+			tenant = tenantContext.SchemaFrom(r.Context())
 		}
 		lg.Error("handler error",
 			"handler", handlerName,
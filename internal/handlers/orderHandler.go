@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"skyrix/internal/domain/order/services"
+	subscriberRepository "skyrix/internal/domain/subscriber/repository"
+	"skyrix/internal/logger"
+	"skyrix/internal/validation"
+)
+
+type OrderHandler struct {
+	*BaseHandler
+	OrderService *services.OrderService
+}
+
+func NewOrderHandler(logger logger.Interface, orderService *services.OrderService, validator *validation.Validator) *OrderHandler {
+	return &OrderHandler{
+		BaseHandler:  &BaseHandler{HandlerName: "OrderHandler", Logger: logger, Validator: validator},
+		OrderService: orderService,
+	}
+}
+
+type createOrderRequest struct {
+	SubscriberID int64  `json:"subscriber_id" validate:"required,gt=0"`
+	Total        int64  `json:"total" validate:"gte=0"`
+	Currency     string `json:"currency" validate:"required,len=3,alpha"`
+}
+
+// Create places an order: POST /orders -> 201 with the stored order.
+func (h *OrderHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req createOrderRequest
+	if !h.DecodeJSON(w, r, &req, 0) {
+		return
+	}
+	if details := h.MapValidationErrors(&req); details != nil {
+		h.WriteJSON(w, http.StatusBadRequest, ErrorPayload{
+			Error: ErrorBody{Code: ErrCodeValidation, Message: "Invalid order", Details: details},
+		})
+		return
+	}
+
+	order, err := h.OrderService.PlaceOrder(r.Context(), services.PlaceOrderInput{
+		SubscriberID: req.SubscriberID,
+		Total:        req.Total,
+		Currency:     req.Currency,
+	})
+	if errors.Is(err, subscriberRepository.ErrSubscriberNotFound) {
+		h.HandleError(w, r, err, "Subscriber not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.HandleError(w, r, err, "Failed to place order", http.StatusInternalServerError)
+		return
+	}
+	h.WriteJSON(w, http.StatusCreated, order)
+}
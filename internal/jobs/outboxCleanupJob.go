@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+
+	engineJobs "skyrix/internal/engine/jobs"
+	"skyrix/internal/engine/outbox"
+)
+
+// OutboxCleanupJob deletes published outbox messages older than the configured retention.
+type OutboxCleanupJob struct {
+	Relay *outbox.Relay
+}
+
+func NewOutboxCleanupJob(relay *outbox.Relay) *OutboxCleanupJob {
+	return &OutboxCleanupJob{Relay: relay}
+}
+
+func (j *OutboxCleanupJob) Name() string { return "outbox.cleanup" }
+
+func (j *OutboxCleanupJob) RetryCount() int { return 1 }
+
+func (j *OutboxCleanupJob) Scope() engineJobs.Scope { return engineJobs.ScopeMain }
+
+func (j *OutboxCleanupJob) Execute(ctx context.Context, _ map[string]any) error {
+	_, err := j.Relay.Cleanup(ctx)
+	return err
+}
+
+var _ engineJobs.Job = (*OutboxCleanupJob)(nil)
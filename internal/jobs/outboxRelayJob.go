@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+
+	engineJobs "skyrix/internal/engine/jobs"
+	"skyrix/internal/engine/outbox"
+)
+
+// OutboxRelayJob drains the outbox once: it processes batches until no due messages remain.
+// For a long-running relay use the outbox:relay console command.
+type OutboxRelayJob struct {
+	Relay *outbox.Relay
+}
+
+func NewOutboxRelayJob(relay *outbox.Relay) *OutboxRelayJob {
+	return &OutboxRelayJob{Relay: relay}
+}
+
+func (j *OutboxRelayJob) Name() string { return "outbox.relay" }
+
+func (j *OutboxRelayJob) RetryCount() int { return 2 }
+
+func (j *OutboxRelayJob) Scope() engineJobs.Scope { return engineJobs.ScopeMain }
+
+// Execute processes batches until a batch comes back empty.
+func (j *OutboxRelayJob) Execute(ctx context.Context, _ map[string]any) error {
+	for {
+		n, err := j.Relay.ProcessBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if n == 0 || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+var _ engineJobs.Job = (*OutboxRelayJob)(nil)
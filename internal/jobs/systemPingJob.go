@@ -21,6 +21,8 @@ func (j *SystemPingJob) Name() string { return "system.ping" }
 
 func (j *SystemPingJob) RetryCount() int { return 0 }
 
+func (j *SystemPingJob) Scope() engineJobs.Scope { return engineJobs.ScopeMain }
+
 // Execute logs a ping and returns nil.
 // Expected args (optional):
 //   - "msg": string
@@ -2,7 +2,9 @@ package kernel
 
 import (
 	"context"
+	"time"
 
+	"skyrix/internal/config"
 	"skyrix/internal/providers"
 
 	"github.com/spf13/cobra"
@@ -11,16 +13,21 @@ import (
 // ConsoleApp is the final runnable CLI application.
 // It wires the Kernel plus the Commands bundle and exposes a single Execute entrypoint.
 type ConsoleApp struct {
-	Kernel   *Kernel
-	Jobs     *providers.Jobs
-	Commands *providers.Commands
+	Kernel    *Kernel
+	Jobs      *providers.Jobs
+	Commands  *providers.Commands
+	Lifecycle *Lifecycle
+
+	shutdownTimeout time.Duration
 }
 
-func NewConsoleApp(kernel *Kernel, jobs *providers.Jobs, commands *providers.Commands) *ConsoleApp {
+func NewConsoleApp(kernel *Kernel, jobs *providers.Jobs, commands *providers.Commands, lifecycle *Lifecycle, cfg *config.HttpServer) *ConsoleApp {
 	return &ConsoleApp{
-		Kernel:   kernel,
-		Jobs:     jobs,
-		Commands: commands,
+		Kernel:          kernel,
+		Jobs:            jobs,
+		Commands:        commands,
+		Lifecycle:       lifecycle,
+		shutdownTimeout: cfg.ShutdownTimeout,
 	}
 }
 
@@ -31,6 +38,17 @@ func (c *ConsoleApp) Execute(ctx context.Context) error {
 	return root.Execute()
 }
 
+// Stop runs the OnStop hooks within APP_SHUTDOWN_TIMEOUT.
+func (c *ConsoleApp) Stop() error {
+	ctx := context.Background()
+	if c.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.shutdownTimeout)
+		defer cancel()
+	}
+	return c.Lifecycle.Stop(ctx)
+}
+
 // newRootCommand constructs the CLI root command and registers all sub-commands.
 func (c *ConsoleApp) newRootCommand() *cobra.Command {
 	root := &cobra.Command{
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"skyrix/internal/engine/metrics"
+
+	"gorm.io/gorm"
+)
+
+const metricsStartKey = "metrics:start"
+
+// MetricsPlugin records the duration and errors of every GORM statement in
+// db_query_duration_seconds and db_query_errors_total, labelled by operation and table.
+type MetricsPlugin struct {
+	metrics *metrics.Metrics
+}
+
+func NewMetricsPlugin(m *metrics.Metrics) *MetricsPlugin { return &MetricsPlugin{metrics: m} }
+
+func (p *MetricsPlugin) Name() string { return "metrics" }
+
+func (p *MetricsPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Before("*").Register("metrics:before:query", p.before); err != nil {
+		return err
+	}
+	if err := cb.Query().After("*").Register("metrics:after:query", p.after("query")); err != nil {
+		return err
+	}
+	if err := cb.Create().Before("*").Register("metrics:before:create", p.before); err != nil {
+		return err
+	}
+	if err := cb.Create().After("*").Register("metrics:after:create", p.after("create")); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("*").Register("metrics:before:update", p.before); err != nil {
+		return err
+	}
+	if err := cb.Update().After("*").Register("metrics:after:update", p.after("update")); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("*").Register("metrics:before:delete", p.before); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("*").Register("metrics:after:delete", p.after("delete")); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("*").Register("metrics:before:row", p.before); err != nil {
+		return err
+	}
+	if err := cb.Row().After("*").Register("metrics:after:row", p.after("row")); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("*").Register("metrics:before:raw", p.before); err != nil {
+		return err
+	}
+	return cb.Raw().After("*").Register("metrics:after:raw", p.after("raw"))
+}
+
+func (p *MetricsPlugin) before(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+
+func (p *MetricsPlugin) after(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		start, _ := v.(time.Time)
+		table := db.Statement.Table
+		if table == "" {
+			table = "none"
+		}
+		p.metrics.DBQueryDuration.With(op, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			p.metrics.DBQueryErrors.With(op, table).Inc()
+		}
+	}
+}
@@ -1,21 +1,227 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"skyrix/internal/config"
+	"skyrix/internal/engine/metrics"
+	"skyrix/internal/kernel/db/scope"
+	"skyrix/internal/logger"
+	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
 )
 
 type MainSchema string
 
-func InitPostgres(cfg *config.Database) (DB *gorm.DB, err error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable search_path=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Pass, cfg.Name, cfg.MainSchema)
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+const (
+	connectBaseDelay = 500 * time.Millisecond
+	connectMaxDelay  = 10 * time.Second
+)
+
+// InitPostgres connects to the primary, retrying with backoff until cfg.ConnectTimeout
+// (Postgres starting after the app is normal under docker-compose) or until ctx is done,
+// then applies pool limits and registers the schema and replica routers. Statements are
+// recorded in m, which may be nil.
+func InitPostgres(ctx context.Context, cfg *config.Database, log logger.Interface, m *metrics.Metrics) (DB *gorm.DB, err error) {
+	gormCfg := &gorm.Config{
+		Logger:               gormLogger.Default.LogMode(gormLogLevel(cfg.LogLevel)),
+		DisableAutomaticPing: true, // openPostgres pings with ctx
+	}
+	DB, err = openWithRetry(ctx, openPostgres(postgresDSN(cfg, cfg.Host, cfg.Port), gormCfg), cfg.ConnectTimeout, log)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := DB.DB()
 	if err != nil {
 		return nil, err
 	}
+	configurePool(sqlDB, cfg)
+	// Every ORM statement is schema-qualified by the router; search_path is only a fallback.
+	if err = DB.Use(scope.NewPlugin(cfg.MainSchema, cfg.TenantStrict)); err != nil {
+		return nil, err
+	}
+	if m != nil {
+		if err = DB.Use(NewMetricsPlugin(m)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cfg.Replicas) > 0 {
+		replicas, err := openReplicas(cfg)
+		if err != nil {
+			_ = ClosePostgres(DB)
+			return nil, err
+		}
+		if err = DB.Use(NewReplicaRouter(replicas, cfg.ReplicaPolicy, cfg.ReplicaMaxLag, cfg.ReplicaHealthInterval)); err != nil {
+			for _, r := range replicas {
+				_ = r.Close()
+			}
+			_ = ClosePostgres(DB)
+			return nil, err
+		}
+	}
 	return DB, nil
 }
+
+// ClosePostgres closes plugins holding resources (replica pools) and the primary pool.
+func ClosePostgres(DB *gorm.DB) error {
+	for _, p := range DB.Config.Plugins {
+		if c, ok := p.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// openPostgres returns an open func for openWithRetry that pings dsn with the attempt's ctx.
+func openPostgres(dsn string, gormCfg *gorm.Config) func(ctx context.Context) (*gorm.DB, error) {
+	return func(ctx context.Context) (*gorm.DB, error) {
+		DB, err := gorm.Open(postgres.Open(dsn), gormCfg)
+		if err != nil {
+			return nil, err
+		}
+		sqlDB, err := DB.DB()
+		if err != nil {
+			return nil, err
+		}
+		if err := sqlDB.PingContext(ctx); err != nil {
+			_ = sqlDB.Close()
+			return nil, err
+		}
+		return DB, nil
+	}
+}
+
+// openWithRetry calls open with exponential backoff until it succeeds, the next attempt would
+// start after timeout, or ctx is done (shutdown during startup).
+func openWithRetry(ctx context.Context, open func(ctx context.Context) (*gorm.DB, error), timeout time.Duration, log logger.Interface) (*gorm.DB, error) {
+	deadline := time.Now().Add(timeout)
+	delay := connectBaseDelay
+	for attempt := 1; ; attempt++ {
+		DB, err := open(ctx)
+		if err == nil {
+			return DB, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("postgres connect interrupted after %d attempts: %w", attempt, errors.Join(ctx.Err(), err))
+		}
+		if time.Now().Add(delay).After(deadline) {
+			return nil, fmt.Errorf("postgres not reachable after %d attempts: %w", attempt, err)
+		}
+		log.Warn("Postgres not ready, retrying", "attempt", attempt, "backoff", delay, "error", err)
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, fmt.Errorf("postgres connect interrupted after %d attempts: %w", attempt, errors.Join(ctx.Err(), err))
+		case <-t.C:
+		}
+		if delay *= 2; delay > connectMaxDelay {
+			delay = connectMaxDelay
+		}
+	}
+}
+
+func configurePool(sqlDB *sql.DB, cfg *config.Database) {
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+func gormLogLevel(level string) gormLogger.LogLevel {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "silent":
+		return gormLogger.Silent
+	case "error":
+		return gormLogger.Error
+	case "info", "debug":
+		return gormLogger.Info
+	default:
+		return gormLogger.Warn
+	}
+}
+
+// postgresDSN builds a keyword/value DSN. Values are quoted, so passwords may contain spaces or quotes.
+func postgresDSN(cfg *config.Database, host string, port int) string {
+	sslMode := strings.TrimSpace(cfg.SSLMode)
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	params := []string{
+		"host=" + dsnValue(host),
+		"port=" + strconv.Itoa(port),
+		"user=" + dsnValue(cfg.User),
+		"password=" + dsnValue(cfg.Pass),
+		"dbname=" + dsnValue(cfg.Name),
+		"sslmode=" + dsnValue(sslMode),
+		"search_path=" + dsnValue(cfg.MainSchema),
+	}
+	if cfg.SSLRootCert != "" {
+		params = append(params, "sslrootcert="+dsnValue(cfg.SSLRootCert))
+	}
+	if cfg.AppName != "" {
+		params = append(params, "application_name="+dsnValue(cfg.AppName))
+	}
+	if cfg.StatementTimeout > 0 {
+		params = append(params, "statement_timeout="+strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
+	}
+	return strings.Join(params, " ")
+}
+
+func dsnValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+// openReplicas opens one pool per replica address ("host" or "host:port", default port = primary's).
+func openReplicas(cfg *config.Database) (map[string]*sql.DB, error) {
+	out := make(map[string]*sql.DB, len(cfg.Replicas))
+	fail := func(err error) (map[string]*sql.DB, error) {
+		for _, db := range out {
+			_ = db.Close()
+		}
+		return nil, err
+	}
+
+	for _, addr := range cfg.Replicas {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		host, port := addr, cfg.Port
+		if h, p, err := net.SplitHostPort(addr); err == nil {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				return fail(fmt.Errorf("replica %q: invalid port: %w", addr, err))
+			}
+			host, port = h, n
+		}
+
+		gdb, err := gorm.Open(postgres.Open(postgresDSN(cfg, host, port)), &gorm.Config{DisableAutomaticPing: true})
+		if err != nil {
+			return fail(fmt.Errorf("replica %q: %w", addr, err))
+		}
+		sqlDB, err := gdb.DB()
+		if err != nil {
+			return fail(fmt.Errorf("replica %q: %w", addr, err))
+		}
+		configurePool(sqlDB, cfg)
+		out[addr] = sqlDB
+	}
+	return out, nil
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"skyrix/internal/config"
+	"skyrix/internal/logger"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+func TestPostgresDSNRoundTrips(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		cfg  config.Database
+	}{
+		{"plain", config.Database{User: "app", Pass: "secret", Name: "skyrix", MainSchema: "core"}},
+		{"spaces", config.Database{User: "app user", Pass: "pass word", Name: "my db", MainSchema: "core"}},
+		{"quotes", config.Database{User: "o'neil", Pass: `it's "quoted"`, Name: "skyrix", MainSchema: "core"}},
+		{"backslashes", config.Database{User: "app", Pass: `a\b\'c\\`, Name: "skyrix", MainSchema: "core"}},
+		{"keyword lookalike", config.Database{User: "app", Pass: "x sslmode=require host=evil", Name: "skyrix", MainSchema: "core"}},
+		{"empty password", config.Database{User: "app", Name: "skyrix", MainSchema: "core"}},
+		{"application name", config.Database{User: "app", Name: "skyrix", MainSchema: "core", AppName: "skyrix worker's"}},
+		{"statement timeout", config.Database{User: "app", Name: "skyrix", MainSchema: "core", StatementTimeout: 2500 * time.Millisecond}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			dsn := postgresDSN(&c.cfg, "db.internal", 6432)
+			pc, err := pgconn.ParseConfig(dsn)
+			if err != nil {
+				t.Fatalf("ParseConfig(%s): %v", dsn, err)
+			}
+			if pc.Host != "db.internal" || pc.Port != 6432 {
+				t.Errorf("host %s:%d", pc.Host, pc.Port)
+			}
+			if pc.User != c.cfg.User || pc.Password != c.cfg.Pass || pc.Database != c.cfg.Name {
+				t.Errorf("user %q password %q dbname %q", pc.User, pc.Password, pc.Database)
+			}
+			if pc.TLSConfig != nil {
+				t.Error("sslmode did not default to disable")
+			}
+			params := pc.RuntimeParams
+			if params["search_path"] != c.cfg.MainSchema {
+				t.Errorf("search_path %q", params["search_path"])
+			}
+			if params["application_name"] != c.cfg.AppName {
+				t.Errorf("application_name %q, want %q", params["application_name"], c.cfg.AppName)
+			}
+			want := ""
+			if c.cfg.StatementTimeout > 0 {
+				want = "2500"
+			}
+			if params["statement_timeout"] != want {
+				t.Errorf("statement_timeout %q, want %q", params["statement_timeout"], want)
+			}
+		})
+	}
+}
+
+func TestPostgresDSNTLS(t *testing.T) {
+	cfg := &config.Database{User: "app", Name: "skyrix", SSLMode: " verify-full ", SSLRootCert: "/etc/ssl/it's ca.pem"}
+	dsn := postgresDSN(cfg, "db", 5432)
+	for _, want := range []string{`sslmode='verify-full'`, `sslrootcert='/etc/ssl/it\'s ca.pem'`} {
+		if !strings.Contains(dsn, want) {
+			t.Errorf("dsn %s does not contain %s", dsn, want)
+		}
+	}
+	if dsn := postgresDSN(&config.Database{}, "db", 5432); strings.Contains(dsn, "sslrootcert") {
+		t.Errorf("dsn %s has an sslrootcert without one configured", dsn)
+	}
+}
+
+func TestDSNValue(t *testing.T) {
+	for in, want := range map[string]string{
+		"":       `''`,
+		"plain":  `'plain'`,
+		"a b":    `'a b'`,
+		"it's":   `'it\'s'`,
+		`a\b`:    `'a\\b'`,
+		`\'`:     `'\\\''`,
+		"a=b c=": `'a=b c='`,
+	} {
+		if got := dsnValue(in); got != want {
+			t.Errorf("dsnValue(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestOpenWithRetry(t *testing.T) {
+	log := logger.NewSlogWrapper(slog.New(slog.DiscardHandler))
+	unreachable := errors.New("connection refused")
+
+	t.Run("retries until open succeeds", func(t *testing.T) {
+		calls := 0
+		db, err := openWithRetry(context.Background(), func(context.Context) (*gorm.DB, error) {
+			if calls++; calls < 2 {
+				return nil, unreachable
+			}
+			return &gorm.DB{}, nil
+		}, time.Minute, log)
+		if err != nil || db == nil || calls != 2 {
+			t.Errorf("got %v, %v after %d calls; want a connection after 2", db, err, calls)
+		}
+	})
+
+	t.Run("gives up at the deadline", func(t *testing.T) {
+		calls := 0
+		_, err := openWithRetry(context.Background(), func(context.Context) (*gorm.DB, error) {
+			calls++
+			return nil, unreachable
+		}, 0, log)
+		if !errors.Is(err, unreachable) || calls != 1 {
+			t.Errorf("got %v after %d calls; want the open error after 1", err, calls)
+		}
+	})
+
+	t.Run("stops waiting when ctx is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		done := make(chan error, 1)
+		go func() {
+			_, err := openWithRetry(ctx, func(context.Context) (*gorm.DB, error) {
+				if calls++; calls == 1 {
+					cancel() // shutdown while the first backoff is pending
+				}
+				return nil, unreachable
+			}, time.Hour, log)
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) || !errors.Is(err, unreachable) || calls != 1 {
+				t.Errorf("got %v after %d calls; want cancellation and the open error after 1", err, calls)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("openWithRetry kept retrying after ctx was cancelled")
+		}
+	})
+
+	t.Run("passes ctx to open", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := openWithRetry(ctx, func(ctx context.Context) (*gorm.DB, error) {
+			return nil, ctx.Err()
+		}, time.Hour, log)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want context.Canceled", err)
+		}
+	})
+}
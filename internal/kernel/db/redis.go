@@ -2,23 +2,115 @@ package db
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"os"
 	"skyrix/internal/config"
+	"strings"
 
 	"github.com/redis/go-redis/v9"
 )
 
-func InitRedis(cfg *config.Redis) (*redis.Client, error) {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Password: cfg.Pass,
-		DB:       cfg.Db,
-	})
+// Redis deployment modes (config REDIS_MODE).
+const (
+	RedisStandalone = "standalone"
+	RedisSentinel   = "sentinel"
+	RedisCluster    = "cluster"
+)
+
+// NewRedisClient creates a client for the configured mode without connecting; go-redis dials
+// on first use. Sentinel clients follow master failovers; cluster clients route by hash slot.
+func NewRedisClient(cfg *config.Redis) (redis.UniversalClient, error) {
+	tlsCfg, err := redisTLS(cfg)
+	if err != nil {
+		return nil, err
+	}
+	addrs := cfg.Addrs
+	if len(addrs) == 0 {
+		addrs = []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)}
+	}
+
+	switch mode := strings.ToLower(strings.TrimSpace(cfg.Mode)); mode {
+	case "", RedisStandalone:
+		return redis.NewClient(&redis.Options{
+			Addr:      fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+			Username:  cfg.User,
+			Password:  cfg.Pass,
+			DB:        cfg.Db,
+			TLSConfig: tlsCfg,
+		}), nil
+	case RedisSentinel:
+		if cfg.MasterName == "" {
+			return nil, errors.New("redis: REDIS_MASTER_NAME is required in sentinel mode")
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.MasterName,
+			SentinelAddrs:    addrs,
+			SentinelUsername: cfg.SentinelUser,
+			SentinelPassword: cfg.SentinelPass,
+			Username:         cfg.User,
+			Password:         cfg.Pass,
+			DB:               cfg.Db,
+			TLSConfig:        tlsCfg,
+		}), nil
+	case RedisCluster:
+		if cfg.Db != 0 {
+			return nil, errors.New("redis: cluster mode only supports REDIS_DB 0")
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     addrs,
+			Username:  cfg.User,
+			Password:  cfg.Pass,
+			TLSConfig: tlsCfg,
+		}), nil
+	default:
+		return nil, fmt.Errorf("redis: unknown REDIS_MODE %q (want %s, %s or %s)", mode, RedisStandalone, RedisSentinel, RedisCluster)
+	}
+}
+
+// redisTLS builds the TLS config, or nil when REDIS_TLS is off.
+func redisTLS(cfg *config.Redis) (*tls.Config, error) {
+	if !cfg.TLS {
+		return nil, nil
+	}
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.TLSServerName,
+		InsecureSkipVerify: cfg.TLSSkipVerify, // opt-in, test environments only
+	}
+	if cfg.TLSCACert != "" {
+		pem, err := os.ReadFile(cfg.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("redis: read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis: no certificates in %s", cfg.TLSCACert)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if cfg.TLSCert != "" || cfg.TLSKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("redis: load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+func InitRedis(cfg *config.Redis) (redis.UniversalClient, error) {
+	rdb, err := NewRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	// Test connection
 	ctx := context.Background()
-	_, err := rdb.Ping(ctx).Result()
-	if err != nil {
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		_ = rdb.Close()
 		return nil, err
 	}
 
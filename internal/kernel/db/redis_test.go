@@ -0,0 +1,212 @@
+package db
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"skyrix/internal/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func miniredisConfig(t *testing.T, mr *miniredis.Miniredis) config.Redis {
+	t.Helper()
+	host, port, _ := strings.Cut(mr.Addr(), ":")
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.Redis{Host: host, Port: p}
+}
+
+func TestInitRedisModes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	mr.RequireUserAuth("app", "secret")
+
+	for _, mode := range []string{"", RedisStandalone, " Cluster "} {
+		cfg := miniredisConfig(t, mr)
+		cfg.Mode, cfg.User, cfg.Pass = mode, "app", "secret"
+		rdb, err := InitRedis(&cfg)
+		if err != nil {
+			t.Errorf("mode %q: %v", mode, err)
+			continue
+		}
+		_, cluster := rdb.(*redis.ClusterClient)
+		if want := strings.TrimSpace(mode) == "Cluster"; cluster != want {
+			t.Errorf("mode %q: got %T", mode, rdb)
+		}
+		_ = rdb.Close()
+	}
+
+	cfg := miniredisConfig(t, mr)
+	cfg.User, cfg.Pass = "app", "wrong"
+	if rdb, err := InitRedis(&cfg); err == nil {
+		_ = rdb.Close()
+		t.Error("wrong ACL password accepted")
+	}
+}
+
+func TestNewRedisClientOptions(t *testing.T) {
+	base := config.Redis{Host: "redis", Port: 6380, User: "app", Pass: "secret", Db: 2}
+
+	rdb, err := NewRedisClient(&base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := rdb.(*redis.Client)
+	if !ok {
+		t.Fatalf("standalone: got %T", rdb)
+	}
+	if o := c.Options(); o.Addr != "redis:6380" || o.Username != "app" || o.Password != "secret" || o.DB != 2 || o.TLSConfig != nil {
+		t.Errorf("standalone options: %+v", o)
+	}
+
+	sentinel := base
+	sentinel.Mode, sentinel.MasterName = RedisSentinel, "mymaster"
+	sentinel.Addrs = []string{"s1:26379", "s2:26379"}
+	if rdb, err = NewRedisClient(&sentinel); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := rdb.(*redis.Client); !ok {
+		t.Errorf("sentinel: got %T", rdb)
+	} else if o := c.Options(); o.Username != "app" || o.Password != "secret" || o.DB != 2 {
+		t.Errorf("sentinel options: %+v", o)
+	}
+
+	cluster := base
+	cluster.Mode, cluster.Db = RedisCluster, 0
+	if rdb, err = NewRedisClient(&cluster); err != nil {
+		t.Fatal(err)
+	}
+	if cc, ok := rdb.(*redis.ClusterClient); !ok {
+		t.Errorf("cluster: got %T", rdb)
+	} else if o := cc.Options(); len(o.Addrs) != 1 || o.Addrs[0] != "redis:6380" || o.Username != "app" {
+		t.Errorf("cluster options: %+v", o)
+	}
+
+	for name, cfg := range map[string]config.Redis{
+		"sentinel without master": {Mode: RedisSentinel, Addrs: []string{"s1:26379"}},
+		"cluster with a db":       {Mode: RedisCluster, Db: 1},
+		"unknown mode":            {Mode: "replicated"},
+	} {
+		if _, err := NewRedisClient(&cfg); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestRedisTLS(t *testing.T) {
+	dir := t.TempDir()
+	caFile, certFile, keyFile := writeTestCerts(t, dir)
+
+	if cfg, err := redisTLS(&config.Redis{TLSCACert: caFile}); cfg != nil || err != nil {
+		t.Errorf("TLS off: got %v, %v", cfg, err)
+	}
+
+	cfg, err := redisTLS(&config.Redis{TLS: true, TLSCACert: caFile, TLSCert: certFile, TLSKey: keyFile, TLSServerName: "redis.internal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RootCAs == nil || len(cfg.Certificates) != 1 || cfg.ServerName != "redis.internal" || cfg.InsecureSkipVerify {
+		t.Errorf("mutual TLS config: %+v", cfg)
+	}
+
+	// Every mode gets the TLS config.
+	for _, mode := range []string{RedisStandalone, RedisSentinel, RedisCluster} {
+		rdb, err := NewRedisClient(&config.Redis{Mode: mode, Host: "redis", Port: 6379, MasterName: "m", TLS: true, TLSCACert: caFile})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tlsCfg any
+		switch c := rdb.(type) {
+		case *redis.Client:
+			tlsCfg = c.Options().TLSConfig
+		case *redis.ClusterClient:
+			tlsCfg = c.Options().TLSConfig
+		}
+		if tlsCfg == nil {
+			t.Errorf("%s: no TLS config", mode)
+		}
+		_ = rdb.Close()
+	}
+
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for name, c := range map[string]config.Redis{
+		"missing CA file":              {TLS: true, TLSCACert: filepath.Join(dir, "missing.pem")},
+		"CA file without certificates": {TLS: true, TLSCACert: empty},
+		"certificate without key":      {TLS: true, TLSCert: certFile},
+		"key without certificate":      {TLS: true, TLSKey: keyFile},
+	} {
+		if _, err := redisTLS(&c); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+// writeTestCerts writes a self-signed CA and a client certificate and key signed by it.
+func writeTestCerts(t *testing.T, dir string) (caFile, certFile, keyFile string) {
+	t.Helper()
+	newKey := func() *ecdsa.PrivateKey {
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	write := func(name, typ string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	caKey := newKey()
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientKey := newKey()
+	client := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "app"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, client, ca, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return write("ca.pem", "CERTIFICATE", caDER), write("client.pem", "CERTIFICATE", clientDER), write("client.key", "EC PRIVATE KEY", keyDER)
+}
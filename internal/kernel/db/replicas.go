@@ -0,0 +1,309 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"skyrix/internal/engine"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ReplicaRoundRobin = "round_robin"
+	ReplicaLeastLag   = "least_lag"
+)
+
+// replicaStateQuery reads a replicaState. The WAL receiver status needs pg_read_all_stats;
+// without it only the pid is visible and a running receiver counts as streaming.
+const replicaStateQuery = `SELECT
+	pg_is_in_recovery(),
+	COALESCE((SELECT COALESCE(status = 'streaming', pid IS NOT NULL) FROM pg_stat_wal_receiver), false),
+	pg_last_wal_receive_lsn() IS NOT DISTINCT FROM pg_last_wal_replay_lsn(),
+	COALESCE(EXTRACT(EPOCH FROM (now() - pg_last_xact_replay_timestamp())), -1)::float8`
+
+// replicaState is what one health check reads from a replica.
+type replicaState struct {
+	Recovering bool          // a standby; false once promoted
+	Streaming  bool          // its WAL receiver is connected to the primary
+	CaughtUp   bool          // it replayed all WAL it received
+	ReplayAge  time.Duration // since the last replayed commit; negative when none was replayed
+}
+
+// queryReplicaState is the default probe.
+func queryReplicaState(ctx context.Context, db *sql.DB) (replicaState, error) {
+	var s replicaState
+	var age float64
+	err := db.QueryRowContext(ctx, replicaStateQuery).Scan(&s.Recovering, &s.Streaming, &s.CaughtUp, &age)
+	s.ReplayAge = time.Duration(age * float64(time.Second))
+	return s, err
+}
+
+// primaryOnlySQL matches SELECTs that write or need a writable session: sequence and advisory
+// lock functions, NOTIFY, session settings, SELECT INTO and row locks. Functions of the
+// application that write are not detectable here; call them with engine.WithPrimary(ctx).
+var primaryOnlySQL = regexp.MustCompile(`(?is)\b(?:nextval|setval|currval|lastval|pg_(?:try_)?advisory_\w+|pg_notify|set_config|txid_current|pg_current_xact_id)\s*\(|\binto\b|\bfor\s+(?:update|share|no\s+key\s+update|key\s+share)\b`)
+
+type replica struct {
+	addr    string
+	db      *sql.DB
+	probe   func(ctx context.Context, db *sql.DB) (replicaState, error)
+	healthy atomic.Bool
+	lag     atomic.Int64 // nanoseconds
+}
+
+// ReplicaRouter is a GORM plugin that sends reads to healthy read replicas.
+//
+// A read goes to a replica only when all of these hold:
+//   - it runs on the shared primary pool (not in a transaction, not on a pinned connection),
+//     or on a tenant's pinned connection whose ctx opted in with engine.WithReplicaReads;
+//   - it is a query, or raw SQL starting with SELECT that calls no writing function such as
+//     nextval (pinned connections: ORM queries only);
+//   - it takes no row locks (FOR UPDATE/SHARE);
+//   - ctx does not require the primary (engine.WithPrimary, or the unit of work already wrote).
+//
+// Replicas are probed every health interval; failing or lagging ones are skipped and,
+// with none left, reads fall back to the primary.
+type ReplicaRouter struct {
+	primary  *sql.DB
+	replicas []*replica
+	policy   string
+	maxLag   time.Duration
+	interval time.Duration
+	next     atomic.Uint64
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+func NewReplicaRouter(dbs map[string]*sql.DB, policy string, maxLag, interval time.Duration) *ReplicaRouter {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	r := &ReplicaRouter{
+		policy:   strings.ToLower(strings.TrimSpace(policy)),
+		maxLag:   maxLag,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+	for addr, db := range dbs {
+		r.replicas = append(r.replicas, &replica{addr: addr, db: db, probe: queryReplicaState})
+	}
+	return r
+}
+
+func (r *ReplicaRouter) Name() string { return "replica-router" }
+
+func (r *ReplicaRouter) Initialize(db *gorm.DB) error {
+	primary, err := db.DB()
+	if err != nil {
+		return err
+	}
+	r.primary = primary
+
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("replica-router:query", r.route); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("replica-router:row", r.route); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("replica-router:create", markWritten); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("replica-router:update", markWritten); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("replica-router:delete", markWritten); err != nil {
+		return err
+	}
+	if err := cb.Raw().After("gorm:raw").Register("replica-router:raw", markWritten); err != nil {
+		return err
+	}
+
+	r.checkAll()
+	r.wg.Add(1)
+	go r.healthLoop()
+	return nil
+}
+
+// Close stops health checks and closes the replica pools.
+func (r *ReplicaRouter) Close() error {
+	r.once.Do(func() { close(r.stop) })
+	r.wg.Wait()
+	var firstErr error
+	for _, rep := range r.replicas {
+		if err := rep.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func markWritten(db *gorm.DB) {
+	if db.Error == nil {
+		engine.MarkWritten(db.Statement.Context)
+	}
+}
+
+func (r *ReplicaRouter) route(db *gorm.DB) {
+	if db.Error != nil || len(r.replicas) == 0 {
+		return
+	}
+	stmt := db.Statement
+	if engine.ReadsFromPrimary(stmt.Context) {
+		return
+	}
+
+	switch pool := stmt.ConnPool.(type) {
+	case *sql.DB:
+		if pool != r.primary {
+			return
+		}
+		if stmt.SQL.Len() > 0 && !isSelect(stmt.SQL.String()) {
+			return
+		}
+	case *sql.Conn:
+		// Pinned tenant connection: only schema-qualified ORM queries may leave it. Shared-schema
+		// tenants stay: replica pools do not carry app.tenant_id, which RLS policies require.
+		if !engine.ReplicaReadsAllowed(stmt.Context) || stmt.SQL.Len() > 0 {
+			return
+		}
+		if _, shared := tenantContext.TenantIDFrom(stmt.Context); shared {
+			return
+		}
+	default:
+		return // transactions and anything else stay where they are
+	}
+	if _, locking := stmt.Clauses["FOR"]; locking {
+		return
+	}
+
+	if rep := r.pick(); rep != nil {
+		stmt.ConnPool = rep.db
+	}
+}
+
+// ReaderPool returns a healthy replica pool for a read-only transaction in ctx,
+// or nil when the primary must be used.
+func (r *ReplicaRouter) ReaderPool(ctx context.Context) *sql.DB {
+	if engine.ReadsFromPrimary(ctx) {
+		return nil
+	}
+	if rep := r.pick(); rep != nil {
+		return rep.db
+	}
+	return nil
+}
+
+// isSelect reports whether raw SQL is a read a replica can serve.
+func isSelect(sql string) bool {
+	sql = strings.TrimSpace(sql)
+	return len(sql) >= 6 && strings.EqualFold(sql[:6], "select") && !primaryOnlySQL.MatchString(sql)
+}
+
+// pick returns a healthy replica by policy, or nil to fall back to the primary.
+func (r *ReplicaRouter) pick() *replica {
+	var healthy []*replica
+	for _, rep := range r.replicas {
+		if rep.healthy.Load() {
+			healthy = append(healthy, rep)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	if r.policy == ReplicaLeastLag {
+		best := healthy[0]
+		for _, rep := range healthy[1:] {
+			if rep.lag.Load() < best.lag.Load() {
+				best = rep
+			}
+		}
+		return best
+	}
+	return healthy[r.next.Add(1)%uint64(len(healthy))]
+}
+
+func (r *ReplicaRouter) healthLoop() {
+	defer r.wg.Done()
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-t.C:
+			r.checkAll()
+		}
+	}
+}
+
+func (r *ReplicaRouter) checkAll() {
+	for _, rep := range r.replicas {
+		r.check(rep)
+	}
+}
+
+func (r *ReplicaRouter) check(rep *replica) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+	defer cancel()
+
+	state, err := rep.probe(ctx, rep.db)
+	if err != nil {
+		rep.healthy.Store(false)
+		return
+	}
+	lag, healthy := r.assess(state)
+	rep.lag.Store(int64(lag))
+	rep.healthy.Store(healthy)
+}
+
+// assess turns a probe into the replica's lag and health.
+//
+// A streaming replica that replayed all WAL it received is not behind, however long ago the
+// last commit was (an idle primary sends none), so only pending WAL is measured against the
+// last replayed commit. A replica whose WAL receiver is down stops seeing new writes without
+// knowing it: its lag is the age of the last replayed commit, an upper bound that keeps
+// growing, and without a max lag to compare it against it is unhealthy right away.
+func (r *ReplicaRouter) assess(s replicaState) (time.Duration, bool) {
+	var lag time.Duration
+	switch {
+	case !s.Recovering:
+		return 0, true
+	case !s.Streaming:
+		if s.ReplayAge < 0 || r.maxLag <= 0 {
+			return max(s.ReplayAge, 0), false
+		}
+		lag = s.ReplayAge
+	case s.CaughtUp:
+		return 0, true
+	default:
+		lag = max(s.ReplayAge, 0)
+	}
+	return lag, r.maxLag <= 0 || lag <= r.maxLag
+}
+
+// ReplicaStatus is a point-in-time view of one replica.
+type ReplicaStatus struct {
+	Addr    string
+	Healthy bool
+	Lag     time.Duration
+}
+
+// Status reports the last health check of every replica.
+func (r *ReplicaRouter) Status() []ReplicaStatus {
+	out := make([]ReplicaStatus, 0, len(r.replicas))
+	for _, rep := range r.replicas {
+		out = append(out, ReplicaStatus{Addr: rep.addr, Healthy: rep.healthy.Load(), Lag: time.Duration(rep.lag.Load())})
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIsSelect(t *testing.T) {
+	cases := []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT id FROM orders WHERE id = $1", true},
+		{"  select count(*) from orders", true},
+		{"SELECT * FROM orders WHERE note = 'for review'", true},
+		{"SELECT interval_seconds FROM settings", true},
+		{"INSERT INTO orders (id) VALUES (1)", false},
+		{"WITH x AS (DELETE FROM orders RETURNING id) SELECT id FROM x", false},
+		{"SELECT nextval('orders_id_seq')", false},
+		{"SELECT NEXTVAL ('orders_id_seq')", false},
+		{"SELECT setval('orders_id_seq', 10)", false},
+		{"SELECT pg_advisory_xact_lock(42)", false},
+		{"SELECT pg_try_advisory_lock(42)", false},
+		{"SELECT pg_notify('orders', '1')", false},
+		{"SELECT set_config('app.tenant_id', '7', false)", false},
+		{"SELECT * INTO archive FROM orders", false},
+		{"SELECT * FROM orders FOR UPDATE", false},
+		{"SELECT * FROM orders\nFOR  SHARE", false},
+		{"SELECT * FROM orders FOR NO KEY UPDATE SKIP LOCKED", false},
+	}
+	for _, c := range cases {
+		if got := isSelect(c.sql); got != c.want {
+			t.Errorf("isSelect(%q) = %v, want %v", c.sql, got, c.want)
+		}
+	}
+}
+
+func TestReplicaAssess(t *testing.T) {
+	const maxLag = 10 * time.Second
+	cases := []struct {
+		name        string
+		maxLag      time.Duration
+		state       replicaState
+		wantLag     time.Duration
+		wantHealthy bool
+	}{
+		{"promoted", maxLag, replicaState{Recovering: false, ReplayAge: time.Hour}, 0, true},
+		{"streaming, caught up on an idle primary", maxLag, replicaState{Recovering: true, Streaming: true, CaughtUp: true, ReplayAge: time.Hour}, 0, true},
+		{"streaming, replaying", maxLag, replicaState{Recovering: true, Streaming: true, ReplayAge: 2 * time.Second}, 2 * time.Second, true},
+		{"streaming, too far behind", maxLag, replicaState{Recovering: true, Streaming: true, ReplayAge: time.Minute}, time.Minute, false},
+		{"streaming, no max lag", 0, replicaState{Recovering: true, Streaming: true, ReplayAge: time.Hour}, time.Hour, true},
+		// The receiver is down: replayed WAL always looks caught up, so the replay age counts.
+		{"disconnected, recent", maxLag, replicaState{Recovering: true, CaughtUp: true, ReplayAge: 3 * time.Second}, 3 * time.Second, true},
+		{"disconnected, stale", maxLag, replicaState{Recovering: true, CaughtUp: true, ReplayAge: time.Minute}, time.Minute, false},
+		{"disconnected, nothing replayed", maxLag, replicaState{Recovering: true, CaughtUp: true, ReplayAge: -time.Second}, 0, false},
+		{"disconnected, no max lag", 0, replicaState{Recovering: true, CaughtUp: true, ReplayAge: time.Second}, time.Second, false},
+	}
+	for _, c := range cases {
+		r := NewReplicaRouter(nil, ReplicaRoundRobin, c.maxLag, 0)
+		lag, healthy := r.assess(c.state)
+		if lag != c.wantLag || healthy != c.wantHealthy {
+			t.Errorf("%s: lag %s healthy %v, want %s %v", c.name, lag, healthy, c.wantLag, c.wantHealthy)
+		}
+	}
+}
+
+// probedRouter builds a router whose replicas report the given states (nil: the probe fails).
+func probedRouter(policy string, states map[string]*replicaState) *ReplicaRouter {
+	dbs := make(map[string]*sql.DB, len(states))
+	for addr := range states {
+		dbs[addr] = nil
+	}
+	r := NewReplicaRouter(dbs, policy, 10*time.Second, 0)
+	for _, rep := range r.replicas {
+		state := states[rep.addr]
+		rep.probe = func(context.Context, *sql.DB) (replicaState, error) {
+			if state == nil {
+				return replicaState{}, errors.New("connection refused")
+			}
+			return *state, nil
+		}
+	}
+	r.checkAll()
+	return r
+}
+
+func TestReplicaHealthCheckAndPick(t *testing.T) {
+	r := probedRouter(ReplicaLeastLag, map[string]*replicaState{
+		"down":   nil,
+		"stale":  {Recovering: true, ReplayAge: time.Hour},
+		"behind": {Recovering: true, Streaming: true, ReplayAge: 4 * time.Second},
+		"close":  {Recovering: true, Streaming: true, ReplayAge: time.Second},
+	})
+
+	healthy := map[string]bool{}
+	for _, s := range r.Status() {
+		healthy[s.Addr] = s.Healthy
+	}
+	want := map[string]bool{"down": false, "stale": false, "behind": true, "close": true}
+	if !reflect.DeepEqual(healthy, want) {
+		t.Errorf("health %v, want %v", healthy, want)
+	}
+	if rep := r.pick(); rep == nil || rep.addr != "close" {
+		t.Errorf("least lag picked %v, want close", rep)
+	}
+
+	rr := probedRouter(ReplicaRoundRobin, map[string]*replicaState{
+		"a":     {Recovering: true, Streaming: true, CaughtUp: true},
+		"b":     {Recovering: true, Streaming: true, CaughtUp: true},
+		"stale": {Recovering: true, ReplayAge: time.Hour},
+	})
+	picked := map[string]int{}
+	for range 10 {
+		picked[rr.pick().addr]++
+	}
+	if picked["a"] != 5 || picked["b"] != 5 {
+		t.Errorf("round robin picked %v, want a and b alternately", picked)
+	}
+}
+
+func TestReplicaFallsBackToPrimary(t *testing.T) {
+	r := probedRouter(ReplicaRoundRobin, map[string]*replicaState{
+		"down":         nil,
+		"disconnected": {Recovering: true, ReplayAge: time.Hour},
+	})
+	if rep := r.pick(); rep != nil {
+		t.Errorf("picked %s, want the primary", rep.addr)
+	}
+	if pool := r.ReaderPool(context.Background()); pool != nil {
+		t.Error("ReaderPool returned a replica")
+	}
+}
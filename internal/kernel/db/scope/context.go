@@ -1,20 +1,18 @@
 package scope
 
-import "context"
+import (
+	"context"
 
-type ctxKey string
-
-const ctxTenantKey ctxKey = "tenant"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+)
 
+// WithTenant stores the tenant schema in ctx. It is the same key TenantMiddleware sets,
+// so the plugin and the rest of the framework always agree on the tenant.
 func WithTenant(ctx context.Context, tenant string) context.Context {
-	return context.WithValue(ctx, ctxTenantKey, tenant)
+	return tenantContext.WithSchema(ctx, tenant)
 }
 
+// TenantFrom returns the tenant schema from ctx ("" when absent).
 func TenantFrom(ctx context.Context) string {
-	if v := ctx.Value(ctxTenantKey); v != nil {
-		if s, ok := v.(string); ok {
-			return s
-		}
-	}
-	return ""
+	return tenantContext.SchemaFrom(ctx)
 }
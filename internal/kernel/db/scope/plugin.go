@@ -1,81 +1,247 @@
 package scope
 
 import (
-	"fmt"
-	"skyrix/internal/engine"
+	"errors"
+	"reflect"
 	"strings"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	gormSchema "gorm.io/gorm/schema"
 )
 
-type Plugin struct{}
+// ErrNoTenant is returned in strict mode when a tenant-scoped model (or a raw query using
+// the {{tenant}} placeholder) is executed without a tenant schema in the context.
+var ErrNoTenant = errors.New("schema-router: tenant-scoped query without a tenant in context")
+
+// Raw SQL placeholders replaced with the quoted schema name, e.g.
+// db.Raw(`SELECT * FROM {{tenant}}.orders WHERE id = ?`, id).
+const (
+	TenantPlaceholder = "{{tenant}}"
+	MainPlaceholder   = "{{main}}"
+)
+
+// Plugin qualifies every table with its schema ("schema"."table"), so isolation does not
+// depend on the connection's search_path:
+//   - models embedding MainModel go to the main schema;
+//   - everything else is tenant-scoped and goes to tenantContext.SchemaFrom(ctx);
+//   - tables joined through Joins("Relation") are qualified the same way;
+//   - Preload runs ordinary queries and is covered by the query callback;
+//   - Raw/Exec SQL is left as written, except for the {{tenant}} and {{main}} placeholders.
+//
+// For shared-schema tenants (tenantContext.WithTenantID) tenant-scoped models are additionally
+// filtered by tenant_id and new rows are stamped with it (see TenantOwned and RLSPolicySQL).
+//
+// In strict mode a tenant-scoped statement without a tenant fails with ErrNoTenant;
+// otherwise it falls back to the main schema.
+type Plugin struct {
+	MainSchema string
+	Strict     bool
+}
+
+func NewPlugin(mainSchema string, strict bool) *Plugin {
+	return &Plugin{MainSchema: normalize(mainSchema), Strict: strict}
+}
 
 func (p *Plugin) Name() string { return "schema-router" }
 
 func (p *Plugin) Initialize(db *gorm.DB) error {
 	cb := db.Callback()
-	cb.Query().Before("gorm:query").Register("schema-router:query", p.before)
-	cb.Create().Before("gorm:create").Register("schema-router:create", p.before)
-	cb.Update().Before("gorm:update").Register("schema-router:update", p.before)
-	cb.Delete().Before("gorm:delete").Register("schema-router:delete", p.before)
+	if err := cb.Query().Before("gorm:query").Register("schema-router:query", p.before); err != nil {
+		return err
+	}
+	if err := cb.Create().Before("gorm:create").Register("schema-router:create", p.before); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("schema-router:update", p.before); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("schema-router:delete", p.before); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("schema-router:row", p.before); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("gorm:raw").Register("schema-router:raw", p.before); err != nil {
+		return err
+	}
+
+	// Shared-schema tenants: tenant_id filtering and stamping.
+	if err := cb.Query().Before("gorm:query").Register("schema-router:tenant-filter:query", p.rowFilter); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("schema-router:tenant-filter:row", p.rowFilter); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("schema-router:tenant-filter:update", p.rowWriteFilter); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("schema-router:tenant-filter:delete", p.rowWriteFilter); err != nil {
+		return err
+	}
+	if err := cb.Create().Before("gorm:create").Register("schema-router:tenant-stamp", p.rowStamp); err != nil {
+		return err
+	}
+
+	prev := db.ClauseBuilders["FROM"]
+	db.ClauseBuilders["FROM"] = p.buildFrom(prev)
 	return nil
 }
 
+func normalize(s string) string { return strings.ToLower(strings.TrimSpace(s)) }
+
 func quoteIdent(s string) string {
-	out := `"`
-	for i := 0; i < len(s); i++ {
-		if s[i] == '"' {
-			out += `""`
-		} else {
-			out += string(s[i])
-		}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// schemaFor resolves the schema for scope s. ok is false when the statement must not run.
+func (p *Plugin) schemaFor(db *gorm.DB, s Scope) (string, bool) {
+	if s == Main {
+		return p.MainSchema, true
+	}
+	if t := normalize(TenantFrom(db.Statement.Context)); t != "" {
+		return t, true
+	}
+	if p.Strict {
+		_ = db.AddError(ErrNoTenant)
+		return "", false
 	}
-	return out + `"`
+	return p.MainSchema, true
 }
 
 func (p *Plugin) before(db *gorm.DB) {
-	if db.Statement.TableExpr != nil {
+	if db.Error != nil {
 		return
 	}
+	stmt := db.Statement
 
-	ctx := db.Statement.Context
-	e := engine.EngineFrom(ctx)
-	if e == nil {
+	// Raw / Exec: SQL is already written, only placeholders are resolved.
+	if stmt.SQL.Len() > 0 {
+		p.rewriteRaw(db)
 		return
 	}
-
-	scope := Tenant
-	if s, ok := db.Statement.Model.(SchemaScoped); ok && s != nil {
-		scope = s.SchemaScope()
+	if stmt.TableExpr != nil {
+		return
 	}
 
 	var base string
-	if tb, ok := db.Statement.Model.(TableBasable); ok && tb != nil {
+	if tb, ok := stmt.Model.(TableBasable); ok && tb != nil {
 		base = tb.TableBase()
-	} else if db.Statement.Table != "" {
-		base = db.Statement.Table
-	} else if db.Statement.Schema != nil {
-		base = db.Statement.Schema.Table
+	} else if stmt.Table != "" {
+		base = stmt.Table
+	} else if stmt.Schema != nil {
+		base = stmt.Schema.Table
 	}
-	if base == "" {
+	if base == "" || strings.Contains(base, ".") {
 		return
 	}
-	if strings.Contains(base, ".") {
+
+	schema, ok := p.schemaFor(db, scopeOfStatement(stmt))
+	if !ok {
+		return
+	}
+	stmt.TableExpr = &clause.Expr{SQL: quoteIdent(schema) + "." + quoteIdent(base)}
+}
+
+func (p *Plugin) rewriteRaw(db *gorm.DB) {
+	sql := db.Statement.SQL.String()
+	hasTenant := strings.Contains(sql, TenantPlaceholder)
+	if !hasTenant && !strings.Contains(sql, MainPlaceholder) {
 		return
 	}
 
-	var schema string
-	if scope == Main {
-		schema = e.Main()
-	} else {
-		if t := TenantFrom(ctx); t != "" {
-			schema = t
-		} else {
-			schema = e.Main()
+	sql = strings.ReplaceAll(sql, MainPlaceholder, quoteIdent(p.MainSchema))
+	if hasTenant {
+		schema, ok := p.schemaFor(db, Tenant)
+		if !ok {
+			return
 		}
+		sql = strings.ReplaceAll(sql, TenantPlaceholder, quoteIdent(schema))
 	}
+	db.Statement.SQL.Reset()
+	db.Statement.SQL.WriteString(sql)
+}
+
+// buildFrom wraps the FROM clause builder to qualify tables joined via Joins("Relation").
+// String joins (Joins("JOIN x ON ...")) are expressions and are left untouched.
+func (p *Plugin) buildFrom(prev clause.ClauseBuilder) clause.ClauseBuilder {
+	return func(c clause.Clause, builder clause.Builder) {
+		if stmt, ok := builder.(*gorm.Statement); ok && stmt.DB.Error == nil {
+			if from, ok := c.Expression.(clause.From); ok && len(from.Joins) > 0 {
+				joins := make([]clause.Join, len(from.Joins))
+				copy(joins, from.Joins)
+				for i := range joins {
+					name := joins[i].Table.Name
+					if name == "" || name == clause.CurrentTable || strings.Contains(name, ".") {
+						continue
+					}
+					schema, ok := p.schemaFor(stmt.DB, scopeOfTable(stmt.Schema, name))
+					if !ok {
+						return
+					}
+					// Raw keeps the builder from re-quoting the qualified name, so the alias
+					// (the relation name, referenced quoted in ON) is quoted here as well.
+					joins[i].Table.Name = quoteIdent(schema) + "." + quoteIdent(name)
+					if joins[i].Table.Alias != "" {
+						joins[i].Table.Alias = quoteIdent(joins[i].Table.Alias)
+					}
+					joins[i].Table.Raw = true
+				}
+				from.Joins = joins
+				c.Expression = from
+			}
+		}
+		if prev != nil {
+			prev(c, builder)
+			return
+		}
+		c.Build(builder)
+	}
+}
 
-	full := fmt.Sprintf("%s.%s", quoteIdent(schema), quoteIdent(base))
-	db.Statement.TableExpr = &clause.Expr{SQL: full}
+// scopeOfStatement reads the scope from the statement's model, falling back to the parsed
+// schema's model type (Find(&[]T{}) and Preload pass slices, not models).
+func scopeOfStatement(stmt *gorm.Statement) Scope {
+	if s, ok := stmt.Model.(SchemaScoped); ok && s != nil {
+		return s.SchemaScope()
+	}
+	if stmt.Schema != nil {
+		return scopeOfType(stmt.Schema.ModelType)
+	}
+	return Tenant
+}
+
+func scopeOfType(t reflect.Type) Scope {
+	if t == nil {
+		return Tenant
+	}
+	if s, ok := reflect.New(t).Interface().(SchemaScoped); ok {
+		return s.SchemaScope()
+	}
+	return Tenant
+}
+
+// scopeOfTable finds the model behind a joined table among the relationships reachable from s.
+func scopeOfTable(s *gormSchema.Schema, table string) Scope {
+	seen := map[*gormSchema.Schema]bool{}
+	var walk func(*gormSchema.Schema) (Scope, bool)
+	walk = func(s *gormSchema.Schema) (Scope, bool) {
+		if s == nil || seen[s] {
+			return Tenant, false
+		}
+		seen[s] = true
+		for _, rel := range s.Relationships.Relations {
+			if rel.FieldSchema != nil && rel.FieldSchema.Table == table {
+				return scopeOfType(rel.FieldSchema.ModelType), true
+			}
+		}
+		for _, rel := range s.Relationships.Relations {
+			if sc, ok := walk(rel.FieldSchema); ok {
+				return sc, true
+			}
+		}
+		return Tenant, false
+	}
+	sc, _ := walk(s)
+	return sc
 }
@@ -0,0 +1,71 @@
+package scope
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+type testCompany struct {
+	ID   int64
+	Name string
+}
+
+func (testCompany) TableName() string { return "companies" }
+
+type testUser struct {
+	MainModel
+	ID        int64
+	CompanyID int64
+	Company   testCompany
+}
+
+func (testUser) TableName() string { return "users" }
+
+// dryRunDB builds SQL without a server (DryRun, no ping, no implicit transaction for writes).
+func dryRunDB(t *testing.T, strict bool) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 gormLogger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(NewPlugin("Core", strict)); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestPluginQuotesJoinedTables(t *testing.T) {
+	db := dryRunDB(t, true)
+	// A dot inside a schema name only survives if the qualified name is quoted by parts.
+	ctx := WithTenant(context.Background(), "Acme.EU")
+
+	var users []testUser
+	sql := db.WithContext(ctx).Joins("Company").Find(&users).Statement.SQL.String()
+
+	for _, want := range []string{
+		`FROM "core"."users"`,
+		`LEFT JOIN "acme.eu"."companies" "Company" ON "users"."company_id" = "Company"."id"`,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("SQL %q does not contain %q", sql, want)
+		}
+	}
+}
+
+func TestPluginStrictWithoutTenant(t *testing.T) {
+	db := dryRunDB(t, true)
+	var companies []testCompany
+	if err := db.WithContext(context.Background()).Find(&companies).Error; err != ErrNoTenant {
+		t.Fatalf("got %v, want ErrNoTenant", err)
+	}
+}
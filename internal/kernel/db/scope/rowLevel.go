@@ -0,0 +1,179 @@
+package scope
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	gormSchema "gorm.io/gorm/schema"
+)
+
+// TenantIDColumn is the column shared-schema tables are partitioned by.
+const TenantIDColumn = "tenant_id"
+
+// ErrTenantMismatch is returned when a row being created belongs to another tenant.
+var ErrTenantMismatch = errors.New("schema-router: row belongs to another tenant")
+
+// ErrNoTenantColumn is returned in strict mode when a shared-schema tenant queries a
+// tenant-scoped model without a tenant_id column (its rows could not be separated).
+var ErrNoTenantColumn = errors.New("schema-router: shared-schema tenant queried a model without tenant_id")
+
+// TenantOwned adds the tenant_id column used by shared-schema (row-level) tenancy.
+// The same model works for schema-per-tenant tenants, where tenant_id is simply not filtered.
+type TenantOwned struct {
+	TenantID int64 `gorm:"column:tenant_id;not null;index"`
+}
+
+// rowFilter adds "tenant_id = <ctx tenant>" to queries, updates and deletes of tenant-scoped
+// models while ctx carries a shared-schema tenant (tenantContext.WithTenantID).
+func (p *Plugin) rowFilter(db *gorm.DB) {
+	id, field, ok := p.rowTenant(db)
+	if !ok || field == nil {
+		return
+	}
+	p.addRowFilter(db, id, field)
+}
+
+// rowWriteFilter is rowFilter for updates and deletes. The tenant condition alone would satisfy
+// GORM's missing-WHERE check, turning an unconditioned Update or Delete into one over every row
+// of the tenant, so such statements fail with gorm.ErrMissingWhereClause unless
+// AllowGlobalUpdate is set.
+func (p *Plugin) rowWriteFilter(db *gorm.DB) {
+	id, field, ok := p.rowTenant(db)
+	if !ok || field == nil {
+		return
+	}
+	if !db.AllowGlobalUpdate && !hasConditions(db.Statement) {
+		_ = db.AddError(gorm.ErrMissingWhereClause)
+		return
+	}
+	p.addRowFilter(db, id, field)
+}
+
+// hasConditions reports whether an update or delete has a WHERE of its own or will get one from
+// the primary key of its value or model, as GORM adds it while building the statement.
+func hasConditions(stmt *gorm.Statement) bool {
+	if _, ok := stmt.Clauses["WHERE"]; ok {
+		return true
+	}
+	if stmt.Schema == nil || len(stmt.Schema.PrimaryFields) == 0 {
+		return false
+	}
+	if _, values := gormSchema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields); len(values) > 0 {
+		return true
+	}
+	if stmt.Model != nil && stmt.Model != stmt.Dest {
+		_, values := gormSchema.GetIdentityFieldValuesMap(stmt.Context, reflect.ValueOf(stmt.Model), stmt.Schema.PrimaryFields)
+		return len(values) > 0
+	}
+	return false
+}
+
+func (p *Plugin) addRowFilter(db *gorm.DB, id int64, field *gormSchema.Field) {
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: id},
+	}})
+}
+
+// rowStamp sets tenant_id on rows being created and rejects rows of another tenant.
+func (p *Plugin) rowStamp(db *gorm.DB) {
+	id, field, ok := p.rowTenant(db)
+	if !ok || field == nil {
+		return
+	}
+	ctx := db.Statement.Context
+	set := func(rv reflect.Value) {
+		rv = reflect.Indirect(rv)
+		if rv.Kind() != reflect.Struct {
+			return
+		}
+		v, zero := field.ValueOf(ctx, rv)
+		if !zero {
+			if rowID, ok := asTenantID(v); !ok || rowID != id {
+				_ = db.AddError(fmt.Errorf("%w (row %v, ctx %d)", ErrTenantMismatch, v, id))
+				return
+			}
+		}
+		if err := field.Set(ctx, rv, id); err != nil {
+			_ = db.AddError(err)
+		}
+	}
+
+	switch rv := db.Statement.ReflectValue; rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			set(rv.Index(i))
+		}
+	case reflect.Struct:
+		set(rv)
+	}
+}
+
+// asTenantID converts a tenant_id field value to int64. Models may declare the column as any
+// integer type, a pointer to one or a driver.Valuer such as sql.NullInt64; ok is false for
+// anything else and for NULL.
+func asTenantID(v any) (int64, bool) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil || dv == nil {
+			return 0, false
+		}
+		v = dv
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return 0, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return int64(u), true
+		}
+	}
+	return 0, false
+}
+
+// rowTenant returns the shared-schema tenant of the statement and the model's tenant_id field.
+// ok is false when no filtering applies (no shared tenant, raw SQL, main-scoped model).
+func (p *Plugin) rowTenant(db *gorm.DB) (int64, *gormSchema.Field, bool) {
+	if db.Error != nil {
+		return 0, nil, false
+	}
+	stmt := db.Statement
+	id, shared := tenantContext.TenantIDFrom(stmt.Context)
+	if !shared || stmt.SQL.Len() > 0 || stmt.Schema == nil || scopeOfStatement(stmt) == Main {
+		return 0, nil, false
+	}
+	field := stmt.Schema.LookUpField(TenantIDColumn)
+	if field == nil && p.Strict {
+		_ = db.AddError(fmt.Errorf("%w: %s", ErrNoTenantColumn, stmt.Schema.Table))
+	}
+	return id, field, field != nil
+}
+
+// RLSPolicySQL returns the statements enabling Postgres row-level security on a shared table,
+// as defense in depth behind the plugin's filter. Rows are visible only while app.tenant_id
+// (set by the framework per transaction and pinned connection) matches tenant_id.
+// Identifiers are quoted, so schema and table names are taken as written (case included).
+func RLSPolicySQL(schema, table string) []string {
+	qualified := quoteIdent(schema) + "." + quoteIdent(table)
+	policy := quoteIdent(table + "_tenant_isolation")
+	cond := fmt.Sprintf("%s = NULLIF(current_setting('app.tenant_id', true), '')::bigint", TenantIDColumn)
+	return []string{
+		"ALTER TABLE " + qualified + " ENABLE ROW LEVEL SECURITY",
+		"ALTER TABLE " + qualified + " FORCE ROW LEVEL SECURITY",
+		"DROP POLICY IF EXISTS " + policy + " ON " + qualified,
+		"CREATE POLICY " + policy + " ON " + qualified + " USING (" + cond + ") WITH CHECK (" + cond + ")",
+	}
+}
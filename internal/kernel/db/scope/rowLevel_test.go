@@ -0,0 +1,122 @@
+package scope
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+
+	"gorm.io/gorm"
+)
+
+type testIntNote struct {
+	ID       int64
+	TenantID int `gorm:"column:tenant_id"`
+}
+
+func (testIntNote) TableName() string { return "notes" }
+
+type testNullNote struct {
+	ID       int64
+	TenantID sql.NullInt64 `gorm:"column:tenant_id"`
+}
+
+func (testNullNote) TableName() string { return "notes" }
+
+type testUintNote struct {
+	ID       int64
+	TenantID *uint32 `gorm:"column:tenant_id"`
+}
+
+func (testUintNote) TableName() string { return "notes" }
+
+func sharedTenant(id int64) context.Context {
+	return tenantContext.WithTenantID(WithTenant(context.Background(), "shared"), id)
+}
+
+func TestRowStampAcceptsOwnTenantOfAnyIntegerType(t *testing.T) {
+	db := dryRunDB(t, true)
+	ctx := sharedTenant(7)
+	seven := uint32(7)
+
+	for name, row := range map[string]any{
+		"int":        &testIntNote{TenantID: 7},
+		"NullInt64":  &testNullNote{TenantID: sql.NullInt64{Int64: 7, Valid: true}},
+		"*uint32":    &testUintNote{TenantID: &seven},
+		"int unset":  &testIntNote{},
+		"null unset": &testNullNote{},
+	} {
+		if err := db.WithContext(ctx).Create(row).Error; err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestRowStampRejectsOtherTenant(t *testing.T) {
+	db := dryRunDB(t, true)
+	ctx := sharedTenant(7)
+	eight := uint32(8)
+
+	for name, row := range map[string]any{
+		"int":       &testIntNote{TenantID: 8},
+		"NullInt64": &testNullNote{TenantID: sql.NullInt64{Int64: 8, Valid: true}},
+		"*uint32":   &testUintNote{TenantID: &eight},
+	} {
+		if err := db.WithContext(ctx).Create(row).Error; !errors.Is(err, ErrTenantMismatch) {
+			t.Errorf("%s: got %v, want ErrTenantMismatch", name, err)
+		}
+	}
+}
+
+func TestRowStampSetsTenant(t *testing.T) {
+	db := dryRunDB(t, true)
+	ctx := sharedTenant(7)
+
+	rows := []testIntNote{{}, {TenantID: 7}}
+	if err := db.WithContext(ctx).Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range rows {
+		if row.TenantID != 7 {
+			t.Errorf("row %d: tenant_id = %d, want 7", i, row.TenantID)
+		}
+	}
+}
+
+func TestRowFilterKeepsMissingWhereCheck(t *testing.T) {
+	db := dryRunDB(t, true).WithContext(sharedTenant(7))
+
+	for name, run := range map[string]func() *gorm.DB{
+		"update": func() *gorm.DB { return db.Model(&testIntNote{}).Update("id", 1) },
+		"delete": func() *gorm.DB { return db.Delete(&testIntNote{}) },
+	} {
+		if err := run().Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+			t.Errorf("unconditioned %s: got %v, want ErrMissingWhereClause", name, err)
+		}
+	}
+
+	for name, run := range map[string]func() *gorm.DB{
+		"update by key":   func() *gorm.DB { return db.Model(&testIntNote{ID: 5}).Update("id", 6) },
+		"update by where": func() *gorm.DB { return db.Model(&testIntNote{}).Where("id = ?", 5).Update("id", 6) },
+		"delete by key":   func() *gorm.DB { return db.Delete(&testIntNote{ID: 5}) },
+		"delete by where": func() *gorm.DB { return db.Where("id = ?", 5).Delete(&testIntNote{}) },
+		"global update": func() *gorm.DB {
+			return db.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&testIntNote{}).Update("id", 6)
+		},
+		"global delete": func() *gorm.DB {
+			return db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&testIntNote{})
+		},
+	} {
+		res := run()
+		if res.Error != nil {
+			t.Errorf("%s: %v", name, res.Error)
+			continue
+		}
+		if sql := res.Statement.SQL.String(); !strings.Contains(sql, `"notes"."tenant_id" = `) {
+			t.Errorf("%s is not filtered by tenant: %s", name, sql)
+		}
+	}
+}
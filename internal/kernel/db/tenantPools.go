@@ -0,0 +1,372 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"skyrix/internal/config"
+	"skyrix/internal/engine"
+	"skyrix/internal/engine/metrics"
+	"skyrix/internal/kernel/db/scope"
+	"skyrix/internal/logger"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// ErrTenantPoolsClosed is returned by Pool after Close.
+var ErrTenantPoolsClosed = errors.New("tenant pools are closed")
+
+type tenantPool struct {
+	ref      string
+	db       *gorm.DB
+	lastUsed time.Time // last Acquire or release
+	refs     int       // open leases
+	retired  bool      // unlinked from the LRU; closed when the last lease is released
+}
+
+// pending is a pool being opened; concurrent requests for the same ref wait on done.
+type pending struct {
+	done chan struct{}
+	err  error
+}
+
+// TenantPools keeps the connection pools of tenants with a dedicated database:
+//   - pools are opened lazily on the first request of the tenant;
+//   - callers lease a pool (Acquire) for a unit of work; a leased pool is never closed;
+//   - at most cfg.TenantDBMaxPools stay open, the least recently used unleased one is closed
+//     first (while every pool is leased the limit is exceeded until leases are released);
+//   - every cfg.TenantDBHealthInterval, unleased pools unused for cfg.TenantDBIdleTimeout are
+//     closed, and pools failing a ping are retired: closed once their last lease is released.
+//
+// Each pool gets the schema-router plugin, so routing inside a tenant database works as on
+// the main one. Read replicas are not used for tenant databases.
+type TenantPools struct {
+	cfg     *config.Database
+	log     logger.Interface
+	metrics *metrics.Metrics // nil: statements are not recorded
+	// ResolveDSN turns a reference into a DSN (default ResolveDSN).
+	ResolveDSN func(ref string) (string, error)
+	// openPool opens the pool of ref (default: open); replaced in tests.
+	openPool func(ctx context.Context, ref string) (*gorm.DB, error)
+
+	mu      sync.Mutex
+	lru     *list.List // of *tenantPool, most recently used first
+	byRef   map[string]*list.Element
+	opening map[string]*pending
+	closed  bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+var _ engine.TenantPools = (*TenantPools)(nil)
+
+func NewTenantPools(cfg *config.Database, log logger.Interface, m *metrics.Metrics) *TenantPools {
+	p := &TenantPools{
+		cfg:        cfg,
+		log:        log,
+		metrics:    m,
+		ResolveDSN: ResolveDSN,
+		lru:        list.New(),
+		byRef:      map[string]*list.Element{},
+		opening:    map[string]*pending{},
+		stop:       make(chan struct{}),
+	}
+	p.openPool = p.open
+	p.wg.Add(1)
+	go p.maintain()
+	return p
+}
+
+// ErrInlineDSN is returned for a tenant database given as a DSN rather than a reference:
+// tenant rows are cached (Redis) and logged, so they must not carry credentials.
+var ErrInlineDSN = errors.New(`tenant database must be a secret reference ("env:NAME" or "file:/path"), not a DSN`)
+
+// ResolveDSN resolves a tenant database reference: "env:NAME" reads the environment variable,
+// "file:/path" reads the file (trimmed). Anything else fails with ErrInlineDSN.
+func ResolveDSN(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	var dsn string
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		dsn = os.Getenv(strings.TrimPrefix(ref, "env:"))
+	case strings.HasPrefix(ref, "file:"):
+		b, err := os.ReadFile(strings.TrimPrefix(ref, "file:"))
+		if err != nil {
+			return "", err
+		}
+		dsn = string(b)
+	default:
+		return "", ErrInlineDSN
+	}
+	if dsn = strings.TrimSpace(dsn); dsn == "" {
+		return "", fmt.Errorf("tenant database %s: empty DSN", redactRef(ref))
+	}
+	return dsn, nil
+}
+
+// redactRef makes ref safe to log: secret references are shown, inline DSNs are not.
+func redactRef(ref string) string {
+	if strings.HasPrefix(ref, "env:") || strings.HasPrefix(ref, "file:") {
+		return ref
+	}
+	return "<inline dsn>"
+}
+
+// Acquire leases the pool for ref, opening it on first use. Concurrent first requests share
+// one open.
+func (p *TenantPools) Acquire(ctx context.Context, ref string) (*gorm.DB, func(), error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, nil, ErrTenantPoolsClosed
+		}
+		if el, ok := p.byRef[ref]; ok {
+			tp := el.Value.(*tenantPool)
+			tp.refs++
+			tp.lastUsed = time.Now()
+			p.lru.MoveToFront(el)
+			p.mu.Unlock()
+			return tp.db, p.releaser(tp), nil
+		}
+		if op, ok := p.opening[ref]; ok {
+			p.mu.Unlock()
+			select {
+			case <-op.done:
+				if op.err != nil {
+					return nil, nil, op.err
+				}
+				continue // lease the pool just opened
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+		}
+		op := &pending{done: make(chan struct{})}
+		p.opening[ref] = op
+		p.mu.Unlock()
+
+		db, err := p.openPool(ctx, ref)
+
+		p.mu.Lock()
+		delete(p.opening, ref)
+		op.err = err
+		if err == nil && p.closed {
+			op.err = ErrTenantPoolsClosed
+		}
+		var tp *tenantPool
+		var evicted []*tenantPool
+		if op.err == nil {
+			tp = &tenantPool{ref: ref, db: db, lastUsed: time.Now(), refs: 1}
+			p.byRef[ref] = p.lru.PushFront(tp)
+			evicted = p.trimLocked()
+		}
+		close(op.done)
+		p.mu.Unlock()
+
+		if err == nil && op.err != nil {
+			p.closePool(&tenantPool{ref: ref, db: db}, "shutdown")
+		}
+		for _, e := range evicted {
+			p.closePool(e, "evicted")
+		}
+		if op.err != nil {
+			return nil, nil, op.err
+		}
+		return tp.db, p.releaser(tp), nil
+	}
+}
+
+// releaser returns the release func of one lease of tp.
+func (p *TenantPools) releaser(tp *tenantPool) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			tp.refs--
+			tp.lastUsed = time.Now()
+			var toClose []*tenantPool
+			reason := "evicted"
+			if tp.retired {
+				if tp.refs == 0 {
+					toClose = append(toClose, tp)
+				}
+				reason = "retired"
+			} else if tp.refs == 0 {
+				toClose = p.trimLocked()
+			}
+			p.mu.Unlock()
+
+			for _, c := range toClose {
+				p.closePool(c, reason)
+			}
+		})
+	}
+}
+
+func (p *TenantPools) open(ctx context.Context, ref string) (*gorm.DB, error) {
+	dsn, err := p.ResolveDSN(ref)
+	if err != nil {
+		return nil, err
+	}
+	gdb, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:               gormLogger.Default.LogMode(gormLogLevel(p.cfg.LogLevel)),
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("tenant database %s: %w", redactRef(ref), err)
+	}
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(p.cfg.TenantDBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(p.cfg.TenantDBMaxOpenConns)
+	sqlDB.SetConnMaxLifetime(p.cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(p.cfg.ConnMaxIdleTime)
+
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := sqlDB.PingContext(pingCtx); err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("tenant database %s: %w", redactRef(ref), err)
+	}
+	if err := gdb.Use(scope.NewPlugin(p.cfg.MainSchema, p.cfg.TenantStrict)); err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+	if p.metrics != nil {
+		if err := gdb.Use(NewMetricsPlugin(p.metrics)); err != nil {
+			_ = sqlDB.Close()
+			return nil, err
+		}
+	}
+	p.log.Info("tenant database opened", "database", redactRef(ref))
+	return gdb, nil
+}
+
+// trimLocked unlinks the least recently used unleased pools beyond cfg.TenantDBMaxPools;
+// the caller closes them outside the lock.
+func (p *TenantPools) trimLocked() []*tenantPool {
+	limit := p.cfg.TenantDBMaxPools
+	var evicted []*tenantPool
+	for el := p.lru.Back(); el != nil && limit > 0 && p.lru.Len() > limit; {
+		prev := el.Prev()
+		if el.Value.(*tenantPool).refs == 0 {
+			evicted = append(evicted, p.retireLocked(el))
+		}
+		el = prev
+	}
+	return evicted
+}
+
+// retireLocked unlinks el so no new lease picks it up. The pool must be closed by the caller
+// if it has no lease left, otherwise by the release of its last lease.
+func (p *TenantPools) retireLocked(el *list.Element) *tenantPool {
+	tp := p.lru.Remove(el).(*tenantPool)
+	delete(p.byRef, tp.ref)
+	tp.retired = true
+	return tp
+}
+
+// closePool closes tp. database/sql lets queries already running finish first.
+func (p *TenantPools) closePool(tp *tenantPool, reason string) {
+	if sqlDB, err := tp.db.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+	p.log.Info("tenant database closed", "database", redactRef(tp.ref), "reason", reason)
+}
+
+func (p *TenantPools) maintain() {
+	defer p.wg.Done()
+	interval := p.cfg.TenantDBHealthInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-t.C:
+			p.check(interval)
+		}
+	}
+}
+
+// check closes idle pools and retires pools failing a ping.
+func (p *TenantPools) check(timeout time.Duration) {
+	p.mu.Lock()
+	var idle []*tenantPool
+	var live []*tenantPool
+	for el := p.lru.Front(); el != nil; {
+		next := el.Next()
+		tp := el.Value.(*tenantPool)
+		if tp.refs == 0 && p.cfg.TenantDBIdleTimeout > 0 && time.Since(tp.lastUsed) > p.cfg.TenantDBIdleTimeout {
+			idle = append(idle, p.retireLocked(el))
+		} else {
+			live = append(live, tp)
+		}
+		el = next
+	}
+	p.mu.Unlock()
+
+	for _, tp := range idle {
+		p.closePool(tp, "idle")
+	}
+	for _, tp := range live {
+		sqlDB, err := tp.db.DB()
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			err = sqlDB.PingContext(ctx)
+			cancel()
+		}
+		if err == nil {
+			continue
+		}
+		p.log.Warn("tenant database unhealthy", "database", redactRef(tp.ref), "error", err)
+		p.mu.Lock()
+		el, ok := p.byRef[tp.ref]
+		closeNow := false
+		if ok && el.Value.(*tenantPool) == tp {
+			p.retireLocked(el)
+			closeNow = tp.refs == 0
+		}
+		p.mu.Unlock()
+		if closeNow {
+			p.closePool(tp, "unhealthy")
+		}
+	}
+}
+
+// Close stops maintenance and closes every pool without a lease; leased pools are closed when
+// their last lease is released. Acquire fails with ErrTenantPoolsClosed afterwards.
+func (p *TenantPools) Close() error {
+	p.once.Do(func() { close(p.stop) })
+	p.wg.Wait()
+
+	p.mu.Lock()
+	p.closed = true
+	var unleased []*tenantPool
+	for p.lru.Len() > 0 {
+		if tp := p.retireLocked(p.lru.Front()); tp.refs == 0 {
+			unleased = append(unleased, tp)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, tp := range unleased {
+		p.closePool(tp, "shutdown")
+	}
+	return nil
+}
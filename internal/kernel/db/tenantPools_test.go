@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"skyrix/internal/config"
+	"skyrix/internal/logger"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// testPools returns TenantPools whose pools are never connected (nothing listens on port 1),
+// so opening is instant and pings fail.
+func testPools(t *testing.T, cfg config.Database) *TenantPools {
+	t.Helper()
+	cfg.TenantDBHealthInterval = time.Hour // checks are run by hand
+	p := NewTenantPools(&cfg, logger.NewSlogWrapper(slog.New(slog.DiscardHandler)), nil)
+	p.openPool = func(context.Context, string) (*gorm.DB, error) {
+		return gorm.Open(postgres.Open("host=127.0.0.1 port=1"), &gorm.Config{
+			DisableAutomaticPing: true,
+			Logger:               gormLogger.Discard,
+		})
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	return p
+}
+
+func isClosed(t *testing.T, db *gorm.DB) bool {
+	t.Helper()
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sqlDB.Conn(context.Background())
+	return err != nil && strings.Contains(err.Error(), "database is closed")
+}
+
+func TestTenantPoolsSharesOnePoolPerRef(t *testing.T) {
+	p := testPools(t, config.Database{})
+	ctx := context.Background()
+
+	a, releaseA, err := p.Acquire(ctx, "env:A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, releaseB, err := p.Acquire(ctx, "env:A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatal("two leases of one ref got different pools")
+	}
+	releaseA()
+	releaseA() // a second call is a no-op
+	releaseB()
+	if isClosed(t, a) {
+		t.Fatal("released pool was closed without eviction")
+	}
+}
+
+func TestTenantPoolsEvictionWaitsForLeases(t *testing.T) {
+	p := testPools(t, config.Database{TenantDBMaxPools: 1})
+	ctx := context.Background()
+
+	a, releaseA, err := p.Acquire(ctx, "env:A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, releaseB, err := p.Acquire(ctx, "env:B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isClosed(t, a) {
+		t.Fatal("leased pool was closed by LRU eviction")
+	}
+
+	releaseA()
+	if !isClosed(t, a) {
+		t.Fatal("pool beyond the limit was not closed after its last release")
+	}
+	releaseB()
+	if isClosed(t, b) {
+		t.Fatal("pool within the limit was closed")
+	}
+}
+
+func TestTenantPoolsIdleCheckSkipsLeasedPools(t *testing.T) {
+	p := testPools(t, config.Database{TenantDBIdleTimeout: time.Nanosecond})
+	ctx := context.Background()
+
+	idle, releaseIdle, err := p.Acquire(ctx, "env:IDLE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseIdle()
+	busy, releaseBusy, err := p.Acquire(ctx, "env:BUSY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	// A job outliving the idle timeout keeps its pool; the failing ping only retires it.
+	p.check(time.Second)
+	if !isClosed(t, idle) {
+		t.Fatal("idle pool was not closed")
+	}
+	if isClosed(t, busy) {
+		t.Fatal("leased pool was closed by the health check")
+	}
+
+	releaseBusy()
+	if !isClosed(t, busy) {
+		t.Fatal("retired pool was not closed after its last release")
+	}
+	again, releaseAgain, err := p.Acquire(ctx, "env:BUSY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseAgain()
+	if again == busy {
+		t.Fatal("retired pool was handed out again")
+	}
+}
+
+func TestTenantPoolsCloseWaitsForLeases(t *testing.T) {
+	p := testPools(t, config.Database{})
+	ctx := context.Background()
+
+	db, release, err := p.Acquire(ctx, "env:A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if isClosed(t, db) {
+		t.Fatal("leased pool was closed on shutdown")
+	}
+	release()
+	if !isClosed(t, db) {
+		t.Fatal("pool was not closed after its last release")
+	}
+	if _, _, err := p.Acquire(ctx, "env:A"); !errors.Is(err, ErrTenantPoolsClosed) {
+		t.Fatalf("Acquire after Close: got %v, want ErrTenantPoolsClosed", err)
+	}
+}
+
+func TestResolveDSNRejectsInlineDSN(t *testing.T) {
+	t.Setenv("SKYRIX_TEST_TENANT_DSN", " postgres://u:p@db/t ")
+	if dsn, err := ResolveDSN("env:SKYRIX_TEST_TENANT_DSN"); err != nil || dsn != "postgres://u:p@db/t" {
+		t.Fatalf("env reference: got %q, %v", dsn, err)
+	}
+	if _, err := ResolveDSN("postgres://u:p@db/t"); !errors.Is(err, ErrInlineDSN) {
+		t.Fatalf("inline DSN: got %v, want ErrInlineDSN", err)
+	}
+}
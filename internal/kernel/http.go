@@ -2,14 +2,21 @@ package kernel
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"skyrix/internal/config"
 	"skyrix/internal/logger"
+	"time"
 )
 
 // HTTPApp is the final runnable HTTP application.
 type HTTPApp struct {
-	Server *http.Server
-	Kernel *Kernel
+	Server    *http.Server
+	Kernel    *Kernel
+	Lifecycle *Lifecycle
+
+	shutdownGrace   time.Duration
+	shutdownTimeout time.Duration
 }
 
 // NewHTTPApp is now a very simple constructor.
@@ -17,10 +24,15 @@ type HTTPApp struct {
 func NewHTTPApp(
 	server *http.Server,
 	kernel *Kernel,
+	lifecycle *Lifecycle,
+	cfg *config.HttpServer,
 ) (*HTTPApp, error) {
 	return &HTTPApp{
-		Server: server,
-		Kernel: kernel,
+		Server:          server,
+		Kernel:          kernel,
+		Lifecycle:       lifecycle,
+		shutdownGrace:   cfg.ShutdownGrace,
+		shutdownTimeout: cfg.ShutdownTimeout,
 	}, nil
 }
 
@@ -39,10 +51,45 @@ func (a *HTTPApp) Run(ctx context.Context, log logger.Interface) error {
 
 	select {
 	case <-ctx.Done():
-		log.Info("HTTP server shutting down")
-		_ = a.Server.Shutdown(context.Background())
-		return nil
+		return a.shutdown(log)
 	case err := <-errCh:
+		ctx, cancel := a.stopContext()
+		defer cancel()
+		_ = a.Lifecycle.Stop(ctx)
 		return err
 	}
 }
+
+// shutdown fails readiness, keeps serving for the grace period so load balancers notice,
+// then drains in-flight requests and runs the stop hooks, all within the shutdown timeout. Connections still open at the deadline are closed.
+func (a *HTTPApp) shutdown(log logger.Interface) error {
+	a.Lifecycle.Drain()
+	if a.shutdownGrace > 0 {
+		log.Info("HTTP server shutting down, waiting for load balancers", "grace", a.shutdownGrace)
+		time.Sleep(a.shutdownGrace)
+	}
+
+	ctx, cancel := a.stopContext()
+	defer cancel()
+
+	log.Info("HTTP server draining", "timeout", a.shutdownTimeout)
+	var errs []error
+	if err := a.Server.Shutdown(ctx); err != nil {
+		log.Warn("HTTP server did not drain in time, closing connections", "error", err)
+		_ = a.Server.Close()
+		errs = append(errs, err)
+	}
+	if err := a.Lifecycle.Stop(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	log.Info("HTTP server stopped")
+	return errors.Join(errs...)
+}
+
+// stopContext bounds draining and the stop hooks by the shutdown timeout, if one is set.
+func (a *HTTPApp) stopContext() (context.Context, context.CancelFunc) {
+	if a.shutdownTimeout > 0 {
+		return context.WithTimeout(context.Background(), a.shutdownTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
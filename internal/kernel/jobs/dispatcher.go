@@ -0,0 +1,280 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"skyrix/internal/engine"
+	"skyrix/internal/engine/cache"
+	engineJobs "skyrix/internal/engine/jobs"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+	"skyrix/internal/logger"
+)
+
+const defaultUniqueTTL = 10 * time.Minute
+
+// Dispatcher runs registered jobs asynchronously in-process and implements
+// unique, debounce, throttle and delayed execution on top of cache.TenantCache and engine.Locker.
+//
+// Keys are scoped by job name and by the tenant in ctx (TenantCache's tenant scope; jobs
+// enqueued without a tenant use its global scope), so the same key for two tenants never
+// collides. Cache state and unique leases live in Redis, which makes unique/throttle/debounce
+// decisions consistent across instances; timers and execution are local to this process.
+type Dispatcher struct {
+	jobs      *Registry
+	cache     *cache.TenantCache
+	locker    engine.Locker
+	log       logger.Interface
+	uniqueTTL time.Duration
+
+	runs *engineJobs.Tracker // pending and running runs
+	// pending is cancelled by Stop: runs still waiting for their time are dropped.
+	pending context.Context
+	drop    context.CancelFunc
+	// running is cancelled when Stop gives up waiting: running jobs see their ctx cancelled.
+	running context.Context
+	abort   context.CancelFunc
+}
+
+// NewDispatcher creates a Dispatcher; Stop (or Close) ends it.
+// It takes the concrete *Registry (not the populated engineJobs.Registry) so that jobs may depend
+// on the dispatcher without a dependency cycle; lookups happen at Enqueue time, after registration.
+func NewDispatcher(jobs *Registry, c *cache.TenantCache, locker engine.Locker, log logger.Interface) *Dispatcher {
+	pending, drop := context.WithCancel(context.Background())
+	running, abort := context.WithCancel(context.Background())
+	return &Dispatcher{
+		jobs:      jobs,
+		cache:     c,
+		locker:    locker,
+		log:       log,
+		uniqueTTL: defaultUniqueTTL,
+		runs:      engineJobs.NewTracker(),
+		pending:   pending,
+		drop:      drop,
+		running:   running,
+		abort:     abort,
+	}
+}
+
+// Stop refuses new runs, drops pending (delayed/debounced) ones and waits for running ones until
+// ctx is done; then it cancels them and returns ctx.Err().
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.drop()
+	err := d.runs.Drain(ctx)
+	if err != nil {
+		d.log.Warn("async jobs still running at shutdown deadline, cancelling them", "running", d.runs.Running())
+		d.abort()
+	}
+	return err
+}
+
+// Close stops the dispatcher right away: pending runs are dropped, running ones cancelled, and
+// Close waits for them to return.
+func (d *Dispatcher) Close() {
+	d.drop()
+	d.abort()
+	_ = d.runs.Drain(context.Background())
+}
+
+// Enqueue schedules a registered job. It never blocks on the job itself.
+// A Duplicate or Throttled status is not an error: the call was intentionally dropped.
+// The unique lease is checked before the throttle window, so a duplicate does not use it up.
+func (d *Dispatcher) Enqueue(ctx context.Context, name string, args map[string]any, opts ...engineJobs.EnqueueOption) (engineJobs.EnqueueStatus, error) {
+	job, ok := d.jobs.Get(name)
+	if !ok {
+		return "", fmt.Errorf("job not found: %s", name)
+	}
+	done, err := d.runs.Start()
+	if err != nil {
+		return "", fmt.Errorf("dispatcher is closed")
+	}
+
+	var o engineJobs.EnqueueOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	if o.UniqueTTL <= 0 {
+		o.UniqueTTL = d.uniqueTTL
+	}
+
+	runAt := o.RunAt
+	var debounceKey string
+	if o.DebounceKey != "" && o.DebounceWindow > 0 {
+		debounceKey = key("debounce", name, o.DebounceKey)
+		if until := time.Now().Add(o.DebounceWindow); until.After(runAt) {
+			runAt = until
+		}
+	}
+
+	// A call that is not launched gives back what it took, so a duplicate or a failed call
+	// neither holds the unique lease nor uses up the throttle window.
+	var (
+		unique      engine.Lock
+		throttleKey string
+		launched    bool
+	)
+	defer func() {
+		if launched {
+			return
+		}
+		cleanupCtx := context.WithoutCancel(ctx)
+		d.unlock(cleanupCtx, unique)
+		if throttleKey != "" {
+			_ = d.store(ctx).Del(cleanupCtx, throttleKey)
+		}
+		done()
+	}()
+
+	var uniqueKey string
+	if o.UniqueKey != "" {
+		uniqueKey = d.lockKey(ctx, name, o.UniqueKey)
+		// With debounce the lock is taken when the run starts, otherwise every debounced
+		// call after the first would be reported as a duplicate.
+		if debounceKey == "" {
+			// A delayed run holds the lease while it waits, so the TTL covers the delay too.
+			ttl := o.UniqueTTL
+			if delay := time.Until(runAt); delay > 0 {
+				ttl += delay
+			}
+			lock, err := d.tryUnique(ctx, uniqueKey, ttl)
+			if errors.Is(err, engine.ErrLockHeld) {
+				return engineJobs.Duplicate, nil
+			}
+			if err != nil {
+				return "", fmt.Errorf("unique %q: %w", name, err)
+			}
+			unique = lock
+		}
+	}
+
+	if o.ThrottleKey != "" && o.ThrottleWindow > 0 {
+		k := key("throttle", name, o.ThrottleKey)
+		stored, err := d.store(ctx).SetNX(ctx, k, []byte("1"), o.ThrottleWindow)
+		if err != nil {
+			return "", fmt.Errorf("throttle %q: %w", name, err)
+		}
+		if !stored {
+			return engineJobs.Throttled, nil
+		}
+		throttleKey = k
+	}
+
+	var debounceToken []byte
+	if debounceKey != "" {
+		debounceToken = newToken()
+		// The latest caller owns the key; earlier timers see a different token and drop out.
+		if err := d.store(ctx).Set(ctx, debounceKey, debounceToken, 2*o.DebounceWindow); err != nil {
+			return "", fmt.Errorf("debounce %q: %w", name, err)
+		}
+	}
+
+	// Keep ctx values (tenant schema, request id) but not its cancellation:
+	// the caller (typically an HTTP request) returns long before the job runs.
+	base := context.WithoutCancel(ctx)
+
+	launched = true
+	go func() {
+		defer done()
+
+		if delay := time.Until(runAt); delay > 0 {
+			t := time.NewTimer(delay)
+			select {
+			case <-t.C:
+			case <-d.pending.Done():
+				t.Stop()
+				d.unlock(base, unique)
+				return
+			}
+		}
+
+		if debounceKey != "" {
+			if !d.claim(base, debounceKey, debounceToken) {
+				return // superseded by a later call within the window
+			}
+			if uniqueKey != "" {
+				lock, err := d.tryUnique(base, uniqueKey, o.UniqueTTL)
+				if err != nil {
+					d.log.Info("debounced job dropped: unique run in progress", "job", name, "error", err)
+					return
+				}
+				unique = lock
+			}
+		}
+		defer d.unlock(base, unique)
+
+		runCtx, cancel := context.WithCancel(base)
+		defer cancel()
+		stop := context.AfterFunc(d.running, cancel)
+		defer stop()
+
+		d.log.Info("async job started", "job", name)
+		if err := d.jobs.Execute(runCtx, job, args); err != nil {
+			d.log.Error("async job failed", "job", name, "error", err)
+			return
+		}
+		d.log.Info("async job finished", "job", name)
+	}()
+
+	return engineJobs.Enqueued, nil
+}
+
+// store returns the cache scope of ctx: the tenant's, or the global one for jobs enqueued
+// outside a tenant.
+func (d *Dispatcher) store(ctx context.Context) *cache.TenantCache {
+	if tenantContext.SchemaFrom(ctx) == "" {
+		return d.cache.Global()
+	}
+	return d.cache
+}
+
+// key builds "jobs:<kind>:<job>:<key>"; TenantCache adds the prefix and the tenant.
+func key(kind, job, key string) string {
+	return "jobs:" + kind + ":" + job + ":" + key
+}
+
+// lockKey builds the unique lease key "jobs:unique:<job>:<tenant key>:<key>"; the locker
+// adds its own prefix.
+func (d *Dispatcher) lockKey(ctx context.Context, job, key string) string {
+	return "jobs:unique:" + job + ":" + tenantContext.Key(ctx) + ":" + key
+}
+
+// tryUnique takes the unique lease for ttl. It is not renewed: ttl bounds how long a crashed
+// instance blocks the key, and a run outliving it no longer counts as in progress.
+func (d *Dispatcher) tryUnique(ctx context.Context, key string, ttl time.Duration) (engine.Lock, error) {
+	return d.locker.TryLock(ctx, key, engine.WithLeaseTTL(ttl), engine.WithoutRenewal())
+}
+
+// unlock releases a unique lease taken by Enqueue; nil is a no-op.
+func (d *Dispatcher) unlock(ctx context.Context, lock engine.Lock) {
+	if lock == nil {
+		return
+	}
+	if err := lock.Unlock(ctx); err != nil && !errors.Is(err, engine.ErrLockLost) {
+		d.log.Warn("failed to release job key", "key", lock.Key(), "error", err)
+	}
+}
+
+// claim deletes the debounce key if it still holds token, i.e. no later call superseded this
+// one. Compare and delete is one atomic step, so a token written in between is never removed.
+func (d *Dispatcher) claim(ctx context.Context, key string, token []byte) bool {
+	ok, err := d.store(ctx).DelIfValue(ctx, key, token)
+	if err != nil {
+		d.log.Warn("failed to claim debounce key", "key", key, "error", err)
+		return false
+	}
+	return ok
+}
+
+func newToken() []byte {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return []byte(hex.EncodeToString(b))
+}
+
+var _ engineJobs.Enqueuer = (*Dispatcher)(nil)
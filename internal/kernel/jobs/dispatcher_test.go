@@ -0,0 +1,190 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"skyrix/internal/engine"
+	"skyrix/internal/engine/cache"
+	engineJobs "skyrix/internal/engine/jobs"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+	"skyrix/internal/logger"
+)
+
+// countingJob records the tenant key of every run.
+type countingJob struct {
+	mu   sync.Mutex
+	runs []string
+}
+
+func (j *countingJob) Name() string    { return "count" }
+func (j *countingJob) RetryCount() int { return 0 }
+
+func (j *countingJob) Execute(ctx context.Context, _ map[string]any) error {
+	j.mu.Lock()
+	j.runs = append(j.runs, tenantContext.Key(ctx))
+	j.mu.Unlock()
+	return nil
+}
+
+func (j *countingJob) Runs() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]string(nil), j.runs...)
+}
+
+func newTestDispatcher(t *testing.T) (*Dispatcher, *countingJob, *engine.MemoryCache) {
+	t.Helper()
+	log := logger.NewSlogWrapper(slog.New(slog.DiscardHandler))
+	mem := engine.NewMemoryCache(engine.MemoryOpts{})
+	t.Cleanup(func() { _ = mem.Close() })
+
+	job := &countingJob{}
+	reg := NewRegistry(log, engine.NewMemoryLocker(log), nil)
+	reg.Register(job)
+	d := NewDispatcher(reg, cache.NewTenantCache(mem, "app"), engine.NewMemoryLocker(log), log)
+	t.Cleanup(d.Close)
+	return d, job, mem
+}
+
+func TestDispatcherThrottleIsTenantScoped(t *testing.T) {
+	d, _, mem := newTestDispatcher(t)
+	bg := context.Background()
+	acme := tenantContext.WithSchema(bg, "acme")
+	beta := tenantContext.WithSchema(bg, "beta")
+	throttle := engineJobs.WithThrottle("x", time.Minute)
+
+	for _, c := range []struct {
+		name string
+		ctx  context.Context
+		want engineJobs.EnqueueStatus
+	}{
+		{"acme", acme, engineJobs.Enqueued},
+		{"acme again", acme, engineJobs.Throttled},
+		{"beta", beta, engineJobs.Enqueued},
+		{"no tenant", bg, engineJobs.Enqueued},
+		{"no tenant again", bg, engineJobs.Throttled},
+	} {
+		got, err := d.Enqueue(c.ctx, "count", nil, throttle)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: status %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	for _, key := range []string{
+		"app:t:acme:jobs:throttle:count:x",
+		"app:t:beta:jobs:throttle:count:x",
+		"app:global:jobs:throttle:count:x",
+	} {
+		if ok, _ := mem.Exists(bg, key); !ok {
+			t.Errorf("throttle key %s missing", key)
+		}
+	}
+}
+
+func TestDispatcherDebounceRunsLastCallPerTenant(t *testing.T) {
+	d, job, _ := newTestDispatcher(t)
+	bg := context.Background()
+	acme := tenantContext.WithSchema(bg, "acme")
+	beta := tenantContext.WithSchema(bg, "beta")
+	debounce := engineJobs.WithDebounce("x", 50*time.Millisecond)
+
+	for _, ctx := range []context.Context{acme, acme, acme, beta} {
+		if _, err := d.Enqueue(ctx, "count", nil, debounce); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.runs.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := job.Runs()
+	if len(runs) != 2 {
+		t.Fatalf("runs = %v, want one per tenant", runs)
+	}
+	seen := map[string]bool{}
+	for _, r := range runs {
+		seen[r] = true
+	}
+	if !seen["acme"] || !seen["beta"] {
+		t.Errorf("runs = %v, want acme and beta", runs)
+	}
+}
+
+func TestDispatcherStop(t *testing.T) {
+	d, job, _ := newTestDispatcher(t)
+	ctx := tenantContext.WithSchema(context.Background(), "acme")
+
+	if _, err := d.Enqueue(ctx, "count", nil, engineJobs.WithDelay(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	stopCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := d.Stop(stopCtx); err != nil {
+		t.Fatalf("Stop did not drop the pending run: %v", err)
+	}
+	if runs := job.Runs(); len(runs) != 0 {
+		t.Errorf("pending run executed: %v", runs)
+	}
+	if _, err := d.Enqueue(ctx, "count", nil); err == nil {
+		t.Error("Enqueue after Stop succeeded")
+	}
+}
+
+func TestDispatcherUniqueCoversDelay(t *testing.T) {
+	d, job, _ := newTestDispatcher(t)
+	ctx := tenantContext.WithSchema(context.Background(), "acme")
+	unique := engineJobs.WithUnique("x", 50*time.Millisecond)
+
+	got, err := d.Enqueue(ctx, "count", nil, unique, engineJobs.WithDelay(300*time.Millisecond))
+	if err != nil || got != engineJobs.Enqueued {
+		t.Fatalf("first enqueue: %q, %v", got, err)
+	}
+	// Past the unique TTL but still before the delayed run: the key must still be held.
+	time.Sleep(150 * time.Millisecond)
+	got, err = d.Enqueue(ctx, "count", nil, unique)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != engineJobs.Duplicate {
+		t.Errorf("second enqueue: %q, want %q", got, engineJobs.Duplicate)
+	}
+
+	if err := d.runs.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if runs := job.Runs(); len(runs) != 1 {
+		t.Errorf("runs = %v, want one", runs)
+	}
+}
+
+func TestDispatcherDuplicateKeepsThrottleWindow(t *testing.T) {
+	d, _, _ := newTestDispatcher(t)
+	ctx := tenantContext.WithSchema(context.Background(), "acme")
+	throttle := engineJobs.WithThrottle("t", time.Minute)
+
+	for _, c := range []struct {
+		name string
+		opts []engineJobs.EnqueueOption
+		want engineJobs.EnqueueStatus
+	}{
+		{"pending unique run", []engineJobs.EnqueueOption{engineJobs.WithUnique("u", 0), engineJobs.WithDelay(time.Hour)}, engineJobs.Enqueued},
+		{"duplicate", []engineJobs.EnqueueOption{engineJobs.WithUnique("u", 0), throttle}, engineJobs.Duplicate},
+		{"first throttled call", []engineJobs.EnqueueOption{engineJobs.WithUnique("v", 0), throttle}, engineJobs.Enqueued},
+		{"second throttled call", []engineJobs.EnqueueOption{throttle}, engineJobs.Throttled},
+	} {
+		got, err := d.Enqueue(ctx, "count", nil, c.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: status %q, want %q", c.name, got, c.want)
+		}
+	}
+}
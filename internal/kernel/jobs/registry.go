@@ -6,21 +6,28 @@ import (
 	"sort"
 	"sync"
 
+	"skyrix/internal/engine"
 	engineJobs "skyrix/internal/engine/jobs"
+	"skyrix/internal/engine/metrics"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
 	"skyrix/internal/logger"
 )
 
 type Registry struct {
-	log logger.Interface
+	log     logger.Interface
+	locker  engine.Locker
+	metrics *metrics.Metrics
 
 	mu   sync.RWMutex
 	jobs map[string]engineJobs.Job
 }
 
-func NewRegistry(log logger.Interface) *Registry {
+func NewRegistry(log logger.Interface, locker engine.Locker, m *metrics.Metrics) *Registry {
 	return &Registry{
-		log:  log,
-		jobs: make(map[string]engineJobs.Job),
+		log:     log,
+		locker:  locker,
+		metrics: m,
+		jobs:    make(map[string]engineJobs.Job),
 	}
 }
 
@@ -63,7 +70,26 @@ func (r *Registry) Run(ctx context.Context, name string, args map[string]any) er
 	if !ok {
 		return fmt.Errorf("job not found: %s", name)
 	}
-	return engineJobs.ExecuteJob(ctx, j, r.log, args)
+	return r.Execute(ctx, j, args)
+}
+
+// Execute runs job through engineJobs.ExecuteJob. Exclusive jobs hold a lock on
+// "jobs:<name>:<tenant key>" for the run and fail with engine.ErrLockHeld if another
+// run holds it; losing the lease cancels the run's ctx.
+func (r *Registry) Execute(ctx context.Context, job engineJobs.Job, args map[string]any) error {
+	if !engineJobs.IsExclusive(job) {
+		return engineJobs.ExecuteJob(ctx, job, r.log, r.metrics, args)
+	}
+	if r.locker == nil {
+		return fmt.Errorf("job %q is exclusive but no locker is configured", job.Name())
+	}
+	lock, err := r.locker.TryLock(ctx, "jobs:"+job.Name()+":"+tenantContext.Key(ctx))
+	if err != nil {
+		return fmt.Errorf("job %q: %w", job.Name(), err)
+	}
+	return engine.RunLocked(ctx, lock, func(ctx context.Context) error {
+		return engineJobs.ExecuteJob(ctx, job, r.log, r.metrics, args)
+	})
 }
 
 var _ engineJobs.Registry = (*Registry)(nil)
@@ -0,0 +1,191 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	engineJobs "skyrix/internal/engine/jobs"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+	"skyrix/internal/engine/tenantPackage/entity"
+	"skyrix/internal/engine/tenantPackage/schemaResolver"
+	"skyrix/internal/engine/tenantPackage/service"
+	"skyrix/internal/logger"
+)
+
+const defaultFanOutConcurrency = 4
+
+// TenantSource lists the tenants a fan-out run iterates over.
+type TenantSource interface {
+	ListActive(ctx context.Context) ([]entity.Tenant, error)
+}
+
+var _ TenantSource = (*service.TenantService)(nil)
+
+// FanOutOpts controls a fan-out run.
+type FanOutOpts struct {
+	// Concurrency bounds how many tenants run at once (default 4).
+	Concurrency int
+}
+
+// TenantResult is the outcome of one tenant's run.
+type TenantResult struct {
+	Namespace string
+	Schema    string
+	Duration  time.Duration
+	Err       error
+	Skipped   bool // not started because the run was cancelled
+}
+
+// FanOutReport aggregates per-tenant results of a fan-out run.
+type FanOutReport struct {
+	Job      string
+	Started  time.Time
+	Duration time.Duration
+	Results  []TenantResult
+}
+
+// Failed returns results that errored or were skipped.
+func (r *FanOutReport) Failed() []TenantResult {
+	var out []TenantResult
+	for _, res := range r.Results {
+		if res.Err != nil {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+// Succeeded returns the number of tenants the job completed for.
+func (r *FanOutReport) Succeeded() int {
+	return len(r.Results) - len(r.Failed())
+}
+
+// Err joins all per-tenant errors; nil when every tenant succeeded.
+func (r *FanOutReport) Err() error {
+	var errs []error
+	for _, res := range r.Failed() {
+		errs = append(errs, fmt.Errorf("tenant %s: %w", res.Namespace, res.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// TenantRunner executes tenant-scoped jobs once per active tenant schema.
+// Each run gets its own context with tenantContext.WithSchema set, and failures
+// (including panics, via ExecuteJob) are isolated per tenant.
+type TenantRunner struct {
+	jobs    engineJobs.Registry
+	tenants TenantSource
+	log     logger.Interface
+}
+
+// NewTenantRunner lists tenants through the TenantService, so database references are
+// sanitized the same way as for HTTP resolution.
+func NewTenantRunner(jobs engineJobs.Registry, tenants *service.TenantService, log logger.Interface) *TenantRunner {
+	return &TenantRunner{jobs: jobs, tenants: tenants, log: log}
+}
+
+// RunForAllTenants looks the job up by name and fans it out across all active tenants.
+// The returned error covers setup problems only; per-tenant failures are in the report.
+func (r *TenantRunner) RunForAllTenants(ctx context.Context, name string, args map[string]any, opts FanOutOpts) (*FanOutReport, error) {
+	job, ok := r.jobs.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("job not found: %s", name)
+	}
+	if engineJobs.ScopeOf(job) != engineJobs.ScopeTenant {
+		return nil, fmt.Errorf("job %q is not tenant-scoped", name)
+	}
+
+	// The tenants table lives in the main schema.
+	tenants, err := r.tenants.ListActive(tenantContext.WithSchema(ctx, ""))
+	if err != nil {
+		return nil, fmt.Errorf("list tenants: %w", err)
+	}
+
+	return r.run(ctx, job, tenants, args, opts), nil
+}
+
+func (r *TenantRunner) run(ctx context.Context, job engineJobs.Job, tenants []entity.Tenant, args map[string]any, opts FanOutOpts) *FanOutReport {
+	limit := opts.Concurrency
+	if limit <= 0 {
+		limit = defaultFanOutConcurrency
+	}
+
+	report := &FanOutReport{
+		Job:     job.Name(),
+		Started: time.Now(),
+		Results: make([]TenantResult, len(tenants)),
+	}
+	r.log.Info("tenant fan-out started", "job", job.Name(), "tenants", len(tenants), "concurrency", limit)
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+
+	for i := range tenants {
+		res := &report.Results[i]
+		res.Namespace = tenants[i].Namespace
+		if tenants[i].Schema != nil {
+			res.Schema = strings.ToLower(strings.TrimSpace(*tenants[i].Schema))
+		}
+
+		// Fails closed like HTTP resolution: a database-isolated tenant without a usable
+		// reference never runs against the main database.
+		tenant, err := schemaResolver.ResolutionOf(&tenants[i], "fanout")
+		if err != nil {
+			res.Err = err
+			r.log.Error("tenant skipped", "job", job.Name(), "tenant", res.Namespace, "error", err)
+			continue
+		}
+
+		// Checked first: select picks randomly when a slot frees up after cancellation.
+		if err := ctx.Err(); err != nil {
+			res.Err = err
+			res.Skipped = true
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			res.Err = ctx.Err()
+			res.Skipped = true
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// Every field is set, zero values included, so a tenant ID or database already in
+			// the caller's ctx cannot leak into another tenant's run.
+			tctx := tenantContext.WithSchema(ctx, tenant.Schema)
+			tctx = tenantContext.WithTenantID(tctx, tenant.TenantID)
+			tctx = tenantContext.WithDatabase(tctx, tenant.Database)
+			tctx = tenantContext.WithResolvedBy(tctx, tenant.By)
+
+			started := time.Now()
+			res.Err = r.jobs.Run(tctx, job.Name(), maps.Clone(args))
+			res.Duration = time.Since(started)
+
+			if res.Err != nil {
+				r.log.Error("tenant job failed", "job", job.Name(), "tenant", res.Namespace, "error", res.Err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	report.Duration = time.Since(report.Started)
+
+	r.log.Info("tenant fan-out finished",
+		"job", job.Name(),
+		"tenants", len(report.Results),
+		"succeeded", report.Succeeded(),
+		"failed", len(report.Failed()),
+		"duration", report.Duration,
+	)
+	return report
+}
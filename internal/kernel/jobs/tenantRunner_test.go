@@ -0,0 +1,272 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"skyrix/internal/engine"
+	engineJobs "skyrix/internal/engine/jobs"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+	"skyrix/internal/engine/tenantPackage/entity"
+	"skyrix/internal/engine/tenantPackage/schemaResolver"
+	"skyrix/internal/logger"
+)
+
+// tenantRun is what a tenantJob saw in its context.
+type tenantRun struct {
+	Schema   string
+	TenantID int64 // 0 unless the run is a shared-schema tenant's
+	Database string
+}
+
+// tenantJob is a tenant-scoped job that records every run; exec, when set, runs in place of
+// the default no-op.
+type tenantJob struct {
+	exec func(ctx context.Context) error
+
+	mu   sync.Mutex
+	runs []tenantRun
+}
+
+func (j *tenantJob) Name() string            { return "per-tenant" }
+func (j *tenantJob) RetryCount() int         { return 0 }
+func (j *tenantJob) Scope() engineJobs.Scope { return engineJobs.ScopeTenant }
+
+func (j *tenantJob) Execute(ctx context.Context, _ map[string]any) error {
+	id, _ := tenantContext.TenantIDFrom(ctx)
+	j.mu.Lock()
+	j.runs = append(j.runs, tenantRun{
+		Schema:   tenantContext.SchemaFrom(ctx),
+		TenantID: id,
+		Database: tenantContext.DatabaseFrom(ctx),
+	})
+	j.mu.Unlock()
+	if j.exec != nil {
+		return j.exec(ctx)
+	}
+	return nil
+}
+
+func (j *tenantJob) Runs() []tenantRun {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]tenantRun(nil), j.runs...)
+}
+
+type fakeTenants []entity.Tenant
+
+func (f fakeTenants) ListActive(context.Context) ([]entity.Tenant, error) { return f, nil }
+
+func newTestTenantRunner(t *testing.T, job *tenantJob, tenants ...entity.Tenant) *TenantRunner {
+	t.Helper()
+	log := logger.NewSlogWrapper(slog.New(slog.DiscardHandler))
+	reg := NewRegistry(log, engine.NewMemoryLocker(log), nil)
+	reg.Register(job)
+	return &TenantRunner{jobs: reg, tenants: fakeTenants(tenants), log: log}
+}
+
+func strPtr(s string) *string { return &s }
+
+// schemaTenants returns n schema-per-tenant tenants t0..t<n-1>.
+func schemaTenants(n int) []entity.Tenant {
+	out := make([]entity.Tenant, n)
+	for i := range out {
+		ns := fmt.Sprintf("t%d", i)
+		out[i] = entity.Tenant{ID: int64(i + 1), Namespace: ns, Schema: strPtr(ns), Isolation: entity.IsolationSchema}
+	}
+	return out
+}
+
+func TestTenantRunnerBoundsConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	job := &tenantJob{exec: func(context.Context) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}}
+	r := newTestTenantRunner(t, job, schemaTenants(10)...)
+
+	report, err := r.RunForAllTenants(context.Background(), job.Name(), nil, FanOutOpts{Concurrency: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := peak.Load(); got > 3 || got < 1 {
+		t.Errorf("peak concurrency = %d, want 1..3", got)
+	}
+	if report.Succeeded() != 10 || len(report.Failed()) != 0 || report.Err() != nil {
+		t.Errorf("succeeded %d, failed %d, err %v", report.Succeeded(), len(report.Failed()), report.Err())
+	}
+}
+
+func TestTenantRunnerIsolatesFailuresAndPanics(t *testing.T) {
+	job := &tenantJob{exec: func(ctx context.Context) error {
+		switch tenantContext.SchemaFrom(ctx) {
+		case "t1":
+			return errors.New("boom")
+		case "t2":
+			panic("kaboom")
+		}
+		return nil
+	}}
+	r := newTestTenantRunner(t, job, schemaTenants(4)...)
+
+	report, err := r.RunForAllTenants(context.Background(), job.Name(), nil, FanOutOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(job.Runs()) != 4 {
+		t.Errorf("runs = %v, want every tenant to run", job.Runs())
+	}
+	if report.Succeeded() != 2 {
+		t.Errorf("succeeded = %d, want 2", report.Succeeded())
+	}
+	failed := map[string]bool{}
+	for _, res := range report.Failed() {
+		failed[res.Namespace] = true
+		if res.Skipped {
+			t.Errorf("%s: marked skipped", res.Namespace)
+		}
+	}
+	if len(failed) != 2 || !failed["t1"] || !failed["t2"] {
+		t.Errorf("failed = %v, want t1 and t2", failed)
+	}
+	if report.Err() == nil {
+		t.Error("report.Err() = nil")
+	}
+}
+
+func TestTenantRunnerSkipsTenantsAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	job := &tenantJob{exec: func(context.Context) error {
+		cancel()
+		return nil
+	}}
+	r := newTestTenantRunner(t, job, schemaTenants(5)...)
+
+	report, err := r.RunForAllTenants(ctx, job.Name(), nil, FanOutOpts{Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var skipped int
+	for _, res := range report.Results {
+		if res.Skipped {
+			skipped++
+			if !errors.Is(res.Err, context.Canceled) {
+				t.Errorf("%s: skipped with err %v", res.Namespace, res.Err)
+			}
+		}
+	}
+	ran := len(job.Runs())
+	if ran == 0 || skipped == 0 || ran+skipped != 5 {
+		t.Errorf("ran %d, skipped %d, want both > 0 and 5 in total", ran, skipped)
+	}
+	if len(report.Failed()) != skipped {
+		t.Errorf("failed = %d, want the %d skipped tenants", len(report.Failed()), skipped)
+	}
+}
+
+func TestTenantRunnerRejectsMainScopedJob(t *testing.T) {
+	log := logger.NewSlogWrapper(slog.New(slog.DiscardHandler))
+	reg := NewRegistry(log, engine.NewMemoryLocker(log), nil)
+	reg.Register(&countingJob{})
+	r := &TenantRunner{jobs: reg, tenants: fakeTenants(schemaTenants(1)), log: log}
+
+	if _, err := r.RunForAllTenants(context.Background(), "count", nil, FanOutOpts{}); err == nil {
+		t.Error("main-scoped job fanned out")
+	}
+	if _, err := r.RunForAllTenants(context.Background(), "missing", nil, FanOutOpts{}); err == nil {
+		t.Error("unknown job fanned out")
+	}
+}
+
+func TestTenantRunnerDatabaseTenantWithoutRefFailsClosed(t *testing.T) {
+	job := &tenantJob{}
+	r := newTestTenantRunner(t, job,
+		entity.Tenant{ID: 1, Namespace: "acme", Schema: strPtr("acme"), Isolation: entity.IsolationSchema},
+		entity.Tenant{ID: 2, Namespace: "bigco", Schema: strPtr("bigco"), Isolation: entity.IsolationDatabase},
+		entity.Tenant{ID: 3, Namespace: "inline", Schema: strPtr("inline"), Isolation: entity.IsolationDatabase,
+			Database: strPtr("postgres://u:p@db/inline")},
+		entity.Tenant{ID: 4, Namespace: "vault", Schema: strPtr("vault"), Isolation: entity.IsolationDatabase,
+			Database: strPtr("env:VAULT_DSN")},
+	)
+
+	report, err := r.RunForAllTenants(context.Background(), job.Name(), nil, FanOutOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, res := range report.Results {
+		switch res.Namespace {
+		case "bigco", "inline":
+			if !errors.Is(res.Err, schemaResolver.ErrDatabaseInvalid) {
+				t.Errorf("%s: err = %v, want ErrDatabaseInvalid", res.Namespace, res.Err)
+			}
+		default:
+			if res.Err != nil {
+				t.Errorf("%s: %v", res.Namespace, res.Err)
+			}
+		}
+	}
+
+	got := map[string]string{}
+	for _, run := range job.Runs() {
+		got[run.Schema] = run.Database
+	}
+	want := map[string]string{"acme": "", "vault": "env:VAULT_DSN"}
+	if len(got) != len(want) {
+		t.Fatalf("runs = %v, want %v", got, want)
+	}
+	for schema, db := range want {
+		if d, ok := got[schema]; !ok || d != db {
+			t.Errorf("run %s: database %q, want %q", schema, d, db)
+		}
+	}
+}
+
+func TestTenantRunnerReplacesCallerTenant(t *testing.T) {
+	job := &tenantJob{}
+	r := newTestTenantRunner(t, job,
+		entity.Tenant{ID: 1, Namespace: "acme", Schema: strPtr("acme"), Isolation: entity.IsolationSchema},
+		entity.Tenant{ID: 5, Namespace: "small", Schema: strPtr("shared"), Isolation: entity.IsolationShared},
+	)
+	// The caller itself runs as a shared-schema tenant of a dedicated database.
+	ctx := tenantContext.WithTenantID(tenantContext.WithSchema(context.Background(), "other"), 99)
+	ctx = tenantContext.WithDatabase(ctx, "env:OTHER_DSN")
+
+	report, err := r.RunForAllTenants(ctx, job.Name(), nil, FanOutOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed := report.Failed(); len(failed) > 0 {
+		t.Fatalf("failed runs: %+v", failed)
+	}
+
+	got := map[string]tenantRun{}
+	for _, run := range job.Runs() {
+		got[run.Schema] = run
+	}
+	for schema, want := range map[string]tenantRun{
+		"acme":   {Schema: "acme"},
+		"shared": {Schema: "shared", TenantID: 5},
+	} {
+		if got[schema] != want {
+			t.Errorf("run %s = %+v, want %+v", schema, got[schema], want)
+		}
+	}
+}
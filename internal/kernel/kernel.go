@@ -3,6 +3,7 @@ package kernel
 import (
 	"skyrix/internal/config"
 	"skyrix/internal/engine"
+	"skyrix/internal/engine/cache"
 	engineJobs "skyrix/internal/engine/jobs"
 	"skyrix/internal/logger"
 )
@@ -12,7 +13,7 @@ type Kernel struct {
 	Logger logger.Interface
 
 	DB    *engine.Database
-	Cache engine.Cache
+	Cache *cache.TenantCache
 	Jobs  engineJobs.Registry
 }
 
@@ -20,7 +21,7 @@ func NewKernel(
 	cfg *config.Config,
 	log logger.Interface,
 	db *engine.Database,
-	cache engine.Cache,
+	cache *cache.TenantCache,
 	jobs engineJobs.Registry,
 ) *Kernel {
 	return &Kernel{
@@ -0,0 +1,94 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"skyrix/internal/engine"
+	"skyrix/internal/logger"
+)
+
+type stopHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Lifecycle coordinates graceful shutdown. It starts ready; Drain flips readiness so load
+// balancers stop routing new traffic, and Stop runs the OnStop hooks in reverse registration
+// order. Providers register the hooks that close what they opened, so a component stops
+// before the ones it was built from.
+type Lifecycle struct {
+	log logger.Interface
+
+	draining atomic.Bool
+
+	mu      sync.Mutex
+	hooks   []stopHook
+	stopped bool
+}
+
+var _ engine.Lifecycle = (*Lifecycle)(nil)
+
+func NewLifecycle(log logger.Interface) *Lifecycle {
+	return &Lifecycle{log: log}
+}
+
+func (l *Lifecycle) OnStop(name string, fn func(ctx context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stopped {
+		l.log.Warn("stop hook registered after shutdown, ignored", "hook", name)
+		return
+	}
+	l.hooks = append(l.hooks, stopHook{name: name, fn: fn})
+}
+
+func (l *Lifecycle) Ready() bool {
+	return !l.draining.Load()
+}
+
+// Drain marks the application as shutting down; Ready reports false from now on.
+func (l *Lifecycle) Drain() {
+	if l.draining.CompareAndSwap(false, true) {
+		l.log.Info("readiness disabled, draining")
+	}
+}
+
+// Stop runs the stop hooks newest first; components running async work (the job dispatcher,
+// the outbox relay) await it in their own hooks. Every hook runs even if ctx expired or an
+// earlier one failed; the errors are joined. Stop runs the hooks once.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.Drain()
+
+	l.mu.Lock()
+	if l.stopped {
+		l.mu.Unlock()
+		return nil
+	}
+	l.stopped = true
+	hooks := l.hooks
+	l.hooks = nil
+	l.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		if err := runStopHook(ctx, h); err != nil {
+			l.log.Error("stop hook failed", "hook", h.name, "error", err)
+			errs = append(errs, fmt.Errorf("stop hook %q: %w", h.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func runStopHook(ctx context.Context, h stopHook) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h.fn(ctx)
+}
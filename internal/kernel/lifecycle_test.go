@@ -0,0 +1,103 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+
+	"skyrix/internal/engine"
+	"skyrix/internal/logger"
+)
+
+func testLifecycle() *Lifecycle {
+	return NewLifecycle(logger.NewSlogWrapper(slog.New(slog.DiscardHandler)))
+}
+
+// recorder collects the order in which components were closed.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(e string) {
+	r.mu.Lock()
+	r.events = append(r.events, e)
+	r.mu.Unlock()
+}
+
+func (r *recorder) closer(name string) func() error {
+	return func() error { r.add(name); return nil }
+}
+
+func TestLifecycleStopOrder(t *testing.T) {
+	lc := testLifecycle()
+	log := logger.NewSlogWrapper(slog.New(slog.DiscardHandler))
+	var rec recorder
+
+	// Registered in construction order, as the providers do.
+	cleanups := []func(){
+		engine.CloseOnStop(lc, log, "postgres", rec.closer("postgres")),
+		engine.CloseOnStop(lc, log, "redis", rec.closer("redis")),
+	}
+	lc.OnStop("outbox relay", func(context.Context) error { rec.add("outbox relay"); return nil })
+	lc.OnStop("jobs dispatcher", func(context.Context) error { rec.add("jobs dispatcher"); return nil })
+
+	if !lc.Ready() {
+		t.Fatal("not ready before Stop")
+	}
+	if err := lc.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if lc.Ready() {
+		t.Error("still ready after Stop")
+	}
+
+	// The Wire cleanups run afterwards and must not close anything twice.
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+	want := []string{"jobs dispatcher", "outbox relay", "redis", "postgres"}
+	if !slices.Equal(rec.events, want) {
+		t.Errorf("stop order = %v, want %v", rec.events, want)
+	}
+}
+
+func TestLifecycleStopRunsEveryHookOnce(t *testing.T) {
+	lc := testLifecycle()
+	var rec recorder
+	errBoom := errors.New("boom")
+	lc.OnStop("a", func(context.Context) error { rec.add("a"); return nil })
+	lc.OnStop("b", func(context.Context) error { rec.add("b"); return errBoom })
+	lc.OnStop("c", func(context.Context) error { rec.add("c"); panic("c failed") })
+
+	err := lc.Stop(context.Background())
+	if !errors.Is(err, errBoom) {
+		t.Errorf("Stop error %v does not wrap the failing hook's", err)
+	}
+	if err := lc.Stop(context.Background()); err != nil {
+		t.Errorf("second Stop: %v", err)
+	}
+	lc.OnStop("late", func(context.Context) error { rec.add("late"); return nil })
+	if want := []string{"c", "b", "a"}; !slices.Equal(rec.events, want) {
+		t.Errorf("hooks ran %v, want %v", rec.events, want)
+	}
+}
+
+func TestLifecycleStopDeadline(t *testing.T) {
+	lc := testLifecycle()
+	ran := false
+	lc.OnStop("db", func(context.Context) error { ran = true; return nil })
+	lc.OnStop("jobs dispatcher", func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := lc.Stop(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Stop: got %v, want the deadline error", err)
+	}
+	if !ran {
+		t.Error("hooks did not run after the deadline")
+	}
+}
@@ -1,9 +1,13 @@
 package kernel
 
 import (
+	"context"
 	"skyrix/internal/config"
+	"skyrix/internal/engine"
+	"skyrix/internal/engine/metrics"
 	"skyrix/internal/kernel/db"
 	"skyrix/internal/logger"
+	"strings"
 
 	"github.com/google/wire"
 	"github.com/redis/go-redis/v9"
@@ -18,11 +22,24 @@ var ProviderSet = wire.NewSet(
 	ProvideLoggerConfig,
 	ProvideDatabaseConfig,
 	ProvideRedisConfig,
+	ProvideCacheConfig,
 	ProvideHttpServerConfig,
+	ProvideMetricsConfig,
 
 	ProvideLogger,
+
+	// Graceful shutdown: readiness and OnStop hooks. The providers below register their
+	// hooks on it, so both apps close connections in reverse construction order.
+	NewLifecycle,
+	wire.Bind(new(engine.Lifecycle), new(*Lifecycle)),
+
+	// Framework metrics: recorded by both apps, served on /metrics by the HTTP app.
+	metrics.ProviderSet,
+
 	ProvidePostgres,
+	ProvideTenantPools,
 	ProvideRedis,
+	wire.Bind(new(engine.TenantPools), new(*db.TenantPools)),
 )
 
 // ---- Config extractors ----
@@ -39,10 +56,18 @@ func ProvideRedisConfig(cfg *config.Config) *config.Redis {
 	return &cfg.Redis
 }
 
+func ProvideCacheConfig(cfg *config.Config) *config.Cache {
+	return &cfg.Cache
+}
+
 func ProvideHttpServerConfig(cfg *config.Config) *config.HttpServer {
 	return &cfg.HttpServer
 }
 
+func ProvideMetricsConfig(cfg *config.Config) *config.Metrics {
+	return &cfg.Metrics
+}
+
 // ---- Leaf providers ----
 
 func ProvideConfig() (*config.Config, error) {
@@ -53,31 +78,47 @@ func ProvideLogger(cfg *config.Logger) logger.Interface {
 	return logger.NewLogger(cfg.LogLevel, cfg.LogType, cfg.LogFile)
 }
 
-func ProvidePostgres(cfg *config.Database, log logger.Interface) (*gorm.DB, func(), error) {
-	postgres, err := db.InitPostgres(cfg)
+// ProvidePostgres connects to Postgres; ctx (the process signal context) interrupts startup retries.
+func ProvidePostgres(ctx context.Context, cfg *config.Database, log logger.Interface, m *metrics.Metrics, lc engine.Lifecycle) (*gorm.DB, func(), error) {
+	postgres, err := db.InitPostgres(ctx, cfg, log, m)
 	if err != nil {
 		log.Error("Unable to initialize postgres database", "error", err)
 		return nil, nil, err
 	}
-	cleanup := func() {
-		sqlDB, err := postgres.DB()
-		if err == nil {
-			log.Info("Closing postgres database connection")
-			_ = sqlDB.Close()
-		}
-	}
+	cleanup := engine.CloseOnStop(lc, log, "postgres", func() error {
+		log.Info("Closing postgres database connection")
+		return db.ClosePostgres(postgres)
+	})
 	return postgres, cleanup, nil
 }
 
-func ProvideRedis(cfg *config.Redis, log logger.Interface) (*redis.Client, func(), error) {
+func ProvideTenantPools(cfg *config.Database, log logger.Interface, m *metrics.Metrics, lc engine.Lifecycle) (*db.TenantPools, func()) {
+	pools := db.NewTenantPools(cfg, log, m)
+	cleanup := engine.CloseOnStop(lc, log, "tenant pools", func() error {
+		log.Info("Closing tenant database pools")
+		return pools.Close()
+	})
+	return pools, cleanup
+}
+
+func ProvideRedis(cfg *config.Redis, cacheCfg *config.Cache, log logger.Interface, lc engine.Lifecycle) (redis.UniversalClient, func(), error) {
+	if strings.EqualFold(strings.TrimSpace(cacheCfg.Backend), engine.CacheMemory) {
+		// The in-memory cache does not need Redis; the client connects lazily if anything uses it.
+		log.Info("Cache backend is memory, skipping Redis connection check")
+		client, err := db.NewRedisClient(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, engine.CloseOnStop(lc, log, "redis", client.Close), nil
+	}
 	client, err := db.InitRedis(cfg)
 	if err != nil {
 		log.Error("Unable to initialize redis client", "error", err)
 		return nil, nil, err
 	}
-	cleanup := func() {
+	cleanup := engine.CloseOnStop(lc, log, "redis", func() error {
 		log.Info("Closing redis client connection")
-		_ = client.Close()
-	}
+		return client.Close()
+	})
 	return client, cleanup, nil
 }
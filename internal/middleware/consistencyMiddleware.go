@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"skyrix/internal/engine"
+)
+
+// ConsistencyMiddleware makes every request one unit of work for read replica routing:
+// after the request writes, its remaining reads go to the primary.
+type ConsistencyMiddleware struct{}
+
+func NewConsistencyMiddleware() *ConsistencyMiddleware {
+	return &ConsistencyMiddleware{}
+}
+
+func (m *ConsistencyMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(engine.WithReadYourWrites(r.Context())))
+	})
+}
@@ -0,0 +1,291 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"skyrix/internal/config"
+	"skyrix/internal/engine"
+	"skyrix/internal/engine/cache"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+	"skyrix/internal/kernel/contextkeys"
+	"skyrix/internal/logger"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed from the store.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLen  = 255
+	maxIdempotencyRequest = 20 << 20 // same bound as GzipDecompressMiddleware
+)
+
+// skipReplayHeaders are not stored with a response; they belong to the original exchange.
+var skipReplayHeaders = map[string]bool{
+	"Date": true, "Content-Length": true, "Connection": true, "Transfer-Encoding": true, "Set-Cookie": true,
+}
+
+// IdempotencyMiddleware makes POST and PATCH requests carrying an Idempotency-Key safe to retry.
+//
+// The first request with a key runs and its response (status, headers, body) is stored for
+// TTL, scoped per tenant and user. A retry with the same key and the same request replays that
+// response without running the handler. While the first request is still running, duplicates
+// get 409 Conflict; a key reused for a different request (method, path, query or body) gets
+// 422. Responses with status 5xx are not stored, so the client may retry them.
+type IdempotencyMiddleware struct {
+	tenant  *cache.TenantCache
+	global  *cache.TenantCache
+	log     logger.Interface
+	ttl     time.Duration
+	lockTTL time.Duration
+	maxBody int64
+}
+
+type idempotencyRecord struct {
+	Done        bool        `msgpack:"d"`
+	Fingerprint string      `msgpack:"f"`
+	Status      int         `msgpack:"s"`
+	Header      http.Header `msgpack:"h"`
+	Body        []byte      `msgpack:"b"`
+	BodyOmitted bool        `msgpack:"o"` // the response was larger than the store limit
+}
+
+func NewIdempotencyMiddleware(c *cache.TenantCache, cfg *config.HttpServer, log logger.Interface) *IdempotencyMiddleware {
+	lockTTL := 2 * cfg.Timeout
+	if lockTTL < time.Minute {
+		lockTTL = time.Minute
+	}
+	return &IdempotencyMiddleware{
+		tenant:  c,
+		global:  c.Global(),
+		log:     log,
+		ttl:     cfg.IdempotencyTTL,
+		lockTTL: lockTTL,
+		maxBody: cfg.IdempotencyMaxBody,
+	}
+}
+
+func (m *IdempotencyMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+		if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotencyRequest+1))
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxIdempotencyRequest {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		store := m.tenant
+		if tenantContext.SchemaFrom(ctx) == "" {
+			store = m.global
+		}
+		storeKey := idempotencyStoreKey(r, key)
+		fp := requestFingerprint(r, body)
+
+		pending, err := cache.Msgpack.Marshal(idempotencyRecord{Fingerprint: fp})
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		// Two attempts: the record may expire between a failed SetNX and the Get.
+		for attempt := 0; attempt < 2; attempt++ {
+			acquired, err := store.SetNX(ctx, storeKey, pending, m.lockTTL)
+			if err != nil {
+				m.warn("idempotency store unavailable", r, err)
+				http.Error(w, "idempotency store unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			if acquired {
+				m.run(w, r, next, store, storeKey, fp)
+				return
+			}
+
+			rec, found, err := m.load(r, store, storeKey)
+			if err != nil {
+				m.warn("idempotency record unreadable", r, err)
+				http.Error(w, "idempotency store unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			if !found {
+				continue
+			}
+			switch {
+			case rec.Fingerprint != fp:
+				http.Error(w, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+			case !rec.Done:
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "a request with this Idempotency-Key is in progress", http.StatusConflict)
+			default:
+				replay(w, rec)
+			}
+			return
+		}
+		http.Error(w, "a request with this Idempotency-Key is in progress", http.StatusConflict)
+	})
+}
+
+// run executes the first request for a key and stores its response. The pending record is
+// removed when the handler fails with 5xx or panics, so the client can retry.
+func (m *IdempotencyMiddleware) run(w http.ResponseWriter, r *http.Request, next http.Handler, store engine.Cache, storeKey, fp string) {
+	tee := &teeRecorder{ResponseWriter: w, max: m.maxBody}
+	// The outcome is recorded even if the client went away: it will retry.
+	ctx := context.WithoutCancel(r.Context())
+	stored := false
+	defer func() {
+		if !stored {
+			if err := store.Del(ctx, storeKey); err != nil {
+				m.warn("failed to release idempotency key", r, err)
+			}
+		}
+	}()
+
+	next.ServeHTTP(tee, r)
+
+	status := tee.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status >= http.StatusInternalServerError {
+		return
+	}
+	rec := idempotencyRecord{Done: true, Fingerprint: fp, Status: status, Header: http.Header{}, BodyOmitted: tee.overflow}
+	for k, v := range tee.header {
+		if !skipReplayHeaders[k] {
+			rec.Header[k] = v
+		}
+	}
+	if !tee.overflow {
+		rec.Body = tee.buf.Bytes()
+	}
+	b, err := cache.Msgpack.Marshal(rec)
+	if err == nil {
+		err = store.Set(ctx, storeKey, b, m.ttl)
+	}
+	if err != nil {
+		m.warn("failed to store idempotent response", r, err)
+		return
+	}
+	stored = true
+}
+
+func (m *IdempotencyMiddleware) load(r *http.Request, store engine.Cache, storeKey string) (*idempotencyRecord, bool, error) {
+	b, ok, err := store.Get(r.Context(), storeKey)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	var rec idempotencyRecord
+	if err := cache.Msgpack.Unmarshal(b, &rec); err != nil {
+		return nil, false, errors.Join(errors.New("corrupt idempotency record"), err)
+	}
+	return &rec, true, nil
+}
+
+func (m *IdempotencyMiddleware) warn(msg string, r *http.Request, err error) {
+	if m.log != nil {
+		m.log.Warn(msg, "path", r.URL.Path, "error", err)
+	}
+}
+
+func replay(w http.ResponseWriter, rec *idempotencyRecord) {
+	h := w.Header()
+	for k, v := range rec.Header {
+		h[k] = v
+	}
+	h.Set(IdempotentReplayedHeader, "true")
+	if rec.BodyOmitted {
+		// The status and headers are replayed; the body was too large to keep.
+		h.Del("Content-Type")
+		w.WriteHeader(rec.Status)
+		return
+	}
+	h.Set("Content-Length", strconv.Itoa(len(rec.Body)))
+	w.WriteHeader(rec.Status)
+	_, _ = w.Write(rec.Body)
+}
+
+// idempotencyStoreKey scopes the client's key to the user (tenant scope is added by
+// cache.TenantCache). Anonymous clients share one space per tenant.
+func idempotencyStoreKey(r *http.Request, key string) string {
+	user := "anon"
+	if id, err := contextkeys.GetCustomerIDFromContext(r.Context()); err == nil {
+		user = strconv.FormatInt(id, 10)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "idem:" + user + ":" + base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.EscapedPath()))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.Query().Encode()))
+	h.Write([]byte{0})
+	h.Write(body)
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// teeRecorder writes through to the client and keeps a copy of the response up to max bytes.
+type teeRecorder struct {
+	http.ResponseWriter
+	header   http.Header // snapshot at WriteHeader
+	status   int
+	buf      bytes.Buffer
+	max      int64
+	overflow bool
+}
+
+func (t *teeRecorder) WriteHeader(code int) {
+	if t.status != 0 {
+		return
+	}
+	t.status = code
+	t.header = t.ResponseWriter.Header().Clone()
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *teeRecorder) Write(p []byte) (int, error) {
+	if t.status == 0 {
+		t.WriteHeader(http.StatusOK)
+	}
+	if !t.overflow {
+		if int64(t.buf.Len()+len(p)) > t.max {
+			t.overflow = true
+			t.buf.Reset()
+		} else {
+			t.buf.Write(p)
+		}
+	}
+	return t.ResponseWriter.Write(p)
+}
+
+func (t *teeRecorder) Flush() {
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"skyrix/internal/config"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+	"skyrix/internal/kernel/contextkeys"
+)
+
+func newIdempotency(t *testing.T, next http.Handler) http.Handler {
+	t.Helper()
+	cfg := &config.HttpServer{Timeout: time.Second, IdempotencyTTL: time.Hour, IdempotencyMaxBody: 1 << 20}
+	return NewIdempotencyMiddleware(testTenantCache(t), cfg, testLogger()).Handle(next)
+}
+
+func post(h http.Handler, ctx context.Context, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body)).WithContext(ctx)
+	if key != "" {
+		r.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// createHandler answers 201 with a body numbering its calls.
+func createHandler(calls *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/orders/1")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"call":`+strconv.Itoa(int(n))+`}`)
+	})
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	var calls atomic.Int32
+	h := newIdempotency(t, createHandler(&calls))
+	ctx := tenantContext.WithSchema(context.Background(), "acme")
+
+	first := post(h, ctx, "k1", `{"qty":1}`)
+	again := post(h, ctx, "k1", `{"qty":1}`)
+
+	if calls.Load() != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls.Load())
+	}
+	if again.Code != http.StatusCreated || again.Body.String() != first.Body.String() {
+		t.Errorf("replay: %d %q, want %d %q", again.Code, again.Body, first.Code, first.Body)
+	}
+	if again.Header().Get(IdempotentReplayedHeader) != "true" || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("Idempotent-Replayed: first %q, replay %q", first.Header().Get(IdempotentReplayedHeader), again.Header().Get(IdempotentReplayedHeader))
+	}
+	if again.Header().Get("Location") != "/orders/1" {
+		t.Errorf("replayed Location = %q", again.Header().Get("Location"))
+	}
+}
+
+func TestIdempotencyRejectsReusedKey(t *testing.T) {
+	var calls atomic.Int32
+	h := newIdempotency(t, createHandler(&calls))
+	ctx := tenantContext.WithSchema(context.Background(), "acme")
+
+	post(h, ctx, "k1", `{"qty":1}`)
+	if w := post(h, ctx, "k1", `{"qty":2}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key, different body: %d, want 422", w.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+}
+
+func TestIdempotencyConflictWhileRunning(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h := newIdempotency(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+	ctx := tenantContext.WithSchema(context.Background(), "acme")
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(h, ctx, "k1", "{}") }()
+	<-started
+
+	w := post(h, ctx, "k1", "{}")
+	if w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" {
+		t.Errorf("duplicate while running: %d Retry-After=%q, want 409 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+	close(release)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Errorf("first request: %d, want 201", first.Code)
+	}
+}
+
+func TestIdempotencyDoesNotStoreServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	h := newIdempotency(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	ctx := tenantContext.WithSchema(context.Background(), "acme")
+
+	if w := post(h, ctx, "k1", "{}"); w.Code != http.StatusBadGateway {
+		t.Fatalf("first: %d", w.Code)
+	}
+	if w := post(h, ctx, "k1", "{}"); w.Code != http.StatusCreated || w.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("retry after 5xx: %d replayed=%q, want a fresh 201", w.Code, w.Header().Get(IdempotentReplayedHeader))
+	}
+}
+
+func TestIdempotencyKeysAreScoped(t *testing.T) {
+	var calls atomic.Int32
+	h := newIdempotency(t, createHandler(&calls))
+	acme := tenantContext.WithSchema(context.Background(), "acme")
+
+	for _, ctx := range []context.Context{
+		acme,
+		tenantContext.WithSchema(context.Background(), "beta"),
+		context.WithValue(acme, contextkeys.IDContextKey, int64(7)),
+		context.WithValue(acme, contextkeys.IDContextKey, int64(8)),
+		context.Background(),
+	} {
+		if w := post(h, ctx, "k1", "{}"); w.Code != http.StatusCreated || w.Header().Get(IdempotentReplayedHeader) != "" {
+			t.Errorf("%d: got %d replayed=%q, want a fresh 201", calls.Load(), w.Code, w.Header().Get(IdempotentReplayedHeader))
+		}
+	}
+	// Without a key every request runs.
+	post(h, acme, "", "{}")
+	post(h, acme, "", "{}")
+	if calls.Load() != 7 {
+		t.Errorf("handler ran %d times, want 7", calls.Load())
+	}
+}
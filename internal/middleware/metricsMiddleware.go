@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"skyrix/internal/engine/metrics"
+
+	"github.com/go-chi/chi/v5"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// MetricsMiddleware records request count, latency and in-flight requests. The route label is
+// chi's route pattern (e.g. "/api/v1/orders/{id}"), never the raw path, so it stays bounded;
+// requests matching no route are labelled "unmatched". The tenant label is set by the tenant
+// middleware through metrics.TagTenant.
+type MetricsMiddleware struct {
+	metrics *metrics.Metrics
+}
+
+func NewMetricsMiddleware(m *metrics.Metrics) *MetricsMiddleware {
+	return &MetricsMiddleware{metrics: m}
+}
+
+func (m *MetricsMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		m.metrics.HTTPInFlight.Inc()
+		defer m.metrics.HTTPInFlight.Dec()
+
+		ctx, req := metrics.WithRequest(r.Context())
+		ww := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := "unmatched"
+			if rc := chi.RouteContext(ctx); rc != nil {
+				if p := rc.RoutePattern(); p != "" {
+					route = p
+				}
+			}
+			tenant := m.metrics.TenantLabel(req.Tenant())
+			method := metricsMethod(r.Method)
+			m.metrics.HTTPRequests.With(method, route, strconv.Itoa(status), tenant).Inc()
+			m.metrics.HTTPDuration.With(method, route, tenant).Observe(time.Since(start).Seconds())
+		}()
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+	})
+}
+
+// metricsMethod folds non-standard methods into "OTHER"; clients choose the method.
+func metricsMethod(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return m
+	}
+	return "OTHER"
+}
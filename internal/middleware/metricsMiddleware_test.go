@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"skyrix/internal/engine/metrics"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddlewareCountsRequests(t *testing.T) {
+	m, err := metrics.New(prometheus.NewRegistry(), metrics.Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	r.Use(NewMetricsMiddleware(m).Handle)
+	r.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		// The tenant middleware tags the request once the tenant is resolved.
+		metrics.TagTenant(tenantContext.WithSchema(r.Context(), "acme"))
+		if got := testutil.ToFloat64(m.HTTPInFlight); got != 1 {
+			t.Errorf("in flight while serving = %v, want 1", got)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	for _, path := range []string{"/orders/1", "/orders/2", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/orders/1", nil))
+
+	if got := testutil.ToFloat64(m.HTTPRequests.With("GET", "/orders/{id}", "201", "acme")); got != 2 {
+		t.Errorf("requests by route pattern = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.HTTPRequests.With("GET", "unmatched", "404", "none")); got != 1 {
+		t.Errorf("unmatched requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.HTTPRequests.With("OTHER", "unmatched", "405", "none")); got != 1 {
+		t.Errorf("non-standard method requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.HTTPInFlight); got != 0 {
+		t.Errorf("in flight after serving = %v, want 0", got)
+	}
+}
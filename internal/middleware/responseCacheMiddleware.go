@@ -0,0 +1,377 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/textproto"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"skyrix/internal/config"
+	"skyrix/internal/engine/cache"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+	"skyrix/internal/logger"
+	"skyrix/internal/utils/gzip"
+)
+
+// maxIdentityBytes bounds decompression of cached bodies for clients without gzip.
+const maxIdentityBytes = 64 << 20
+
+// unstoredHeaders are never kept with a cached body: cookies, hop-by-hop headers and the
+// framing headers writeCached sets for each response.
+var unstoredHeaders = map[string]bool{
+	"Set-Cookie":         true,
+	"Connection":         true,
+	"Keep-Alive":         true,
+	"Proxy-Authenticate": true,
+	"Proxy-Connection":   true,
+	"Te":                 true,
+	"Trailer":            true,
+	"Transfer-Encoding":  true,
+	"Upgrade":            true,
+	"Content-Length":     true,
+	"Content-Encoding":   true,
+	"Etag":               true,
+	"X-Cache":            true,
+}
+
+// CacheRule configures caching for a group of routes. Zero fields take the config defaults.
+type CacheRule struct {
+	TTL time.Duration
+	// Vary lists request headers that select the cached variant (e.g. Accept-Language).
+	// Responses that differ per user must vary on what identifies the user (e.g. Authorization).
+	Vary []string
+	// Tags are attached to every entry; TagsFunc adds per-request tags (e.g. "product:42").
+	// Domain services drop tagged entries with cache.TenantCache.InvalidateTags.
+	Tags     []string
+	TagsFunc func(r *http.Request) []string
+	// MaxBodyBytes: larger responses are streamed through and not cached.
+	MaxBodyBytes int64
+}
+
+// ResponseCacheMiddleware caches GET responses in engine.Cache. Entries are per tenant
+// (cache.TenantCache), keyed by path, sorted query and the Vary headers. Bodies are stored
+// gzipped and sent as is to clients accepting gzip. Every entry has a strong ETag, and a
+// matching If-None-Match gets 304 Not Modified, also on a miss.
+//
+// Only 200 responses without Set-Cookie and without Cache-Control no-store/private are
+// stored. The headers the handler set are stored with the body, except hop-by-hop and framing
+// headers; headers already on the response before the handler ran (set by outer middleware
+// for each request) are not. HEAD requests are answered from entries written by GET.
+type ResponseCacheMiddleware struct {
+	tenant  *cache.TypedCache[cachedResponse]
+	global  *cache.TypedCache[cachedResponse]
+	log     logger.Interface
+	enabled bool
+	rule    CacheRule
+}
+
+type cachedResponse struct {
+	Header http.Header `msgpack:"h"`
+	Body   []byte      `msgpack:"b"` // gzipped
+	Hash   string      `msgpack:"e"` // sha-256 of the uncompressed body
+}
+
+func NewResponseCacheMiddleware(c *cache.TenantCache, cfg *config.HttpServer, log logger.Interface) *ResponseCacheMiddleware {
+	opts := cache.Options{Namespace: "http", Codec: cache.Msgpack, TTL: cfg.ResponseCacheTTL}
+	return &ResponseCacheMiddleware{
+		tenant:  cache.NewTyped[cachedResponse](c, opts),
+		global:  cache.NewTyped[cachedResponse](c.Global(), opts),
+		log:     log,
+		enabled: cfg.ResponseCacheEnabled,
+		rule: CacheRule{
+			TTL:          cfg.ResponseCacheTTL,
+			Vary:         cfg.ResponseCacheVary,
+			MaxBodyBytes: cfg.ResponseCacheMaxBody,
+		},
+	}
+}
+
+// Handle caches with the configured defaults.
+func (m *ResponseCacheMiddleware) Handle(next http.Handler) http.Handler {
+	return m.With(CacheRule{})(next)
+}
+
+// With caches with rule, e.g. r.With(mw.ResponseCache.With(middleware.CacheRule{Tags: ...})).
+func (m *ResponseCacheMiddleware) With(rule CacheRule) func(http.Handler) http.Handler {
+	if rule.TTL <= 0 {
+		rule.TTL = m.rule.TTL
+	}
+	if rule.Vary == nil {
+		rule.Vary = m.rule.Vary
+	}
+	if rule.MaxBodyBytes <= 0 {
+		rule.MaxBodyBytes = m.rule.MaxBodyBytes
+	}
+	vary := make([]string, 0, len(rule.Vary))
+	for _, h := range rule.Vary {
+		if h = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(h)); h != "" {
+			vary = append(vary, h)
+		}
+	}
+	sort.Strings(vary)
+	rule.Vary = vary
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !m.enabled || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := r.Context()
+			store := m.tenant
+			if tenantContext.SchemaFrom(ctx) == "" {
+				store = m.global
+			}
+			key := responseKey(r, rule.Vary)
+
+			res, ok, err := store.Get(ctx, key)
+			if err != nil && m.log != nil {
+				m.log.Warn("response cache read failed", "path", r.URL.Path, "error", err)
+			}
+			if ok {
+				writeCached(w, r, &res, rule.Vary, "HIT")
+				return
+			}
+			if r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &responseRecorder{w: w, header: w.Header().Clone(), max: rule.MaxBodyBytes}
+			next.ServeHTTP(rec, r)
+			if rec.streaming {
+				return
+			}
+			stored, ok := rec.cacheable(w.Header())
+			if !ok {
+				rec.stream()
+				return
+			}
+
+			tags := append([]string(nil), rule.Tags...)
+			if rule.TagsFunc != nil {
+				tags = append(tags, rule.TagsFunc(r)...)
+			}
+			if err := store.Set(ctx, key, *stored, cache.WithTTL(rule.TTL), cache.WithTags(tags...)); err != nil && m.log != nil {
+				m.log.Warn("response cache write failed", "path", r.URL.Path, "error", err)
+			}
+			h := w.Header()
+			for k, v := range rec.header {
+				if !unstoredHeaders[k] {
+					h[k] = v
+				}
+			}
+			writeCached(w, r, stored, rule.Vary, "MISS")
+		})
+	}
+}
+
+// responseKey hashes path, sorted query and the Vary header values; the tenant and
+// application prefix are added by cache.TenantCache.
+func responseKey(r *http.Request, vary []string) string {
+	h := sha256.New()
+	h.Write([]byte(r.URL.EscapedPath()))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.Query().Encode()))
+	for _, name := range vary {
+		h.Write([]byte{0})
+		h.Write([]byte(name))
+		h.Write([]byte{':'})
+		h.Write([]byte(strings.Join(r.Header.Values(name), ",")))
+	}
+	return "GET:" + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// writeCached sends res. gzip and identity bodies get distinct strong ETags.
+func writeCached(w http.ResponseWriter, r *http.Request, res *cachedResponse, vary []string, status string) {
+	h := w.Header()
+	for k, v := range res.Header {
+		h[k] = v
+	}
+	h.Set("X-Cache", status)
+	h.Add("Vary", "Accept-Encoding")
+	for _, name := range vary {
+		h.Add("Vary", name)
+	}
+
+	gz := acceptsGzip(r)
+	etag := res.etag(gz)
+	h.Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), res) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body := res.Body
+	if gz {
+		h.Set("Content-Encoding", "gzip")
+	} else {
+		var err error
+		if body, err = gzip.GunzipBytesWithLimit(res.Body, maxIdentityBytes); err != nil {
+			http.Error(w, "cached response is corrupt", http.StatusInternalServerError)
+			return
+		}
+	}
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(body)
+	}
+}
+
+func (c *cachedResponse) etag(gzipped bool) string {
+	if gzipped {
+		return `"` + c.Hash + `-gzip"`
+	}
+	return `"` + c.Hash + `"`
+}
+
+// etagMatches implements If-None-Match (weak comparison) against both encodings' ETags.
+func etagMatches(header string, res *cachedResponse) bool {
+	if header = strings.TrimSpace(header); header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == res.etag(false) || tag == res.etag(true) {
+			return true
+		}
+	}
+	return false
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
+
+// responseRecorder buffers a response for caching. Once it is known not to be cacheable
+// (status other than 200, body over max, or a flush) it streams to the client instead.
+type responseRecorder struct {
+	w           http.ResponseWriter
+	header      http.Header
+	status      int
+	buf         bytes.Buffer
+	max         int64
+	wroteHeader bool
+	streaming   bool
+}
+
+func (rec *responseRecorder) Header() http.Header { return rec.header }
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = code
+	if code != http.StatusOK {
+		rec.stream()
+	}
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if !rec.streaming && int64(rec.buf.Len()+len(p)) > rec.max {
+		rec.stream()
+	}
+	if rec.streaming {
+		return rec.w.Write(p)
+	}
+	return rec.buf.Write(p)
+}
+
+func (rec *responseRecorder) Flush() {
+	rec.stream()
+	if f, ok := rec.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// stream sends the headers and buffered body and passes further writes through.
+func (rec *responseRecorder) stream() {
+	if rec.streaming {
+		return
+	}
+	rec.streaming = true
+	dst := rec.w.Header()
+	for k, v := range rec.header {
+		dst[k] = v
+	}
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.w.WriteHeader(rec.status)
+	if rec.buf.Len() > 0 {
+		_, _ = rec.w.Write(rec.buf.Bytes())
+		rec.buf.Reset()
+	}
+}
+
+// cacheable builds the entry for a buffered 200 response, or reports false. prev holds the
+// headers the response had before the handler ran.
+func (rec *responseRecorder) cacheable(prev http.Header) (*cachedResponse, bool) {
+	if rec.status != 0 && rec.status != http.StatusOK {
+		return nil, false
+	}
+	if rec.header.Get("Set-Cookie") != "" {
+		return nil, false
+	}
+	cc := strings.ToLower(rec.header.Get("Cache-Control"))
+	if strings.Contains(cc, "no-store") || strings.Contains(cc, "private") {
+		return nil, false
+	}
+
+	body := rec.buf.Bytes()
+	var gz, identity []byte
+	var err error
+	switch strings.ToLower(rec.header.Get("Content-Encoding")) {
+	case "":
+		identity = body
+		if gz, err = gzip.GzipBytes(body, gzip.OptimalCompressionLevel); err != nil {
+			return nil, false
+		}
+	case "gzip": // pre-compressed, e.g. handlers.WriteGzipJSON
+		gz = append([]byte(nil), body...)
+		if identity, err = gzip.GunzipBytesWithLimit(body, maxIdentityBytes); err != nil {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+
+	sum := sha256.Sum256(identity)
+	res := &cachedResponse{
+		Header: http.Header{},
+		Body:   gz,
+		Hash:   base64.RawURLEncoding.EncodeToString(sum[:]),
+	}
+	hopByHop := map[string]bool{}
+	for _, v := range rec.header.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			hopByHop[textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+	for name, v := range rec.header {
+		if unstoredHeaders[name] || hopByHop[name] || len(v) == 0 || slices.Equal(prev[name], v) {
+			continue
+		}
+		res.Header[name] = append([]string(nil), v...)
+	}
+	return res, true
+}
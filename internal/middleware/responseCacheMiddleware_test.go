@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"skyrix/internal/config"
+	"skyrix/internal/engine"
+	"skyrix/internal/engine/cache"
+	tenantContext "skyrix/internal/engine/tenantPackage/context"
+	"skyrix/internal/logger"
+	"skyrix/internal/utils/gzip"
+)
+
+func testLogger() logger.Interface {
+	return logger.NewSlogWrapper(slog.New(slog.DiscardHandler))
+}
+
+func testTenantCache(t *testing.T) *cache.TenantCache {
+	t.Helper()
+	m := engine.NewMemoryCache(engine.MemoryOpts{})
+	t.Cleanup(func() { _ = m.Close() })
+	return cache.NewTenantCache(m, "test")
+}
+
+// cachedHandler counts its calls and answers with body and the given status and headers.
+func cachedHandler(calls *atomic.Int32, status int, body string, header map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "text/plain")
+		for k, v := range header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, body)
+	})
+}
+
+func newResponseCache(t *testing.T, next http.Handler) http.Handler {
+	t.Helper()
+	cfg := &config.HttpServer{ResponseCacheEnabled: true, ResponseCacheTTL: time.Minute, ResponseCacheMaxBody: 1 << 20}
+	return NewResponseCacheMiddleware(testTenantCache(t), cfg, testLogger()).Handle(next)
+}
+
+func get(h http.Handler, ctx context.Context, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/products?b=2&a=1", nil).WithContext(ctx)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestResponseCacheHitAndETags(t *testing.T) {
+	var calls atomic.Int32
+	h := newResponseCache(t, cachedHandler(&calls, http.StatusOK, "hello", nil))
+	ctx := tenantContext.WithSchema(context.Background(), "acme")
+
+	miss := get(h, ctx, nil)
+	if miss.Code != http.StatusOK || miss.Body.String() != "hello" || miss.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("first GET: %d %q X-Cache=%s", miss.Code, miss.Body, miss.Header().Get("X-Cache"))
+	}
+	etag := miss.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+
+	hit := get(h, ctx, map[string]string{"Accept-Encoding": "gzip"})
+	if hit.Header().Get("X-Cache") != "HIT" || hit.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("second GET: X-Cache=%s Content-Encoding=%s", hit.Header().Get("X-Cache"), hit.Header().Get("Content-Encoding"))
+	}
+	if body, err := gzip.GunzipBytesWithLimit(hit.Body.Bytes(), 1<<20); err != nil || string(body) != "hello" {
+		t.Fatalf("gzipped body = %q, %v", body, err)
+	}
+	if gzTag := hit.Header().Get("ETag"); gzTag == etag || gzTag != etag[:len(etag)-1]+`-gzip"` {
+		t.Errorf("gzip ETag = %s, want a distinct tag derived from %s", gzTag, etag)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+}
+
+func TestResponseCacheNotModified(t *testing.T) {
+	var calls atomic.Int32
+	h := newResponseCache(t, cachedHandler(&calls, http.StatusOK, "hello", nil))
+	ctx := tenantContext.WithSchema(context.Background(), "acme")
+	etag := get(h, ctx, nil).Header().Get("ETag")
+
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w := get(h, ctx, map[string]string{"If-None-Match": inm})
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: %d with %d body bytes, want an empty 304", inm, w.Code, w.Body.Len())
+		}
+		if w.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: ETag %s, want %s", inm, w.Header().Get("ETag"), etag)
+		}
+	}
+	if w := get(h, ctx, map[string]string{"If-None-Match": `"other"`}); w.Code != http.StatusOK {
+		t.Errorf("stale If-None-Match: %d, want 200", w.Code)
+	}
+
+	// A miss answers 304 as well once the handler's body matches the client's copy.
+	other := tenantContext.WithSchema(context.Background(), "beta")
+	if w := get(h, other, map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified || w.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("miss with a matching If-None-Match: %d X-Cache=%s, want 304 MISS", w.Code, w.Header().Get("X-Cache"))
+	}
+}
+
+func TestResponseCacheSkipsUncacheable(t *testing.T) {
+	ctx := tenantContext.WithSchema(context.Background(), "acme")
+	for name, c := range map[string]struct {
+		status int
+		header map[string]string
+	}{
+		"not found":  {http.StatusNotFound, nil},
+		"set-cookie": {http.StatusOK, map[string]string{"Set-Cookie": "s=1"}},
+		"no-store":   {http.StatusOK, map[string]string{"Cache-Control": "no-store"}},
+		"private":    {http.StatusOK, map[string]string{"Cache-Control": "private, max-age=60"}},
+	} {
+		var calls atomic.Int32
+		h := newResponseCache(t, cachedHandler(&calls, c.status, "body", c.header))
+		get(h, ctx, nil)
+		w := get(h, ctx, nil)
+		if calls.Load() != 2 {
+			t.Errorf("%s: handler ran %d times, want 2 (not cached)", name, calls.Load())
+		}
+		if w.Code != c.status || w.Body.String() != "body" {
+			t.Errorf("%s: got %d %q", name, w.Code, w.Body)
+		}
+	}
+}
+
+func TestResponseCacheIsPerTenant(t *testing.T) {
+	var calls atomic.Int32
+	h := newResponseCache(t, cachedHandler(&calls, http.StatusOK, "hello", nil))
+	for _, schema := range []string{"acme", "beta", "acme"} {
+		get(h, tenantContext.WithSchema(context.Background(), schema), nil)
+	}
+	get(h, context.Background(), nil)
+	if calls.Load() != 3 {
+		t.Errorf("handler ran %d times, want 3 (acme, beta, no tenant)", calls.Load())
+	}
+}
+
+func TestResponseCacheKeepsHandlerHeaders(t *testing.T) {
+	var calls atomic.Int32
+	inner := newResponseCache(t, cachedHandler(&calls, http.StatusOK, "hello", map[string]string{
+		"X-Total-Count":  "42",
+		"Link":           `</products?page=2>; rel="next"`,
+		"Connection":     "close",
+		"Content-Length": "999",
+	}))
+	// An outer middleware sets a per-request header before the cache runs.
+	var n atomic.Int32
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", strconv.Itoa(int(n.Add(1))))
+		inner.ServeHTTP(w, r)
+	})
+	ctx := tenantContext.WithSchema(context.Background(), "acme")
+
+	for i, want := range []struct{ cache, requestID string }{{"MISS", "1"}, {"HIT", "2"}} {
+		w := get(h, ctx, nil)
+		got := w.Header()
+		if got.Get("X-Cache") != want.cache || got.Get("X-Total-Count") != "42" || got.Get("Link") == "" {
+			t.Errorf("request %d: headers %v", i, got)
+		}
+		if got.Get("X-Request-Id") != want.requestID {
+			t.Errorf("request %d: X-Request-Id %s, want %s", i, got.Get("X-Request-Id"), want.requestID)
+		}
+		if got.Get("Content-Length") != "5" {
+			t.Errorf("request %d: Content-Length %s, want 5", i, got.Get("Content-Length"))
+		}
+		if want.cache == "HIT" && got.Get("Connection") != "" {
+			t.Errorf("hop-by-hop Connection was replayed from the cache")
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+}
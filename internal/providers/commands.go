@@ -2,6 +2,7 @@ package providers
 
 import (
 	"skyrix/internal/commands"
+	"skyrix/internal/engine/migrate"
 
 	"github.com/google/wire"
 	"github.com/spf13/cobra"
@@ -9,7 +10,11 @@ import (
 
 // Commands is a bundle of all CLI commands exposed by the application.
 type Commands struct {
-	Hello *commands.HelloCommand
+	Hello   *commands.HelloCommand
+	JobRun  *commands.JobRunCommand
+	JobList *commands.JobListCommand
+	Outbox  *commands.OutboxRelayCommand
+	Migrate *commands.MigrateUpCommand
 
 	// All is the final list of cobra commands registered in the root CLI.
 	All []*cobra.Command
@@ -17,17 +22,36 @@ type Commands struct {
 
 // ProvideCommands assembles the command list.
 // Keep this function as the single place that defines command registration order.
-func ProvideCommands(hello *commands.HelloCommand) *Commands {
+func ProvideCommands(
+	hello *commands.HelloCommand,
+	jobRun *commands.JobRunCommand,
+	jobList *commands.JobListCommand,
+	outboxRelay *commands.OutboxRelayCommand,
+	migrateUp *commands.MigrateUpCommand,
+) *Commands {
 	out := &Commands{
-		Hello: hello,
+		Hello:   hello,
+		JobRun:  jobRun,
+		JobList: jobList,
+		Outbox:  outboxRelay,
+		Migrate: migrateUp,
 	}
 	out.All = []*cobra.Command{
 		hello.ToCobraCommand(),
+		jobRun.ToCobraCommand(),
+		jobList.ToCobraCommand(),
+		outboxRelay.ToCobraCommand(),
+		migrateUp.ToCobraCommand(),
 	}
 	return out
 }
 
 var CommandProviderSet = wire.NewSet(
 	commands.NewHelloCommand,
+	commands.NewJobRunCommand,
+	commands.NewJobListCommand,
+	commands.NewOutboxRelayCommand,
+	commands.NewMigrateUpCommand,
+	migrate.ProviderSet,
 	ProvideCommands,
 )
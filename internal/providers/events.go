@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"skyrix/internal/domain/subscriber/listeners"
+	"skyrix/internal/engine/events"
+
+	"github.com/google/wire"
+)
+
+// EventSubscribers bundles every component that registers handlers on the event bus.
+// Add new domain listeners here and to All.
+type EventSubscribers struct {
+	OrderPlaced *listeners.OrderPlacedListener
+}
+
+// All lists the bundled subscribers in registration order.
+func (s *EventSubscribers) All() []events.Subscriber {
+	return []events.Subscriber{
+		s.OrderPlaced,
+	}
+}
+
+// RegisterEventSubscribers subscribes every bundled listener on bus and exposes it as the
+// events.Publisher. It is the only provider of the Publisher, so no domain service can
+// publish on a bus whose subscriptions are not registered yet.
+// Subscribers must not depend on events.Publisher themselves (that would be a cycle);
+// a handler that needs to emit follow-up events can use the *events.Bus it is given in Subscribe.
+func RegisterEventSubscribers(bus *events.Bus, subs *EventSubscribers) events.Publisher {
+	for _, s := range subs.All() {
+		s.Subscribe(bus)
+	}
+	return bus
+}
+
+var EventProviderSet = wire.NewSet(
+	events.NewBus,
+	wire.Struct(new(EventSubscribers), "*"),
+	RegisterEventSubscribers,
+)
@@ -8,12 +8,12 @@ import (
 
 type Handlers struct {
 	Subscriber *handlers.SubscriberHandler
-	// Order *handlers.OrderHandler
+	Order      *handlers.OrderHandler
 }
 
 var HandlerProviderSet = wire.NewSet(
 	handlers.NewSubscriberHandler,
-	// handlers.NewOrderHandler,
+	handlers.NewOrderHandler,
 
 	wire.Struct(new(Handlers), "*"),
 )
@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"skyrix/internal/config"
+	"skyrix/internal/engine/health"
+	"skyrix/internal/logger"
+
+	"github.com/google/wire"
+)
+
+// HealthChecks bundles the checkers behind /health/live and /health/ready.
+// Components add a field with their checker's provider to take part.
+type HealthChecks struct {
+	Database  *health.DatabaseChecker
+	Schema    *health.SchemaChecker
+	Redis     *health.RedisChecker
+	Queue     *health.QueueChecker
+	Lifecycle *health.LifecycleChecker
+}
+
+// ProvideHealth builds the health registry with all known checks; nil checkers are disabled.
+func ProvideHealth(cfg *config.Config, log logger.Interface, all *HealthChecks) *health.Registry {
+	reg := health.NewRegistry(health.Options{
+		Timeout:   cfg.Health.Timeout,
+		CacheTTL:  cfg.Health.CacheTTL,
+		Version:   cfg.Health.Version,
+		ReleaseID: cfg.Health.ReleaseID,
+		ServiceID: cfg.Queue.ServiceName,
+	}, log)
+
+	reg.Register(all.Lifecycle, all.Database, all.Schema)
+	if all.Redis != nil {
+		reg.Register(all.Redis)
+	}
+	if all.Queue != nil {
+		reg.Register(all.Queue)
+	}
+	return reg
+}
+
+// HealthProviderSet wires the health subsystem (checkers + populated registry).
+var HealthProviderSet = wire.NewSet(
+	health.ProviderSet,
+	wire.Struct(new(HealthChecks), "*"),
+	ProvideHealth,
+)
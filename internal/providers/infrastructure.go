@@ -1,9 +1,13 @@
 package providers
 
 import (
+	"context"
 	"skyrix/internal/config"
+	"skyrix/internal/engine"
+	"skyrix/internal/engine/metrics"
 	"skyrix/internal/kernel/db"
 	"skyrix/internal/logger"
+	"strings"
 
 	"github.com/google/wire"
 	"github.com/redis/go-redis/v9"
@@ -23,15 +27,22 @@ func ProvideRedisConfig(cfg *config.Config) *config.Redis {
 	return &cfg.Redis
 }
 
+func ProvideCacheConfig(cfg *config.Config) *config.Cache {
+	return &cfg.Cache
+}
+
 // InfrastructureSet provides basic, non-business dependencies.
 var InfrastructureSet = wire.NewSet(
 	ProvideConfig,
 	ProvideLoggerConfig,
 	ProvideDatabaseConfig,
 	ProvideRedisConfig,
+	ProvideCacheConfig,
 	ProvideLogger,
 	ProvidePostgres,
+	ProvideTenantPools,
 	ProvideRedis,
+	wire.Bind(new(engine.TenantPools), new(*db.TenantPools)),
 )
 
 func ProvideConfig() (*config.Config, error) {
@@ -42,23 +53,38 @@ func ProvideLogger(cfg *config.Logger) logger.Interface {
 	return logger.NewLogger(cfg.LogLevel, cfg.LogType, cfg.LogFile)
 }
 
-func ProvidePostgres(cfg *config.Database, log logger.Interface) (*gorm.DB, func(), error) {
-	postgres, err := db.InitPostgres(cfg)
+func ProvidePostgres(ctx context.Context, cfg *config.Database, log logger.Interface, m *metrics.Metrics) (*gorm.DB, func(), error) {
+	postgres, err := db.InitPostgres(ctx, cfg, log, m)
 	if err != nil {
 		log.Error("Unable to initialize postgres database", "error", err)
 		return nil, nil, err
 	}
 	cleanup := func() {
-		sqlDB, err := postgres.DB()
-		if err == nil {
-			log.Info("Closing postgres database connection")
-			_ = sqlDB.Close()
-		}
+		log.Info("Closing postgres database connection")
+		_ = db.ClosePostgres(postgres)
 	}
 	return postgres, cleanup, nil
 }
 
-func ProvideRedis(cfg *config.Redis, log logger.Interface) (*redis.Client, func(), error) {
+func ProvideTenantPools(cfg *config.Database, log logger.Interface, m *metrics.Metrics) (*db.TenantPools, func()) {
+	pools := db.NewTenantPools(cfg, log, m)
+	cleanup := func() {
+		log.Info("Closing tenant database pools")
+		_ = pools.Close()
+	}
+	return pools, cleanup
+}
+
+func ProvideRedis(cfg *config.Redis, cacheCfg *config.Cache, log logger.Interface) (redis.UniversalClient, func(), error) {
+	if strings.EqualFold(strings.TrimSpace(cacheCfg.Backend), engine.CacheMemory) {
+		// The in-memory cache does not need Redis; the client connects lazily if anything uses it.
+		log.Info("Cache backend is memory, skipping Redis connection check")
+		client, err := db.NewRedisClient(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, func() { _ = client.Close() }, nil
+	}
 	client, err := db.InitRedis(cfg)
 	if err != nil {
 		log.Error("Unable to initialize Redis Client", "error", err)
@@ -1,36 +1,53 @@
 package providers
 
 import (
+	"skyrix/internal/engine"
+	"skyrix/internal/engine/cache"
 	engineJobs "skyrix/internal/engine/jobs"
+	"skyrix/internal/engine/outbox"
 	"skyrix/internal/jobs"
 	kernelJobs "skyrix/internal/kernel/jobs"
+	"skyrix/internal/logger"
 
 	"github.com/google/wire"
 )
 
 type Jobs struct {
-	SystemPingJob *jobs.SystemPingJob
+	SystemPingJob    *jobs.SystemPingJob
+	OutboxRelayJob   *jobs.OutboxRelayJob
+	OutboxCleanupJob *jobs.OutboxCleanupJob
 }
 
-// ProvideJobsInit registers all known jobs into the registry.
-// It returns a cleanup func to satisfy Wire and to avoid double-providing *Registry.
-func ProvideJobsInit(reg *kernelJobs.Registry, all *Jobs) func() {
+// ProvideJobRegistry registers all known jobs into the runtime registry and exposes it
+// as engineJobs.Registry. Consumers of the interface (Kernel, console commands) therefore
+// always receive a populated registry; a standalone init hook would be pruned by Wire.
+func ProvideJobRegistry(reg *kernelJobs.Registry, all *Jobs) engineJobs.Registry {
 	reg.Register(all.SystemPingJob)
-	return func() {}
+	reg.Register(all.OutboxRelayJob)
+	reg.Register(all.OutboxCleanupJob)
+	return reg
+}
+
+// ProvideDispatcher builds the async dispatcher and stops it at shutdown: pending timers are
+// dropped and running jobs awaited before the connections they use are closed.
+func ProvideDispatcher(reg *kernelJobs.Registry, c *cache.TenantCache, locker engine.Locker, lc engine.Lifecycle, log logger.Interface) (*kernelJobs.Dispatcher, func()) {
+	d := kernelJobs.NewDispatcher(reg, c, locker, log)
+	lc.OnStop("jobs dispatcher", d.Stop)
+	return d, d.Close
 }
 
 // JobDomainDepsSet contains ONLY dependencies required by jobs (domain services, publishers, etc).
 // Keep it minimal to avoid pulling entire domains into the console app.
 var JobDomainDepsSet = wire.NewSet(
-// notifications domain
-// notifications.NewRepository,
-// notifications.NewService,
+	// notifications domain
+	// notifications.NewRepository,
+	// notifications.NewService,
 
-// outbox/eventbus dependencies if notifications use them
-// outbox.NewPublisher,
+	// outbox/eventbus dependencies if notifications use them
+	outbox.ProviderSet,
 )
 
-// JobProviderSet wires the jobs subsystem (registry + concrete jobs + init hook).
+// JobProviderSet wires the jobs subsystem (registry + concrete jobs + registration).
 var JobProviderSet = wire.NewSet(
 	JobDomainDepsSet,
 
@@ -39,13 +56,21 @@ var JobProviderSet = wire.NewSet(
 
 	// concrete jobs
 	jobs.NewSystemPingJob,
+	jobs.NewOutboxRelayJob,
+	jobs.NewOutboxCleanupJob,
 
 	// bundle
 	wire.Struct(new(Jobs), "*"),
 
-	// init hook (doesn't provide *Registry)
-	ProvideJobsInit,
+	// populated registry behind the engine interface
+	ProvideJobRegistry,
+	// the outbox job sink runs jobs through the concrete registry, as the relay job depends on it
+	wire.Bind(new(outbox.JobRunner), new(*kernelJobs.Registry)),
+
+	// per-tenant fan-out runner for tenant-scoped jobs
+	kernelJobs.NewTenantRunner,
 
-	// interface binding
-	wire.Bind(new(engineJobs.Registry), new(*kernelJobs.Registry)),
+	// async dispatcher (unique/debounce/throttle/delay)
+	ProvideDispatcher,
+	wire.Bind(new(engineJobs.Enqueuer), new(*kernelJobs.Dispatcher)),
 )
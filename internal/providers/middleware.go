@@ -10,12 +10,20 @@ type GlobalMiddleware struct {
 	ManyRequests   *middleware.ManyRequestsMiddleware
 	Recover        *middleware.RecoverMiddleware
 	GzipDecompress *middleware.GzipDecompressMiddleware
+	Consistency    *middleware.ConsistencyMiddleware
+	ResponseCache  *middleware.ResponseCacheMiddleware // per route group, see router
+	Idempotency    *middleware.IdempotencyMiddleware
+	Metrics        *middleware.MetricsMiddleware
 }
 
 var GlobalMiddlewareProviderSet = wire.NewSet(
 	middleware.NewManyRequestsMiddleware,
 	middleware.NewRecoverMiddleware,
 	middleware.NewGzipDecompressMiddleware,
+	middleware.NewConsistencyMiddleware,
+	middleware.NewResponseCacheMiddleware,
+	middleware.NewIdempotencyMiddleware,
+	middleware.NewMetricsMiddleware,
 
 	wire.Struct(new(GlobalMiddleware), "*"),
 )
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"skyrix/internal/engine/cache"
 	"skyrix/internal/engine/tenantPackage"
 
 	"github.com/google/wire"
@@ -8,5 +9,6 @@ import (
 
 var PlatformProviderSet = wire.NewSet(
 	tenantPackage.ProviderSet,
+	cache.ProviderSet,
 	// auth.ProviderSet, // later
 )
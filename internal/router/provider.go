@@ -3,9 +3,11 @@ package router
 import (
 	"net/http"
 	"skyrix/internal/config"
+	"skyrix/internal/engine/health"
 	"skyrix/internal/providers"
 
 	"github.com/google/wire"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func ProvideRouter(
@@ -13,8 +15,11 @@ func ProvideRouter(
 	globalMw *providers.GlobalMiddleware,
 	tenantMw TenantMiddleware,
 	handlers *providers.Handlers,
+	healthz *health.Registry,
+	metricsReg *prometheus.Registry,
+	metricsCfg *config.Metrics,
 ) http.Handler {
-	return InitRouter(cfg, globalMw, tenantMw, handlers)
+	return InitRouter(cfg, globalMw, tenantMw, handlers, healthz, metricsReg, metricsCfg)
 }
 
 var ProviderSet = wire.NewSet(
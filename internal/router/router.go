@@ -3,10 +3,13 @@ package router
 import (
 	"net/http"
 	"skyrix/internal/config"
+	"skyrix/internal/engine/health"
+	"skyrix/internal/engine/metrics"
 	"skyrix/internal/providers"
 
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // TenantMiddleware is a minimal interface required by the router.
@@ -21,16 +24,23 @@ func InitRouter(
 	globalMw *providers.GlobalMiddleware,
 	tenantMw TenantMiddleware,
 	handlers *providers.Handlers,
+	healthz *health.Registry,
+	metricsReg *prometheus.Registry,
+	metricsCfg *config.Metrics,
 ) http.Handler {
 	r := chi.NewRouter()
 
 	// ==== Global middleware ====
 	r.Use(chiMiddleware.RequestID)
 	r.Use(chiMiddleware.RealIP)
+	if metricsCfg.Enabled {
+		r.Use(globalMw.Metrics.Handle) // outside Recover, so recovered panics count as 500
+	}
 	r.Use(globalMw.Recover.Handle)
 	r.Use(chiMiddleware.Logger)
 	r.Use(chiMiddleware.Timeout(cfg.Timeout))
 	r.Use(globalMw.GzipDecompress.Handle)
+	r.Use(globalMw.Consistency.Handle)
 	r.Use(chiMiddleware.Compress(5, "application/json", "text/plain", "text/html"))
 
 	// Global OPTIONS responder (handy for preflight)
@@ -38,11 +48,16 @@ func InitRouter(
 		w.WriteHeader(http.StatusOK)
 	})
 
-	// /health
-	r.Get("/health", func(w http.ResponseWriter, _ *http.Request) {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		_, _ = w.Write([]byte("ok"))
-	})
+	// Health probes (application/health+json). Liveness covers the process only; readiness
+	// also fails when a dependency is down or shutdown started. /health is kept for readiness.
+	r.Get("/health/live", healthz.LiveHandler())
+	r.Get("/health/ready", healthz.ReadyHandler())
+	r.Get("/health", healthz.ReadyHandler())
+
+	// Prometheus scrape endpoint. Expose it on an internal network only.
+	if metricsCfg.Enabled {
+		r.Get(metricsCfg.Path, metrics.Handler(metricsReg).ServeHTTP)
+	}
 
 	// ==== Routes ====
 	r.Route("/api/v1", func(r chi.Router) {
@@ -50,6 +65,13 @@ func InitRouter(
 
 		// Example:
 		// r.Post("/subscribers", h.Subscriber.Handle)
+		//
+		// Read-heavy routes can cache responses per tenant:
+		// r.With(globalMw.ResponseCache.With(middleware.CacheRule{Tags: []string{"catalog"}})).
+		//	Get("/catalog", h.Catalog.List)
+		//
+		// Unsafe endpoints clients may retry accept an Idempotency-Key.
+		r.With(globalMw.Idempotency.Handle).Post("/orders", handlers.Order.Create)
 	})
 
 	return r